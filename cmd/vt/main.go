@@ -2,6 +2,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/happyhackingspace/vt/internal/app"
 	"github.com/happyhackingspace/vt/internal/cli"
 	"github.com/happyhackingspace/vt/internal/logger"
@@ -13,29 +15,53 @@ import (
 )
 
 func main() {
-	cfg := app.DefaultConfig()
+	cfg, err := app.LoadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load configuration")
+	}
 
-	appLogger := logger.NewWithLevel(cfg.LogLevel)
-	logger.SetGlobal(appLogger)
+	loggerCfg := logger.DefaultConfig()
+	loggerCfg.Level = cfg.LogLevel
+	loggerCfg.NoColor = cfg.LogNoColor
+	logger.SetGlobal(logger.New(loggerCfg))
 
-	templates, err := template.LoadTemplates(cfg.TemplatesPath)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to load templates")
+	templates, _, loadErr := cfg.LoadTemplates()
+	if loadErr != nil {
+		templates = make(map[string]template.Template)
+	}
+
+	scenarios := make(map[string]template.Scenario)
+	if loadErr == nil {
+		scenarios, loadErr = cfg.LoadScenarios()
 	}
 
 	storeCfg := disk.NewConfig().
+		WithDir(cfg.StoragePath).
 		WithFileName("deployments.db").
 		WithBucketName("deployments")
-	stateManager, err := state.NewManager(storeCfg)
+	attackersCfg := disk.NewConfig().
+		WithDir(cfg.StoragePath).
+		WithFileName("attackers.db").
+		WithBucketName("attackers")
+	stateManager, err := state.NewManager(storeCfg, attackersCfg)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to create state manager")
 	}
 
-	providers := registry.NewProviders(stateManager)
+	providers := registry.NewProviders(stateManager, cfg.TemplatesPath)
 
-	application := app.NewApp(templates, providers, stateManager, cfg)
+	application := app.NewApp(templates, scenarios, providers, stateManager, cfg)
+
+	c := cli.New(application)
+	if loadErr != nil {
+		// bundle import must keep working on hosts without network access
+		if !c.RunsWithoutTemplates(os.Args[1:]) {
+			log.Fatal().Err(loadErr).Msg("failed to load templates")
+		}
+		log.Warn().Err(loadErr).Msg("failed to load templates")
+	}
 
-	if err := cli.New(application).Run(); err != nil {
+	if err := c.Run(); err != nil {
 		log.Fatal().Err(err).Msg("CLI error")
 	}
 }
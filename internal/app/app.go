@@ -12,19 +12,60 @@ import (
 
 // Config holds application configuration.
 type Config struct {
-	TemplatesPath string
-	StoragePath   string
-	LogLevel      string
+	// TemplatesPath is the directory the primary template repository is cloned into.
+	TemplatesPath string `yaml:"templates_path,omitempty"`
+	// StoragePath is the directory holding the deployment state database.
+	StoragePath string `yaml:"storage_path,omitempty"`
+	// RepositoriesPath is the directory the other git repositories are cloned into.
+	RepositoriesPath string `yaml:"repositories_path,omitempty"`
+	// DefaultProvider is used when a command is run without --provider.
+	DefaultProvider string `yaml:"default_provider,omitempty"`
+	// Repositories lists the template repositories, the first one is the primary.
+	Repositories []Repository `yaml:"repositories,omitempty"`
+	// LogLevel is the default verbosity level.
+	LogLevel string `yaml:"log_level,omitempty"`
+	// LogNoColor disables colored log output.
+	LogNoColor bool `yaml:"log_no_color,omitempty"`
+	// BindAddress is the host address published ports are bound to.
+	BindAddress string `yaml:"bind_address,omitempty"`
+	// AllowEgress lets deployments reach the internet.
+	AllowEgress bool `yaml:"allow_egress,omitempty"`
+	// AttackerImage is the toolbox image of attacker workstations.
+	AttackerImage string `yaml:"attacker_image,omitempty"`
+
+	// File is the configuration file the configuration was loaded from.
+	File string `yaml:"-"`
+}
+
+// Repository is a template source, either a git repository or a local directory.
+type Repository struct {
+	Name string `yaml:"name"`
+	// URL is the git URL of the repository, empty for local directories.
+	URL string `yaml:"url,omitempty"`
+	// Path is the local template directory, or the clone directory of a git
+	// repository when it should not be cloned into RepositoriesPath.
+	Path string `yaml:"path,omitempty"`
+	// Priority decides which repository wins when several provide the same
+	// template ID. The highest priority wins, ties go to the first listed.
+	Priority int `yaml:"priority,omitempty"`
+	// Ref is the branch, tag or commit of a git repository to check out,
+	// empty for its default branch.
+	Ref string `yaml:"ref,omitempty"`
 }
 
 // App is the dependency container for the application.
 type App struct {
 	Templates    map[string]template.Template
+	Scenarios    map[string]template.Scenario
 	Providers    map[string]provider.Provider
 	StateManager *state.Manager
 	Config       *Config
 }
 
+// DefaultAttackerImage is the toolbox image vt builds for attacker
+// workstations, providing curl, sqlmap, nmap and netcat.
+const DefaultAttackerImage = provider.DefaultAttackerImage
+
 // DefaultConfig returns the default application configuration.
 func DefaultConfig() *Config {
 	homeDir, err := os.UserHomeDir()
@@ -33,21 +74,30 @@ func DefaultConfig() *Config {
 	}
 
 	return &Config{
-		TemplatesPath: filepath.Join(homeDir, "vt-templates"),
-		StoragePath:   filepath.Join(homeDir, ".vt-cli"),
+		TemplatesPath:    filepath.Join(homeDir, "vt-templates"),
+		StoragePath:      filepath.Join(homeDir, ".vt"),
+		RepositoriesPath: filepath.Join(homeDir, ".vt", "repositories"),
+		DefaultProvider:  "docker-compose",
+		Repositories: []Repository{
+			{Name: "official", URL: template.TemplateRemoteRepository},
+		},
 		LogLevel:      "info",
+		BindAddress:   "127.0.0.1",
+		AttackerImage: DefaultAttackerImage,
 	}
 }
 
 // NewApp creates a new App instance with the given dependencies.
 func NewApp(
 	templates map[string]template.Template,
+	scenarios map[string]template.Scenario,
 	providers map[string]provider.Provider,
 	stateManager *state.Manager,
 	config *Config,
 ) *App {
 	return &App{
 		Templates:    templates,
+		Scenarios:    scenarios,
 		Providers:    providers,
 		StateManager: stateManager,
 		Config:       config,
@@ -59,3 +109,41 @@ func (a *App) GetProvider(name string) (provider.Provider, bool) {
 	p, ok := a.Providers[name]
 	return p, ok
 }
+
+// PrimaryRepositoryURL returns the URL of the repository cloned into TemplatesPath.
+func (c *Config) PrimaryRepositoryURL() string {
+	if len(c.Repositories) == 0 || c.Repositories[0].URL == "" {
+		return template.TemplateRemoteRepository
+	}
+	return c.Repositories[0].URL
+}
+
+// Sources returns the template sources of the configured repositories. The
+// primary repository is cloned into TemplatesPath and the other git
+// repositories into a directory named after them in RepositoriesPath.
+func (c *Config) Sources() []template.Source {
+	repositories := c.Repositories
+	if len(repositories) == 0 {
+		repositories = []Repository{{Name: "official", URL: template.TemplateRemoteRepository}}
+	}
+
+	sources := make([]template.Source, 0, len(repositories))
+	for i, repository := range repositories {
+		source := template.Source{
+			Name:     repository.Name,
+			URL:      repository.URL,
+			Path:     expandHome(repository.Path),
+			Priority: repository.Priority,
+			Ref:      repository.Ref,
+		}
+		if source.Path == "" {
+			if i == 0 {
+				source.Path = c.TemplatesPath
+			} else {
+				source.Path = filepath.Join(c.RepositoriesPath, repository.Name)
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Global command line flags read while loading the configuration.
+const (
+	FlagConfig        = "config"
+	FlagTemplatesPath = "templates-path"
+	FlagStateDir      = "state-dir"
+	FlagVerbosity     = "verbosity"
+	FlagNoColor       = "no-color"
+)
+
+// Environment variables overriding the configuration file.
+const (
+	EnvConfig          = "VT_CONFIG"
+	EnvTemplatesPath   = "VT_TEMPLATES_PATH"
+	EnvStateDir        = "VT_STATE_DIR"
+	EnvDefaultProvider = "VT_DEFAULT_PROVIDER"
+	EnvRepositories    = "VT_REPOSITORIES"
+	EnvRepositoriesDir = "VT_REPOSITORIES_PATH"
+	EnvLogLevel        = "VT_LOG_LEVEL"
+	EnvNoColor         = "VT_NO_COLOR"
+	EnvBindAddress     = "VT_BIND_ADDRESS"
+	EnvAllowEgress     = "VT_ALLOW_EGRESS"
+	EnvAttackerImage   = "VT_ATTACKER_IMAGE"
+)
+
+// DefaultConfigPath returns the location of the configuration file,
+// $XDG_CONFIG_HOME/vt/config.yaml or ~/.config/vt/config.yaml.
+func DefaultConfigPath() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			homeDir = "."
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, "vt", "config.yaml")
+}
+
+// LoadConfig builds the configuration from the defaults, the configuration
+// file, VT_* environment variables and the global command line flags found in
+// args, each layer overriding the previous one. The default configuration file
+// is optional, a file given with --config or VT_CONFIG must exist.
+func LoadConfig(args []string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	flags := newConfigFlagSet()
+	if err := flags.Parse(args); err != nil && !errors.Is(err, pflag.ErrHelp) {
+		return nil, err
+	}
+
+	path, explicit := DefaultConfigPath(), false
+	if value, ok := os.LookupEnv(EnvConfig); ok {
+		path, explicit = value, true
+	}
+	if flags.Changed(FlagConfig) {
+		path, _ = flags.GetString(FlagConfig) //nolint:errcheck
+		explicit = true
+	}
+
+	cfg.File = expandHome(path)
+	if err := cfg.loadFile(cfg.File, explicit); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if flags.Changed(FlagTemplatesPath) {
+		cfg.TemplatesPath, _ = flags.GetString(FlagTemplatesPath) //nolint:errcheck
+	}
+	if flags.Changed(FlagStateDir) {
+		cfg.StoragePath, _ = flags.GetString(FlagStateDir) //nolint:errcheck
+	}
+	if flags.Changed(FlagVerbosity) {
+		cfg.LogLevel, _ = flags.GetString(FlagVerbosity) //nolint:errcheck
+	}
+	if flags.Changed(FlagNoColor) {
+		cfg.LogNoColor, _ = flags.GetBool(FlagNoColor) //nolint:errcheck
+	}
+
+	cfg.TemplatesPath = expandHome(cfg.TemplatesPath)
+	cfg.StoragePath = expandHome(cfg.StoragePath)
+	cfg.RepositoriesPath = expandHome(cfg.RepositoriesPath)
+
+	if err := ValidateRepositories(cfg.Repositories); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateBindAddress(cfg.BindAddress); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// newConfigFlagSet returns a flag set knowing only the global flags that
+// affect the configuration. Command specific flags are ignored.
+func newConfigFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("vt", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+
+	flags.String(FlagConfig, "", "")
+	flags.String(FlagTemplatesPath, "", "")
+	flags.String(FlagStateDir, "", "")
+	flags.StringP(FlagVerbosity, "v", "", "")
+	flags.Bool(FlagNoColor, false, "")
+
+	return flags
+}
+
+// loadFile merges the YAML configuration file at path into the configuration.
+func (c *Config) loadFile(path string, required bool) error {
+	file, err := os.Open(path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	fillRepositoryNames(c.Repositories)
+	return nil
+}
+
+// loadEnv merges the VT_* environment variables into the configuration.
+func (c *Config) loadEnv() error {
+	if value, ok := os.LookupEnv(EnvTemplatesPath); ok {
+		c.TemplatesPath = value
+	}
+	if value, ok := os.LookupEnv(EnvStateDir); ok {
+		c.StoragePath = value
+	}
+	if value, ok := os.LookupEnv(EnvRepositoriesDir); ok {
+		c.RepositoriesPath = value
+	}
+	if value, ok := os.LookupEnv(EnvDefaultProvider); ok {
+		c.DefaultProvider = value
+	}
+	if value, ok := os.LookupEnv(EnvLogLevel); ok {
+		c.LogLevel = value
+	}
+	if value, ok := os.LookupEnv(EnvNoColor); ok {
+		noColor, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: %w", EnvNoColor, value, err)
+		}
+		c.LogNoColor = noColor
+	}
+	if value, ok := os.LookupEnv(EnvBindAddress); ok {
+		c.BindAddress = value
+	}
+	if value, ok := os.LookupEnv(EnvAttackerImage); ok {
+		c.AttackerImage = value
+	}
+	if value, ok := os.LookupEnv(EnvAllowEgress); ok {
+		allowEgress, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: %w", EnvAllowEgress, value, err)
+		}
+		c.AllowEgress = allowEgress
+	}
+	if value, ok := os.LookupEnv(EnvRepositories); ok {
+		c.Repositories = nil
+		for _, url := range strings.Split(value, ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				c.Repositories = append(c.Repositories, Repository{URL: url})
+			}
+		}
+		fillRepositoryNames(c.Repositories)
+	}
+	return nil
+}
+
+// ReadConfigFile reads the configuration file at path without applying the
+// defaults, so that it can be changed and written back with Save. A missing
+// file yields an empty configuration.
+func ReadConfigFile(path string) (*Config, error) {
+	cfg := &Config{File: path}
+	if err := cfg.loadFile(path, false); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration to its configuration file.
+func (c *Config) Save() error {
+	if c.File == "" {
+		return errors.New("configuration has no file")
+	}
+
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(c.File, data.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+var repositoryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// invalidRepositoryNameChars matches the runs of characters repository names
+// can not contain.
+var invalidRepositoryNameChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// ValidateRepositories checks that every repository has a unique name usable
+// as a directory name and either a URL or a path. Only git repositories can
+// have a ref.
+func ValidateRepositories(repositories []Repository) error {
+	seen := make(map[string]bool, len(repositories))
+	for _, repository := range repositories {
+		if !repositoryNamePattern.MatchString(repository.Name) {
+			return fmt.Errorf("invalid repository name %q: must start with a lowercase letter or digit and contain only lowercase letters, digits, '.', '_' or '-'", repository.Name)
+		}
+		if seen[repository.Name] {
+			return fmt.Errorf("duplicate repository name %q", repository.Name)
+		}
+		seen[repository.Name] = true
+		if repository.URL == "" && repository.Path == "" {
+			return fmt.Errorf("repository %q needs a url or a path", repository.Name)
+		}
+		if repository.URL == "" && repository.Ref != "" {
+			return fmt.Errorf("repository %q is a local directory and can not have a ref", repository.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateBindAddress checks that address is an IP address, or empty to keep
+// the addresses templates declare.
+func ValidateBindAddress(address string) error {
+	if address != "" && net.ParseIP(address) == nil {
+		return fmt.Errorf("invalid bind address %q: must be an IP address", address)
+	}
+	return nil
+}
+
+// fillRepositoryNames names the repositories without a name, which
+// configuration files written before repositories had names contain, after
+// their URL or path. A numeric suffix keeps derived names unique.
+func fillRepositoryNames(repositories []Repository) {
+	taken := make(map[string]bool, len(repositories))
+	for _, repository := range repositories {
+		taken[repository.Name] = true
+	}
+	for i, repository := range repositories {
+		if repository.Name != "" {
+			continue
+		}
+		source := repository.URL
+		if source == "" {
+			source = repository.Path
+		}
+		base := repositoryName(source)
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[name] = true
+		repositories[i].Name = name
+	}
+}
+
+// repositoryName derives a valid repository name from the last element of
+// its URL, replacing the characters a name can not contain with '-'.
+func repositoryName(url string) string {
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(url, "/")), ".git")
+	name = invalidRepositoryNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-_.")
+	if name == "" {
+		return "repository"
+	}
+	return name
+}
+
+// expandHome replaces a leading ~ with the home directory of the user.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+}
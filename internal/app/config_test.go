@@ -0,0 +1,224 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `templates_path: ~/labs
+default_provider: podman
+log_level: warn
+repositories:
+  - name: internal
+    url: https://git.example.com/labs.git
+`
+
+// setupConfigHome isolates the home and config directories and clears VT_* variables.
+func setupConfigHome(t *testing.T) string {
+	t.Helper()
+
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, ".config"))
+	for _, name := range []string{EnvConfig, EnvTemplatesPath, EnvStateDir, EnvDefaultProvider, EnvRepositories, EnvRepositoriesDir, EnvLogLevel, EnvNoColor, EnvBindAddress, EnvAllowEgress, EnvAttackerImage} {
+		t.Setenv(name, "")
+		require.NoError(t, os.Unsetenv(name))
+	}
+
+	return homeDir
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	homeDir := setupConfigHome(t)
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(homeDir, "vt-templates"), cfg.TemplatesPath)
+	assert.Equal(t, filepath.Join(homeDir, ".vt"), cfg.StoragePath)
+	assert.Equal(t, "docker-compose", cfg.DefaultProvider)
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.Equal(t, "127.0.0.1", cfg.BindAddress)
+	assert.False(t, cfg.AllowEgress)
+	assert.Equal(t, DefaultAttackerImage, cfg.AttackerImage)
+	assert.Equal(t, DefaultConfig().PrimaryRepositoryURL(), cfg.PrimaryRepositoryURL())
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	homeDir := setupConfigHome(t)
+	writeConfigFile(t, DefaultConfigPath(), testConfigFile)
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(homeDir, "labs"), cfg.TemplatesPath)
+	assert.Equal(t, "podman", cfg.DefaultProvider)
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, "https://git.example.com/labs.git", cfg.PrimaryRepositoryURL())
+
+	// environment variables override the file
+	t.Setenv(EnvDefaultProvider, "kubernetes")
+	t.Setenv(EnvLogLevel, "error")
+	t.Setenv(EnvNoColor, "true")
+	t.Setenv(EnvBindAddress, "10.0.0.5")
+	t.Setenv(EnvAllowEgress, "1")
+	cfg, err = LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "kubernetes", cfg.DefaultProvider)
+	assert.Equal(t, "error", cfg.LogLevel)
+	assert.True(t, cfg.LogNoColor)
+	assert.Equal(t, "10.0.0.5", cfg.BindAddress)
+	assert.True(t, cfg.AllowEgress)
+
+	t.Setenv(EnvBindAddress, "localhost")
+	_, err = LoadConfig(nil)
+	assert.EqualError(t, err, `invalid bind address "localhost": must be an IP address`)
+	t.Setenv(EnvBindAddress, "10.0.0.5")
+
+	// flags override the environment, command specific flags are ignored
+	t.Setenv(EnvStateDir, "/var/lib/vt")
+	cfg, err = LoadConfig([]string{"start", "--id", "vt-dvwa", "-v", "debug", "--templates-path", "/srv/labs", "-o", "json"})
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/vt", cfg.StoragePath)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, "/srv/labs", cfg.TemplatesPath)
+	assert.Equal(t, "kubernetes", cfg.DefaultProvider)
+
+	cfg, err = LoadConfig([]string{"ps", "--state-dir=/tmp/project-state"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/project-state", cfg.StoragePath)
+}
+
+func TestLoadConfigExplicitFile(t *testing.T) {
+	homeDir := setupConfigHome(t)
+
+	path := filepath.Join(homeDir, "custom.yaml")
+	_, err := LoadConfig([]string{"--config", path})
+	assert.Error(t, err, "an explicit config file must exist")
+
+	writeConfigFile(t, path, "default_provider: podman\n")
+	cfg, err := LoadConfig([]string{"ps", "--config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "podman", cfg.DefaultProvider)
+
+	writeConfigFile(t, path, "default_providr: podman\n")
+	_, err = LoadConfig([]string{"--config", path})
+	assert.Error(t, err, "unknown keys must be reported")
+}
+
+func TestConfigSources(t *testing.T) {
+	homeDir := setupConfigHome(t)
+	writeConfigFile(t, DefaultConfigPath(), `repositories:
+  - name: official
+    url: https://github.com/HappyHackingSpace/vt-templates
+  - name: internal
+    url: https://git.example.com/labs.git
+    ref: v1.2.0
+    priority: 10
+  - name: local
+    path: ~/labs
+`)
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfigPath(), cfg.File)
+	assert.Equal(t, filepath.Join(filepath.Dir(DefaultConfigPath()), LockFileName), cfg.LockPath())
+
+	sources := cfg.Sources()
+	require.Len(t, sources, 3)
+	assert.Equal(t, cfg.TemplatesPath, sources[0].Path)
+	assert.Equal(t, filepath.Join(homeDir, ".vt", "repositories", "internal"), sources[1].Path)
+	assert.Equal(t, 10, sources[1].Priority)
+	assert.Equal(t, "v1.2.0", sources[1].Ref)
+	assert.Equal(t, filepath.Join(homeDir, "labs"), sources[2].Path)
+	assert.True(t, sources[2].IsLocal())
+
+	t.Setenv(EnvRepositories, "https://git.example.com/Labs.git, https://git.example.com/ctf/")
+	cfg, err = LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Repository{
+		{Name: "labs", URL: "https://git.example.com/Labs.git"},
+		{Name: "ctf", URL: "https://git.example.com/ctf/"},
+	}, cfg.Repositories)
+}
+
+func TestConfigRepositoriesWithoutName(t *testing.T) {
+	homeDir := setupConfigHome(t)
+	// repositories had no name when the configuration file was introduced
+	writeConfigFile(t, DefaultConfigPath(), `repositories:
+  - url: https://github.com/HappyHackingSpace/vt-templates
+  - url: https://git.example.com/Labs.git
+  - path: ~/ctf
+`)
+
+	cfg, err := LoadConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Repository{
+		{Name: "vt-templates", URL: "https://github.com/HappyHackingSpace/vt-templates"},
+		{Name: "labs", URL: "https://git.example.com/Labs.git"},
+		{Name: "ctf", Path: "~/ctf"},
+	}, cfg.Repositories)
+	assert.Equal(t, filepath.Join(homeDir, ".vt", "repositories", "labs"), cfg.Sources()[1].Path)
+
+	fileCfg, err := ReadConfigFile(DefaultConfigPath())
+	require.NoError(t, err)
+	assert.NoError(t, ValidateRepositories(fileCfg.Repositories), "repo add must accept the file too")
+}
+
+func TestFillRepositoryNames(t *testing.T) {
+	repositories := []Repository{
+		{URL: "https://git.example.com/labs"},
+		{URL: "https://git.example.com/team/labs.git"},
+		{Path: "~/My Labs"},
+		{Path: "/srv/.hidden/"},
+		{Path: "/srv/labs"},
+		{Name: "labs-3", Path: "/opt/labs"},
+	}
+
+	fillRepositoryNames(repositories)
+	assert.Equal(t, []string{"labs", "labs-2", "my-labs", "hidden", "labs-4", "labs-3"}, []string{
+		repositories[0].Name, repositories[1].Name, repositories[2].Name,
+		repositories[3].Name, repositories[4].Name, repositories[5].Name,
+	})
+	assert.NoError(t, ValidateRepositories(repositories))
+}
+
+func TestValidateRepositories(t *testing.T) {
+	assert.NoError(t, ValidateRepositories(DefaultConfig().Repositories))
+	assert.Error(t, ValidateRepositories([]Repository{{Name: "../labs", URL: "https://git.example.com/labs.git"}}))
+	assert.Error(t, ValidateRepositories([]Repository{{Name: "labs"}}))
+	assert.Error(t, ValidateRepositories([]Repository{{Name: "labs", Path: "/srv/labs", Ref: "main"}}))
+	assert.Error(t, ValidateRepositories([]Repository{
+		{Name: "labs", URL: "https://git.example.com/labs.git"},
+		{Name: "labs", Path: "/srv/labs"},
+	}))
+}
+
+func TestConfigFileSave(t *testing.T) {
+	homeDir := setupConfigHome(t)
+	path := filepath.Join(homeDir, "config", "vt.yaml")
+
+	cfg, err := ReadConfigFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Repositories)
+
+	cfg.Repositories = append(DefaultConfig().Repositories, Repository{Name: "local", Path: "/srv/labs", Priority: 5})
+	require.NoError(t, cfg.Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "storage_path", "defaults must not be written to the file")
+
+	saved, err := ReadConfigFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Repositories, saved.Repositories)
+}
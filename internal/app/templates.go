@@ -0,0 +1,71 @@
+package app
+
+import (
+	"path/filepath"
+
+	"github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
+)
+
+// LockFileName is the name of the lock file kept next to the configuration file.
+const LockFileName = "templates.lock"
+
+// LockPath returns the path of the lock file recording the commit of each git
+// template repository.
+func (c *Config) LockPath() string {
+	if c.File == "" {
+		return filepath.Join(c.StoragePath, LockFileName)
+	}
+	return filepath.Join(filepath.Dir(c.File), LockFileName)
+}
+
+// LoadTemplates loads the templates of the configured repositories, checking
+// out git repositories at their locked commit. Repositories cloned for the
+// first time are added to the lock file.
+func (c *Config) LoadTemplates() (map[string]template.Template, []template.Collision, error) {
+	lock, err := template.ReadLock(c.LockPath())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := lock.Pin(c.Sources())
+	templates, collisions, err := template.LoadSources(sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.saveLock(lock, sources)
+	return templates, collisions, nil
+}
+
+// LoadScenarios loads the scenarios of the configured repositories, which must
+// have been checked out by LoadTemplates.
+func (c *Config) LoadScenarios() (map[string]template.Scenario, error) {
+	return template.LoadScenarios(c.Sources())
+}
+
+// UpdateTemplates checks out the latest commit of the ref of every git
+// repository, records the new commits in the lock file and reports the
+// templates that changed.
+func (c *Config) UpdateTemplates() ([]template.Update, error) {
+	lock, err := template.ReadLock(c.LockPath())
+	if err != nil {
+		return nil, err
+	}
+
+	sources := c.Sources()
+	updates, err := template.UpdateSources(sources)
+	c.saveLock(lock, sources)
+	return updates, err
+}
+
+// saveLock records the commits checked out for sources in the lock file. The
+// lock file is only written when it changed.
+func (c *Config) saveLock(lock *template.Lock, sources []template.Source) {
+	if !lock.Record(sources) {
+		return
+	}
+	if err := lock.Save(c.LockPath()); err != nil {
+		log.Warn().Err(err).Msg("failed to write the template lock file")
+	}
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// newAttackerCommand creates the attacker command.
+func (c *CLI) newAttackerCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attacker",
+		Short: "Attack deployed environments from a workstation inside their network",
+	}
+
+	cmd.AddCommand(c.newAttackerStartCommand())
+	cmd.AddCommand(c.newAttackerShellCommand())
+	cmd.AddCommand(c.newAttackerStopCommand())
+
+	return cmd
+}
+
+// newAttackerStartCommand creates the attacker start command.
+func (c *CLI) newAttackerStartCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start --for <instance> [--image <image>]",
+		Short: "Start an attacker workstation on the network of a deployed environment",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			image, err := cmd.Flags().GetString("image")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			attacher, template, name := c.resolveAttacher(cmd)
+
+			err = attacher.StartAttacher(template, name, provider.AttackerOptions{Image: image})
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			log.Info().Msgf("attacker workstation for %s is running, open it with vt attacker shell --for %s", name, name)
+
+			attacker, err := c.app.StateManager.GetAttacker(attacher.Name(), name)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			if len(attacker.Hostnames) > 0 {
+				fmt.Printf("  services: %s\n", strings.Join(attacker.Hostnames, ", "))
+			}
+		},
+	}
+
+	c.addAttackerFlags(cmd)
+	cmd.Flags().String("image", c.app.Config.AttackerImage,
+		"Toolbox image of the workstation")
+
+	return cmd
+}
+
+// newAttackerShellCommand creates the attacker shell command.
+func (c *CLI) newAttackerShellCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell --for <instance>",
+		Short: "Open an interactive shell in the attacker workstation of a deployed environment",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			shell, err := cmd.Flags().GetString("shell")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			command := defaultShellCommand
+			if shell != "" {
+				command = []string{shell}
+			}
+
+			attacher, _, name := c.resolveAttacher(cmd)
+
+			exitCode, err := attacher.ExecAttacher(name, provider.ExecOptions{
+				Command:     command,
+				Tty:         term.IsTerminal(int(os.Stdin.Fd())), // #nosec G115
+				Interactive: true,
+			})
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+		},
+	}
+
+	c.addAttackerFlags(cmd)
+	cmd.Flags().String("shell", "", "Shell to start (defaults to bash, falling back to sh)")
+
+	return cmd
+}
+
+// newAttackerStopCommand creates the attacker stop command.
+func (c *CLI) newAttackerStopCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop --for <instance>",
+		Short: "Remove the attacker workstation of a deployed environment",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			attacher, _, name := c.resolveAttacher(cmd)
+
+			if err := attacher.StopAttacher(name); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			log.Info().Msgf("attacker workstation for %s is removed", name)
+		},
+	}
+
+	c.addAttackerFlags(cmd)
+
+	return cmd
+}
+
+// addAttackerFlags registers the flags selecting the deployment of an attacker workstation.
+func (c *CLI) addAttackerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("provider", "p", c.app.Config.DefaultProvider,
+		fmt.Sprintf("Specify the provider of the vulnerable environment (%s)",
+			strings.Join(c.providerNames(), ", ")))
+
+	cmd.Flags().String("for", "",
+		"Instance name of the deployed environment to attack")
+
+	if err := cmd.MarkFlagRequired("for"); err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+}
+
+// resolveAttacher returns the provider, template and instance name selected
+// by the attacker flags. The provider must support attacker workstations.
+func (c *CLI) resolveAttacher(cmd *cobra.Command) (attacherProvider, *tmpl.Template, string) {
+	providerName, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	name, err := cmd.Flags().GetString("for")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	p, ok := c.app.GetProvider(providerName)
+	if !ok {
+		log.Fatal().Msgf("provider %s not found", providerName)
+	}
+
+	attacher, ok := p.(attacherProvider)
+	if !ok {
+		log.Fatal().Msgf("provider %s does not support attacker workstations", providerName)
+	}
+
+	deployment, err := c.app.StateManager.GetDeployment(providerName, name)
+	if err != nil {
+		log.Fatal().Msgf("instance %s not found on %s", name, providerName)
+	}
+
+	template, err := tmpl.GetByID(c.app.Templates, deployment.TemplateID)
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	return attacher, template, name
+}
+
+// attacherProvider is a provider supporting attacker workstations.
+type attacherProvider interface {
+	provider.Provider
+	provider.Attacher
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Batch result statuses reported in the summary table.
+const (
+	batchStatusSucceeded = "succeeded"
+	batchStatusFailed    = "failed"
+	batchStatusSkipped   = "skipped"
+)
+
+// batchResult holds the outcome of a provider operation for a single template.
+type batchResult struct {
+	TemplateID string
+	Status     string
+	Message    string
+}
+
+// parseTags splits a comma separated tag list, dropping empty entries.
+func parseTags(rawTags string) []string {
+	var tags []string
+	for _, tag := range strings.Split(rawTags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// templatesByTags resolves a comma separated tag list to the matching templates.
+func (c *CLI) templatesByTags(rawTags string) ([]tmpl.Template, error) {
+	tags := parseTags(rawTags)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags specified")
+	}
+
+	templates := tmpl.FilterByTags(c.app.Templates, tags)
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no templates found with tags matching '%s'", strings.Join(tags, ", "))
+	}
+
+	return templates, nil
+}
+
+// renderBatchSummary prints the results of a batch operation as a table
+// and returns the number of failed templates.
+func renderBatchSummary(action string, results []batchResult) int {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Template ID", "Result", "Message"})
+
+	failed, succeeded := 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case batchStatusFailed:
+			failed++
+		case batchStatusSucceeded:
+			succeeded++
+		}
+		t.AppendRow(table.Row{result.TemplateID, result.Status, result.Message})
+	}
+
+	t.SetCaption("%s %d of %d templates (%d failed)", action, succeeded, len(results), failed)
+	t.Render()
+
+	return failed
+}
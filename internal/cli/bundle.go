@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/happyhackingspace/vt/internal/app"
+	"github.com/happyhackingspace/vt/pkg/bundle"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newBundleCommand creates the bundle command.
+func (c *CLI) newBundleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Pack templates and their images for hosts without network access",
+	}
+
+	cmd.AddCommand(c.newBundleCreateCommand())
+	cmd.AddCommand(c.newBundleImportCommand())
+
+	return cmd
+}
+
+// newBundleCreateCommand creates the bundle create command.
+func (c *CLI) newBundleCreateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Pack templates and the container images they run into an archive",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			ids, err := cmd.Flags().GetStringSlice("ids")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			output, err := cmd.Flags().GetString("file")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			p, archiver := c.imageArchiver(cmd)
+
+			var templates []tmpl.Template
+			var images []string
+			for _, id := range ids {
+				template, err := tmpl.GetByID(c.app.Templates, strings.TrimSpace(id))
+				if err != nil {
+					log.Fatal().Msgf("%v", err)
+				}
+				templateImages, err := archiver.Images(template)
+				if err != nil {
+					log.Fatal().Msgf("failed to list the images of %s: %v", template.ID, err)
+				}
+				templates = append(templates, *template)
+				images = append(images, templateImages...)
+			}
+			slices.Sort(images)
+			images = slices.Compact(images)
+
+			file, err := os.Create(output) // #nosec G304
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			log.Info().Msgf("bundling %d templates and %d images", len(templates), len(images))
+			_, err = bundle.Create(file, templates, p.Name(), images, func(w io.Writer) error {
+				return archiver.SaveImages(images, w)
+			})
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(output) //nolint:errcheck
+				log.Fatal().Msgf("%v", err)
+			}
+
+			log.Info().Msgf("bundle written to %s", output)
+		},
+	}
+
+	c.addProviderFlag(cmd)
+	cmd.Flags().StringSlice("ids", nil, "Template IDs to bundle (comma separated)")
+	cmd.Flags().StringP("file", "f", "vt-bundle.tar.gz", "Path of the bundle archive")
+	if err := cmd.MarkFlagRequired("ids"); err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	return cmd
+}
+
+// newBundleImportCommand creates the bundle import command.
+func (c *CLI) newBundleImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <bundle>",
+		Short: "Load the images of a bundle and register its templates as a repository",
+		Args:  cobra.ExactArgs(1),
+		// hosts without network access can not clone the repositories before importing
+		Annotations: map[string]string{annotationWithoutTemplates: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			repoName, err := cmd.Flags().GetString("repo")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			// templates are imported into a local repository, created on the first import
+			target := filepath.Join(c.app.Config.RepositoriesPath, repoName)
+			fileCfg := c.readConfigFile()
+			index := slices.IndexFunc(fileCfg.Repositories, func(r app.Repository) bool { return r.Name == repoName })
+			if index >= 0 && fileCfg.Repositories[index].Path != target {
+				log.Fatal().Msgf("repository %s already exists and is not a bundle repository", repoName)
+			}
+			if index < 0 {
+				fileCfg.Repositories = append(fileCfg.Repositories, app.Repository{Name: repoName, Path: target})
+				if err := app.ValidateRepositories(fileCfg.Repositories); err != nil {
+					log.Fatal().Msgf("%v", err)
+				}
+			}
+
+			manifest, staging := c.extractBundle(args[0])
+			defer os.RemoveAll(staging) //nolint:errcheck
+
+			if len(manifest.Images) > 0 {
+				_, archiver := c.imageArchiver(cmd)
+				if err := loadBundleImages(archiver, filepath.Join(staging, bundle.ImagesName)); err != nil {
+					os.RemoveAll(staging) //nolint:errcheck
+					log.Fatal().Msgf("%v", err)
+				}
+			}
+
+			for _, template := range manifest.Templates {
+				dest, err := bundle.TemplateDestination(target, template)
+				if err != nil {
+					os.RemoveAll(staging) //nolint:errcheck
+					log.Fatal().Msgf("%v", err)
+				}
+				if err := replaceDirectory(filepath.Join(staging, filepath.FromSlash(template.Path)), dest); err != nil {
+					os.RemoveAll(staging) //nolint:errcheck
+					log.Fatal().Msgf("failed to import template %s: %v", template.ID, err)
+				}
+			}
+
+			if index < 0 {
+				if err := fileCfg.Save(); err != nil {
+					os.RemoveAll(staging) //nolint:errcheck
+					log.Fatal().Msgf("%v", err)
+				}
+			}
+
+			log.Info().Msgf("imported %d templates and %d images into repository %s", len(manifest.Templates), len(manifest.Images), repoName)
+		},
+	}
+
+	c.addProviderFlag(cmd)
+	cmd.Flags().String("repo", "bundle", "Name of the local repository the templates are imported into")
+
+	return cmd
+}
+
+// addProviderFlag registers the flag selecting the provider handling images.
+func (c *CLI) addProviderFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("provider", "p", c.app.Config.DefaultProvider,
+		fmt.Sprintf("Specify the provider whose images are bundled (%s)",
+			strings.Join(c.providerNames(), ", ")))
+}
+
+// imageArchiver returns the provider selected by the provider flag, which must
+// be able to save and load images.
+func (c *CLI) imageArchiver(cmd *cobra.Command) (provider.Provider, provider.ImageArchiver) {
+	providerName, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	p, ok := c.app.GetProvider(providerName)
+	if !ok {
+		log.Fatal().Msgf("provider %s not found", providerName)
+	}
+
+	archiver, ok := p.(provider.ImageArchiver)
+	if !ok {
+		log.Fatal().Msgf("provider %s does not support bundling images", providerName)
+	}
+	return p, archiver
+}
+
+// extractBundle verifies and unpacks a bundle into a staging directory next
+// to the repositories, so that templates can be moved into place.
+func (c *CLI) extractBundle(path string) (*bundle.Manifest, string) {
+	if err := os.MkdirAll(c.app.Config.RepositoriesPath, 0750); err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+	staging, err := os.MkdirTemp(c.app.Config.RepositoriesPath, ".bundle-")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	file, err := os.Open(path) // #nosec G304
+	if err != nil {
+		os.RemoveAll(staging) //nolint:errcheck
+		log.Fatal().Msgf("%v", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	manifest, err := bundle.Extract(file, staging)
+	if err != nil {
+		os.RemoveAll(staging) //nolint:errcheck
+		log.Fatal().Msgf("%v", err)
+	}
+	return manifest, staging
+}
+
+// loadBundleImages loads the image archive of a bundle.
+func loadBundleImages(archiver provider.ImageArchiver, path string) error {
+	file, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer file.Close() //nolint:errcheck
+
+	log.Info().Msg("loading images")
+	return archiver.LoadImages(file)
+}
+
+// replaceDirectory moves src to dest, replacing an existing dest.
+func replaceDirectory(src, dest string) error {
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0750); err != nil {
+		return err
+	}
+	return os.Rename(src, dest)
+}
@@ -4,6 +4,7 @@ package cli
 import (
 	"fmt"
 	"maps"
+	"os"
 	"slices"
 	"strings"
 
@@ -25,6 +26,10 @@ var logLevels = map[string]bool{
 	zerolog.PanicLevel.String(): true,
 }
 
+// annotationWithoutTemplates marks the commands able to run when the template
+// repositories could not be loaded.
+const annotationWithoutTemplates = "vt.without-templates"
+
 // CLI encapsulates the command-line interface with its dependencies.
 type CLI struct {
 	app     *app.App
@@ -47,11 +52,32 @@ func (c *CLI) setupCommands() {
 		Short:   "Create vulnerable environment",
 		Version: banner.AppVersion,
 		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
-			verbosityLevel, err := cmd.Flags().GetString("verbosity")
+			verbosityLevel, err := cmd.Flags().GetString(app.FlagVerbosity)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			noColor, err := cmd.Flags().GetBool(app.FlagNoColor)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			output, err := cmd.Flags().GetString("output")
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
-			logger.InitWithLevel(verbosityLevel)
+			if err := validateOutputFormat(output); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			// keep stdout clean for machine-readable output
+			cfg := logger.DefaultConfig()
+			cfg.Level = verbosityLevel
+			cfg.NoColor = noColor
+			if output != outputTable {
+				cfg.Output = os.Stderr
+			}
+			logger.SetGlobal(logger.New(cfg))
 		},
 		SilenceErrors: true,
 	}
@@ -65,16 +91,49 @@ func (c *CLI) setupCommands() {
 	})
 
 	// Setup root flags
-	c.rootCmd.PersistentFlags().StringP("verbosity", "v", zerolog.InfoLevel.String(),
+	c.rootCmd.PersistentFlags().StringP(app.FlagVerbosity, "v", c.app.Config.LogLevel,
 		fmt.Sprintf("Set the verbosity level for logs (%s)",
 			strings.Join(slices.Collect(maps.Keys(logLevels)), ", ")))
 
+	c.rootCmd.PersistentFlags().Bool(app.FlagNoColor, c.app.Config.LogNoColor, "Disable colored log output")
+
+	// read by app.LoadConfig before the commands run, registered here for help and validation
+	c.rootCmd.PersistentFlags().String(app.FlagConfig, "",
+		fmt.Sprintf("Path of the configuration file (default %s)", app.DefaultConfigPath()))
+	c.rootCmd.PersistentFlags().String(app.FlagTemplatesPath, c.app.Config.TemplatesPath,
+		"Directory the template repository is cloned into")
+	c.rootCmd.PersistentFlags().String(app.FlagStateDir, c.app.Config.StoragePath,
+		"Directory holding the deployment state database")
+
+	c.rootCmd.PersistentFlags().StringP("output", "o", outputTable,
+		fmt.Sprintf("Output format of listing commands (%s)", strings.Join(outputFormats, ", ")))
+
 	// Register all subcommands
 	c.rootCmd.AddCommand(c.newStartCommand())
 	c.rootCmd.AddCommand(c.newStopCommand())
 	c.rootCmd.AddCommand(c.newPsCommand())
+	c.rootCmd.AddCommand(c.newStatusCommand())
+	c.rootCmd.AddCommand(c.newLogsCommand())
+	c.rootCmd.AddCommand(c.newExecCommand())
+	c.rootCmd.AddCommand(c.newShellCommand())
 	c.rootCmd.AddCommand(c.newTemplateCommand())
 	c.rootCmd.AddCommand(c.newInspectCommand())
+	c.rootCmd.AddCommand(c.newStateCommand())
+	c.rootCmd.AddCommand(c.newRepoCommand())
+	c.rootCmd.AddCommand(c.newBundleCommand())
+	c.rootCmd.AddCommand(c.newFlagCommand())
+	c.rootCmd.AddCommand(c.newVerifyCommand())
+	c.rootCmd.AddCommand(c.newAttackerCommand())
+	c.rootCmd.AddCommand(c.newScenarioCommand())
+	c.rootCmd.AddCommand(c.newReapCommand())
+	c.rootCmd.AddCommand(c.newPruneCommand())
+}
+
+// RunsWithoutTemplates reports whether the command selected by args can run
+// when the template repositories could not be loaded.
+func (c *CLI) RunsWithoutTemplates(args []string) bool {
+	cmd, _, err := c.rootCmd.Find(args)
+	return err == nil && cmd.Annotations[annotationWithoutTemplates] == "true"
 }
 
 // Run executes the CLI and returns any error.
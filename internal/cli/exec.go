@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultShellCommand starts bash when the image provides it and falls back to sh.
+var defaultShellCommand = []string{"/bin/sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+// newExecCommand creates the exec command.
+func (c *CLI) newExecCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exec (--id <template-id> | --name <instance>) [--service <service>] -- <command> [args...]",
+		Short: "Execute a command inside a running vulnerable environment",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			tty, err := cmd.Flags().GetBool("tty")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			interactive, err := cmd.Flags().GetBool("interactive")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			c.runExec(cmd, args, tty, interactive)
+		},
+	}
+
+	c.addExecFlags(cmd)
+	cmd.Flags().BoolP("tty", "t", false, "Allocate a pseudo-TTY")
+	cmd.Flags().BoolP("interactive", "i", true, "Keep STDIN attached to the command")
+
+	return cmd
+}
+
+// newShellCommand creates the shell command.
+func (c *CLI) newShellCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Open an interactive shell inside a running vulnerable environment",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			shell, err := cmd.Flags().GetString("shell")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			command := defaultShellCommand
+			if shell != "" {
+				command = []string{shell}
+			}
+
+			tty := term.IsTerminal(int(os.Stdin.Fd())) // #nosec G115
+			c.runExec(cmd, command, tty, true)
+		},
+	}
+
+	c.addExecFlags(cmd)
+	cmd.Flags().String("shell", "", "Shell to start (defaults to bash, falling back to sh)")
+
+	return cmd
+}
+
+// addExecFlags registers the flags shared by the exec and shell commands.
+func (c *CLI) addExecFlags(cmd *cobra.Command) {
+	c.addInstanceFlags(cmd)
+
+	cmd.Flags().StringP("service", "s", "",
+		"Service to run the command in (defaults to the only service of the template)")
+
+	cmd.Flags().StringP("user", "u", "", "Run the command as the given user")
+}
+
+// runExec executes command in the selected deployment and exits with its exit code.
+func (c *CLI) runExec(cmd *cobra.Command, command []string, tty, interactive bool) {
+	service, err := cmd.Flags().GetString("service")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	user, err := cmd.Flags().GetString("user")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	p, template, name := c.resolveInstance(cmd)
+
+	exitCode, err := p.Exec(template, name, provider.ExecOptions{
+		Service:     service,
+		Command:     command,
+		Tty:         tty,
+		Interactive: interactive,
+		User:        user,
+	})
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
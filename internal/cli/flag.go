@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"github.com/happyhackingspace/vt/pkg/ctf"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newFlagCommand creates the flag command.
+func (c *CLI) newFlagCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flag",
+		Short: "Work with the CTF flags of deployed environments",
+	}
+
+	cmd.AddCommand(c.newFlagSubmitCommand())
+
+	return cmd
+}
+
+// newFlagSubmitCommand creates the flag submit command.
+func (c *CLI) newFlagSubmitCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "submit (--id <template-id> | --name <instance>) <flag>",
+		Short: "Check whether a flag was captured from a deployed environment",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, template, name := c.resolveInstance(cmd)
+
+			deployment, err := c.app.StateManager.GetDeployment(p.Name(), name)
+			if err != nil {
+				log.Fatal().Msgf("instance %s not found on %s", name, p.Name())
+			}
+			if len(deployment.Flags) == 0 {
+				log.Fatal().Msgf("instance %s of %s has no flags", name, template.ID)
+			}
+
+			flagName, ok := ctf.Verify(deployment.Flags, args[0])
+			if !ok {
+				log.Fatal().Msgf("incorrect flag for %s", name)
+			}
+			log.Info().Msgf("correct, flag %s of %s captured", flagName, name)
+		},
+	}
+
+	c.addInstanceFlags(cmd)
+
+	return cmd
+}
@@ -2,34 +2,135 @@ package cli
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/happyhackingspace/vt/internal/state"
 	templ "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+// inspectOutput is the machine-readable schema of an inspect result. Instance
+// is only set when a deployed instance is inspected.
+type inspectOutput struct {
+	Instance *deploymentOutput `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Template templ.Template    `json:"template" yaml:"template"`
+}
+
 func (c *CLI) newInspectCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "inspect",
 		Short: "inspect operations",
 		Run: func(cmd *cobra.Command, _ []string) {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if name != "" {
+				c.inspectInstance(cmd)
+				return
+			}
+
 			templateID, err := cmd.Flags().GetString("id")
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
 
+			if templateID == "" {
+				log.Fatal().Msg("either --id or --name must be specified")
+			}
+
 			template, err := templ.GetByID(c.app.Templates, templateID)
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
 
-			fmt.Println(template.String())
+			if err := writeOutput(c.outputFormat(), inspectOutput{Template: *template}, template.Table()); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
 		},
 	}
-	cmd.Flags().String("id", "", "Specify a template ID for targeted vulnerable environment")
-	err := cmd.MarkFlagRequired("id")
+	c.addInstanceFlags(cmd)
+	return cmd
+}
+
+// inspectInstance prints the deployment record of an instance followed by its template.
+func (c *CLI) inspectInstance(cmd *cobra.Command) {
+	p, template, name := c.resolveInstance(cmd)
+
+	deployment, err := c.app.StateManager.GetDeployment(p.Name(), name)
 	if err != nil {
-		panic(err)
+		log.Fatal().Msgf("instance %s not found on %s", name, p.Name())
 	}
-	return cmd
+
+	status, err := p.Status(template, name)
+	if err != nil {
+		log.Error().Msgf("%v", err)
+	}
+
+	format := c.outputFormat()
+	if format == outputJSON || format == outputYAML {
+		output := inspectOutput{
+			Instance: &deploymentOutput{
+				Provider:        deployment.ProviderName,
+				Name:            deployment.Name,
+				TemplateID:      deployment.TemplateID,
+				State:           status.State,
+				ServicesRunning: status.Running(),
+				ServicesTotal:   len(status.Services),
+				Endpoints:       newEndpointOutputs(deployment.Endpoints),
+				Values:          deployment.Values,
+				CreatedAt:       deployment.CreatedAt,
+				ExpiresAt:       expiryOutput(deployment),
+			},
+			Template: *template,
+		}
+		if err := writeOutput(format, output, nil); err != nil {
+			log.Fatal().Msgf("%v", err)
+		}
+		return
+	}
+
+	// both tables have key and value columns, so they read as a single table or CSV
+	for _, t := range []table.Writer{deploymentTable(deployment, status.State), template.Table()} {
+		if err := writeOutput(format, nil, t); err != nil {
+			log.Fatal().Msgf("%v", err)
+		}
+	}
+}
+
+// deploymentTable returns the state record of a deployed instance as a two column table.
+func deploymentTable(deployment state.Deployment, status string) table.Writer {
+	urls := make([]string, 0, len(deployment.Endpoints))
+	for _, endpoint := range deployment.Endpoints {
+		urls = append(urls, fmt.Sprintf("%s: %s", endpoint.Service, endpoint.URL()))
+	}
+
+	tw := table.NewWriter()
+	tw.AppendRow(table.Row{"Instance", deployment.Name})
+	tw.AppendRow(table.Row{"Template ID", deployment.TemplateID})
+	tw.AppendRow(table.Row{"Provider", deployment.ProviderName})
+	tw.AppendRow(table.Row{"Status", status})
+	tw.AppendRow(table.Row{"Created At", deployment.CreatedAt.Format(time.DateTime)})
+	tw.AppendRow(table.Row{"Expires At", formatExpiry(deployment)})
+	tw.AppendRow(table.Row{"Endpoints", strings.Join(urls, "\n")})
+	if len(deployment.Values) > 0 {
+		names := slices.Sorted(maps.Keys(deployment.Values))
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, fmt.Sprintf("%s=%s", name, deployment.Values[name]))
+		}
+		tw.AppendRow(table.Row{"Values", strings.Join(values, "\n")})
+	}
+
+	tw.Style().Options.DrawBorder = true
+	tw.Style().Options.SeparateRows = true
+	tw.Style().Options.SeparateColumns = true
+
+	return tw
 }
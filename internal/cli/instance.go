@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// addInstanceFlags registers the flags selecting a deployed instance.
+func (c *CLI) addInstanceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("provider", "p", c.app.Config.DefaultProvider,
+		fmt.Sprintf("Specify the provider of the vulnerable environment (%s)",
+			strings.Join(c.providerNames(), ", ")))
+
+	cmd.Flags().String("id", "",
+		"Specify a template ID for targeted vulnerable environment")
+
+	cmd.Flags().String("name", "",
+		"Specify the instance name (defaults to a name derived from the template ID)")
+}
+
+// resolveInstance returns the provider, template and instance name selected by
+// the instance flags. When only --name is given the template is looked up in
+// the deployment state.
+func (c *CLI) resolveInstance(cmd *cobra.Command) (provider.Provider, *tmpl.Template, string) {
+	providerName, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	templateID, err := cmd.Flags().GetString("id")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	if templateID == "" && name == "" {
+		log.Fatal().Msg("either --id or --name must be specified")
+	}
+
+	p, ok := c.app.GetProvider(providerName)
+	if !ok {
+		log.Fatal().Msgf("provider %s not found", providerName)
+	}
+
+	if name == "" {
+		name = provider.DefaultInstanceName(templateID)
+		// instances started before names were derived from the ID are recorded under the ID
+		if exist, _ := c.app.StateManager.DeploymentExist(providerName, name); !exist { //nolint:errcheck
+			if exist, _ := c.app.StateManager.DeploymentExist(providerName, templateID); exist { //nolint:errcheck
+				name = templateID
+			}
+		}
+	}
+
+	if templateID == "" {
+		deployment, err := c.app.StateManager.GetDeployment(providerName, name)
+		if err != nil {
+			log.Fatal().Msgf("instance %s not found on %s", name, providerName)
+		}
+		templateID = deployment.TemplateID
+	}
+
+	template, err := tmpl.GetByID(c.app.Templates, templateID)
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	return p, template, name
+}
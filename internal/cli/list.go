@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"strings"
+
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -48,17 +51,30 @@ func (c *CLI) newTemplateCommand() *cobra.Command {
 			}
 
 			if list {
+				if format := c.outputFormat(); format != outputTable {
+					templates := tmpl.ListWithFilter(c.app.Templates, filter)
+					if err := writeOutput(format, templates, tmpl.ListTable(templates)); err != nil {
+						log.Error().Err(err).Msg("failed to write templates")
+					}
+					return
+				}
 				tmpl.ListTemplatesWithFilter(c.app.Templates, filter)
 				return
 			}
 
 			if update {
-				if err := tmpl.SyncTemplates(c.app.Config.TemplatesPath); err != nil {
+				updates, err := c.app.Config.UpdateTemplates()
+				if len(updates) > 0 {
+					if err := writeOutput(c.outputFormat(), updates, templateUpdatesTable(updates)); err != nil {
+						log.Error().Err(err).Msg("failed to write template changes")
+					}
+				}
+				if err != nil {
 					log.Error().Err(err).Msg("failed to sync templates")
 					return
 				}
 				// Reload templates after sync
-				templates, err := tmpl.LoadTemplates(c.app.Config.TemplatesPath)
+				templates, _, err := c.app.Config.LoadTemplates()
 				if err != nil {
 					log.Error().Err(err).Msg("failed to reload templates")
 					return
@@ -72,8 +88,42 @@ func (c *CLI) newTemplateCommand() *cobra.Command {
 	}
 
 	cmd.Flags().BoolP("list", "l", false, "List available templates")
-	cmd.Flags().BoolP("update", "u", false, "Fetch templates repository to local working directory")
+	cmd.Flags().BoolP("update", "u", false, "Fetch the latest templates of the repositories and show what changed")
 	cmd.Flags().StringP("filter", "f", "", "Filter templates by tag or keyword (only works with --list)")
 
 	return cmd
 }
+
+// templateUpdatesTable returns a table with the templates added, removed and
+// modified by the update of each repository.
+func templateUpdatesTable(updates []tmpl.Update) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.AppendHeader(table.Row{"Repository", "From", "To", "Added", "Removed", "Modified"})
+
+	changed := 0
+	for _, update := range updates {
+		if update.Changed() {
+			changed++
+		}
+		t.AppendRow(table.Row{
+			update.Source,
+			shortCommit(update.From),
+			shortCommit(update.To),
+			strings.Join(update.Added, "\n"),
+			strings.Join(update.Removed, "\n"),
+			strings.Join(update.Modified, "\n"),
+		})
+	}
+
+	t.SetCaption("%d of %d repositories changed", changed, len(updates))
+	return t
+}
+
+// shortCommit abbreviates a commit hash.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newLogsCommand creates the logs command.
+func (c *CLI) newLogsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show logs of a running vulnerable environment",
+		Run: func(cmd *cobra.Command, _ []string) {
+			services, err := cmd.Flags().GetStringSlice("service")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			follow, err := cmd.Flags().GetBool("follow")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			tail, err := cmd.Flags().GetString("tail")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			since, err := cmd.Flags().GetString("since")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			timestamps, err := cmd.Flags().GetBool("timestamps")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			p, template, name := c.resolveInstance(cmd)
+
+			err = p.Logs(template, name, provider.LogOptions{
+				Services:   services,
+				Follow:     follow,
+				Tail:       tail,
+				Since:      since,
+				Timestamps: timestamps,
+				Output:     os.Stdout,
+			})
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+		},
+	}
+
+	c.addInstanceFlags(cmd)
+
+	cmd.Flags().StringSlice("service", nil,
+		"Only show logs of the given services (repeatable or comma separated)")
+
+	cmd.Flags().BoolP("follow", "f", false, "Follow log output")
+	cmd.Flags().String("tail", "all", "Number of lines to show from the end of the logs")
+	cmd.Flags().String("since", "", "Show logs since timestamp (e.g. 2026-01-02T13:23:37Z) or relative (e.g. 42m)")
+	cmd.Flags().BoolP("timestamps", "t", false, "Show timestamps")
+
+	return cmd
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML, outputCSV}
+
+// deploymentOutput is the machine-readable schema of a deployment.
+type deploymentOutput struct {
+	Provider        string            `json:"provider" yaml:"provider"`
+	Name            string            `json:"name" yaml:"name"`
+	TemplateID      string            `json:"template_id" yaml:"template_id"`
+	State           string            `json:"state" yaml:"state"`
+	ServicesRunning int               `json:"services_running" yaml:"services_running"`
+	ServicesTotal   int               `json:"services_total" yaml:"services_total"`
+	Endpoints       []endpointOutput  `json:"endpoints" yaml:"endpoints"`
+	Values          map[string]string `json:"values,omitempty" yaml:"values,omitempty"`
+	CreatedAt       time.Time         `json:"created_at" yaml:"created_at"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// endpointOutput is the machine-readable schema of a deployment endpoint.
+type endpointOutput struct {
+	Service       string `json:"service" yaml:"service"`
+	URL           string `json:"url" yaml:"url"`
+	HostIP        string `json:"host_ip" yaml:"host_ip"`
+	HostPort      int    `json:"host_port" yaml:"host_port"`
+	ContainerPort int    `json:"container_port" yaml:"container_port"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+}
+
+// expiryOutput returns the expiry of a deployment, nil when it never expires.
+func expiryOutput(deployment state.Deployment) *time.Time {
+	if deployment.ExpiresAt.IsZero() {
+		return nil
+	}
+	return &deployment.ExpiresAt
+}
+
+// formatExpiry returns the expiry of a deployment as shown in tables.
+func formatExpiry(deployment state.Deployment) string {
+	if deployment.ExpiresAt.IsZero() {
+		return "never"
+	}
+	return deployment.ExpiresAt.Format(time.DateTime)
+}
+
+// newEndpointOutputs converts recorded endpoints to their output schema.
+func newEndpointOutputs(endpoints []state.Endpoint) []endpointOutput {
+	result := make([]endpointOutput, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		result = append(result, endpointOutput{
+			Service:       endpoint.Service,
+			URL:           endpoint.URL(),
+			HostIP:        endpoint.HostIP,
+			HostPort:      endpoint.HostPort,
+			ContainerPort: endpoint.ContainerPort,
+			Protocol:      endpoint.Protocol,
+		})
+	}
+	return result
+}
+
+// validateOutputFormat checks the value of the --output flag.
+func validateOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("invalid output format %q, must be one of: %s", format, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
+
+// outputFormat returns the value of the global --output flag.
+func (c *CLI) outputFormat() string {
+	format, err := c.rootCmd.PersistentFlags().GetString("output")
+	if err != nil {
+		return outputTable
+	}
+	return format
+}
+
+// writeOutput prints v as JSON or YAML, or renders t as a table or CSV,
+// depending on the output format.
+func writeOutput(format string, v any, t table.Writer) error {
+	return renderOutput(os.Stdout, format, v, t)
+}
+
+// renderOutput writes v as JSON or YAML, or renders t as a table or CSV to w.
+func renderOutput(w io.Writer, format string, v any, t table.Writer) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case outputCSV:
+		return renderCSV(w, t)
+	default:
+		t.SetOutputMirror(w)
+		t.Render()
+		return nil
+	}
+}
+
+// renderCSV writes the header and rows of t as RFC 4180 CSV. go-pretty
+// escapes commas and quotes of its CSV output with backslashes, but quotes its
+// TSV output the standard way, so the rows are read back from the latter. The
+// caption is not part of the data and is left out.
+func renderCSV(w io.Writer, t table.Writer) error {
+	t.SetTitle("")
+	t.SetCaption("")
+
+	reader := csv.NewReader(strings.NewReader(t.RenderTSV()))
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read table rows: %w", err)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOutput returns a deployment output and the table rendering it.
+func testOutput() (deploymentOutput, table.Writer) {
+	output := deploymentOutput{
+		Provider:        "docker-compose",
+		Name:            "dvwa",
+		TemplateID:      "vt-dvwa",
+		State:           "running",
+		ServicesRunning: 2,
+		ServicesTotal:   2,
+		Endpoints: []endpointOutput{
+			{Service: "web", URL: "http://localhost:8080", HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		},
+		Values:    map[string]string{"MOTD": `say "hi", then leave`},
+		CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Name", "Template ID", "Endpoints", "MOTD"})
+	t.AppendRow(table.Row{output.Name, output.TemplateID, "http://localhost:8080,\nhttp://localhost:8443", output.Values["MOTD"]})
+	t.SetCaption("1 deployment")
+	return output, t
+}
+
+func TestRenderOutputJSON(t *testing.T) {
+	output, tw := testOutput()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderOutput(&buf, outputJSON, []deploymentOutput{output}, tw))
+
+	assert.JSONEq(t, `[{
+		"provider": "docker-compose",
+		"name": "dvwa",
+		"template_id": "vt-dvwa",
+		"state": "running",
+		"services_running": 2,
+		"services_total": 2,
+		"endpoints": [{"service": "web", "url": "http://localhost:8080", "host_ip": "127.0.0.1", "host_port": 8080, "container_port": 80, "protocol": "tcp"}],
+		"values": {"MOTD": "say \"hi\", then leave"},
+		"created_at": "2024-01-01T12:00:00Z"
+	}]`, buf.String())
+}
+
+func TestRenderOutputYAML(t *testing.T) {
+	output, tw := testOutput()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderOutput(&buf, outputYAML, []deploymentOutput{output}, tw))
+
+	assert.YAMLEq(t, `- provider: docker-compose
+  name: dvwa
+  template_id: vt-dvwa
+  state: running
+  services_running: 2
+  services_total: 2
+  endpoints:
+    - service: web
+      url: http://localhost:8080
+      host_ip: 127.0.0.1
+      host_port: 8080
+      container_port: 80
+      protocol: tcp
+  values:
+    MOTD: say "hi", then leave
+  created_at: 2024-01-01T12:00:00Z
+`, buf.String())
+	assert.NotContains(t, buf.String(), "expires_at", "deployments that never expire have no expiry")
+}
+
+func TestRenderOutputCSV(t *testing.T) {
+	output, tw := testOutput()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderOutput(&buf, outputCSV, []deploymentOutput{output}, tw))
+
+	assert.Equal(t, "Name,Template ID,Endpoints,MOTD\n"+
+		"dvwa,vt-dvwa,\"http://localhost:8080,\nhttp://localhost:8443\",\"say \"\"hi\"\", then leave\"\n", buf.String())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Name", "Template ID", "Endpoints", "MOTD"},
+		{"dvwa", "vt-dvwa", "http://localhost:8080,\nhttp://localhost:8443", `say "hi", then leave`},
+	}, records, "the caption is not a record")
+}
+
+func TestRenderOutputTable(t *testing.T) {
+	_, tw := testOutput()
+	tw.SetStyle(table.StyleDefault)
+
+	var buf bytes.Buffer
+	require.NoError(t, renderOutput(&buf, outputTable, nil, tw))
+
+	assert.Contains(t, buf.String(), "| NAME ")
+	assert.Contains(t, buf.String(), "1 deployment")
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// prunedResource is a resource found by a provider, with the provider name.
+type prunedResource struct {
+	provider.Resource
+	ProviderName string
+}
+
+// newPruneCommand creates the prune command.
+func (c *CLI) newPruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the resources vt created that no deployment record references",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			providerName, err := cmd.Flags().GetString("provider")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			names := c.providerNames()
+			slices.Sort(names)
+			if providerName != "" {
+				if _, ok := c.app.GetProvider(providerName); !ok {
+					log.Fatal().Msgf("provider %s not found", providerName)
+				}
+				names = []string{providerName}
+			}
+
+			var resources []prunedResource
+			failed := 0
+			for _, name := range names {
+				p, _ := c.app.GetProvider(name)
+				providerResources, err := c.prune(p, dryRun)
+				if err != nil {
+					// an unreachable provider must not prevent pruning the others
+					log.Warn().Err(err).Msgf("failed to prune %s", name)
+					failed++
+				}
+				resources = append(resources, providerResources...)
+			}
+
+			if len(resources) > 0 {
+				renderPrunedResources(resources, dryRun)
+			}
+
+			if failed == len(names) {
+				log.Fatal().Msg("no provider could be pruned")
+			}
+
+			if len(resources) == 0 {
+				log.Info().Msg("there is no orphaned resource")
+			}
+		},
+	}
+
+	cmd.Flags().StringP("provider", "p", "",
+		fmt.Sprintf("Only prune the given provider (%s)",
+			strings.Join(c.providerNames(), ", ")))
+
+	cmd.Flags().Bool("dry-run", false, "Only list the orphaned resources without removing them")
+
+	return cmd
+}
+
+// prune finds the resources of the provider that its deployment records do not
+// reference and removes them. Providers that can not find their resources are
+// left as is.
+func (c *CLI) prune(p provider.Provider, dryRun bool) ([]prunedResource, error) {
+	pruner, ok := p.(provider.Pruner)
+	if !ok {
+		return nil, nil
+	}
+
+	deployments, err := c.app.StateManager.ListDeployments()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := provider.References{Instances: make(map[string]bool), Networks: make(map[string]bool)}
+	for _, deployment := range deployments {
+		if deployment.ProviderName != p.Name() {
+			continue
+		}
+		refs.Instances[deployment.Name] = true
+		if deployment.Scenario != nil {
+			for _, network := range deployment.Scenario.Networks {
+				refs.Networks[network] = true
+			}
+		}
+	}
+
+	orphans, err := pruner.Orphans(refs)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]prunedResource, 0, len(orphans))
+	for _, orphan := range orphans {
+		resources = append(resources, prunedResource{Resource: orphan, ProviderName: p.Name()})
+	}
+
+	if dryRun || len(orphans) == 0 {
+		return resources, nil
+	}
+	return resources, pruner.Prune(orphans)
+}
+
+// renderPrunedResources prints the orphaned resources as a table.
+func renderPrunedResources(resources []prunedResource, dryRun bool) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Provider Name", "Kind", "Name", "Instance"})
+
+	for _, resource := range resources {
+		t.AppendRow(table.Row{
+			resource.ProviderName,
+			resource.Kind,
+			resource.Name,
+			resource.Instance,
+		})
+	}
+
+	if dryRun {
+		t.SetCaption("%d orphaned resources found (dry run, nothing removed)", len(resources))
+	} else {
+		t.SetCaption("%d orphaned resources pruned", len(resources))
+	}
+	t.Render()
+}
@@ -1,7 +1,7 @@
 package cli
 
 import (
-	"os"
+	"strings"
 	"time"
 
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
@@ -24,10 +24,9 @@ func (c *CLI) newPsCommand() *cobra.Command {
 
 			t := table.NewWriter()
 			t.SetStyle(table.StyleDefault)
-			t.SetOutputMirror(os.Stdout)
-			t.AppendHeader(table.Row{"Provider Name", "Template ID", "Status", "Created At"})
+			t.AppendHeader(table.Row{"Provider Name", "Name", "Template ID", "Status", "Services", "Endpoints", "Created At", "Expires At"})
 
-			count := 0
+			outputs := make([]deploymentOutput, 0, len(deployments))
 			for _, deployment := range deployments {
 				provider, ok := c.app.GetProvider(deployment.ProviderName)
 				if !ok {
@@ -40,28 +39,44 @@ func (c *CLI) newPsCommand() *cobra.Command {
 					continue
 				}
 
-				status := "unknown"
-				if s, err := provider.Status(template); err != nil {
+				status, err := provider.Status(template, deployment.Name)
+				if err != nil {
 					log.Error().Msgf("%v", err)
-				} else {
-					status = s
 				}
 
 				t.AppendRow(table.Row{
 					deployment.ProviderName,
+					deployment.Name,
 					deployment.TemplateID,
-					status,
+					status.State,
+					status.Summary(),
+					strings.Join(endpointURLs(deployment.Endpoints), "\n"),
 					deployment.CreatedAt.Format(time.DateTime),
+					formatExpiry(deployment),
+				})
+
+				outputs = append(outputs, deploymentOutput{
+					Provider:        deployment.ProviderName,
+					Name:            deployment.Name,
+					TemplateID:      deployment.TemplateID,
+					State:           status.State,
+					ServicesRunning: status.Running(),
+					ServicesTotal:   len(status.Services),
+					Endpoints:       newEndpointOutputs(deployment.Endpoints),
+					CreatedAt:       deployment.CreatedAt,
+					ExpiresAt:       expiryOutput(deployment),
 				})
-				count++
 			}
 
-			if count == 0 {
+			format := c.outputFormat()
+			if len(outputs) == 0 && format == outputTable {
 				log.Info().Msg("there is no running environment")
 				return
 			}
 
-			t.Render()
+			if err := writeOutput(format, outputs, t); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
 		},
 	}
 }
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newReapCommand creates the reap command.
+func (c *CLI) newReapCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reap",
+		Short: "Stop deployments whose time to live is over",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			daemon, err := cmd.Flags().GetBool("daemon")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			interval, err := cmd.Flags().GetDuration("interval")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			if interval <= 0 {
+				log.Fatal().Msgf("invalid interval %s, must be positive", interval)
+			}
+
+			if !daemon {
+				if err := c.reap(dryRun); err != nil {
+					log.Fatal().Msgf("%v", err)
+				}
+				return
+			}
+
+			if err := c.reapDaemon(interval, dryRun); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Only log the deployments that would be stopped")
+
+	cmd.Flags().Bool("daemon", false, "Keep running and reap expired deployments every interval")
+
+	cmd.Flags().Duration("interval", time.Minute, "Time between two reaps in daemon mode")
+
+	return cmd
+}
+
+// reapDaemon reaps every interval until interrupted. The state is only open
+// during a reap, so that other vt commands can run in between.
+func (c *CLI) reapDaemon(interval time.Duration, dryRun bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := c.app.StateManager.Close(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("reaping expired deployments every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.app.StateManager.Reopen(); err != nil {
+			return err
+		}
+		err := c.reap(dryRun)
+		if closeErr := c.app.StateManager.Close(); closeErr != nil {
+			return closeErr
+		}
+		if err != nil {
+			log.Error().Msgf("%v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("reaper stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reap stops the expired deployments through their provider and logs each
+// one removed. The targets of a scenario are stopped together, as soon as one
+// of them expired. A deployment failing to stop does not keep the others
+// from being reaped.
+func (c *CLI) reap(dryRun bool) error {
+	deployments, err := c.app.StateManager.ListDeployments()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	reaped := make(map[string]bool)
+	for _, deployment := range deployments {
+		if !deployment.Expired(now) {
+			continue
+		}
+
+		key := deployment.ProviderName + "/" + deployment.Name
+		if deployment.Scenario != nil {
+			key = deployment.ProviderName + "/scenario/" + deployment.Scenario.Name
+		}
+		if reaped[key] {
+			continue
+		}
+		reaped[key] = true
+
+		if err := c.reapDeployment(deployment, dryRun); err != nil {
+			log.Error().Err(err).Msgf("failed to reap %s on %s", deployment.Name, deployment.ProviderName)
+			errs = append(errs, fmt.Errorf("%s on %s: %w", deployment.Name, deployment.ProviderName, err))
+		}
+	}
+
+	if len(reaped) == 0 {
+		log.Debug().Msg("there is no expired deployment")
+	}
+	return errors.Join(errs...)
+}
+
+// reapDeployment stops an expired deployment, or the whole scenario it belongs to.
+func (c *CLI) reapDeployment(deployment state.Deployment, dryRun bool) error {
+	p, ok := c.app.GetProvider(deployment.ProviderName)
+	if !ok {
+		return fmt.Errorf("provider %s not found", deployment.ProviderName)
+	}
+
+	expiredAt := deployment.ExpiresAt.Format(time.DateTime)
+
+	if deployment.Scenario != nil {
+		name := deployment.Scenario.Name
+		if dryRun {
+			log.Info().Msgf("would stop %s scenario on %s, expired at %s", name, p.Name(), expiredAt)
+			return nil
+		}
+		if err := c.stopScenario(p, c.scenarioDeployments(p.Name(), name)); err != nil {
+			return err
+		}
+		log.Info().Msgf("stopped %s scenario on %s, expired at %s", name, p.Name(), expiredAt)
+		return nil
+	}
+
+	if dryRun {
+		log.Info().Msgf("would stop %s instance of %s template on %s, expired at %s", deployment.Name, deployment.TemplateID, p.Name(), expiredAt)
+		return nil
+	}
+	if err := c.stopDeployment(p, deployment); err != nil {
+		return err
+	}
+	log.Info().Msgf("stopped %s instance of %s template on %s, expired at %s", deployment.Name, deployment.TemplateID, p.Name(), expiredAt)
+	return nil
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/app"
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/store/disk"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider records the deployments it starts, and removes the record of
+// the instances it stops or removes, failing for the instances in failing.
+type fakeProvider struct {
+	stateManager *state.Manager
+	failing      map[string]bool
+	stopped      []string
+	removed      []string
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) Start(template *tmpl.Template, instance string, _ provider.StartOptions) error {
+	return f.stateManager.AddNewDeployment(f.Name(), template.ID, instance)
+}
+
+func (f *fakeProvider) Stop(_ *tmpl.Template, instance string) error {
+	if f.failing[instance] {
+		return errors.New("engine unreachable")
+	}
+	f.stopped = append(f.stopped, instance)
+	return f.stateManager.RemoveDeployment(f.Name(), instance)
+}
+
+func (f *fakeProvider) Remove(instance string) error {
+	f.removed = append(f.removed, instance)
+	return f.stateManager.RemoveDeployment(f.Name(), instance)
+}
+
+func (f *fakeProvider) Status(*tmpl.Template, string) (provider.Status, error) {
+	return provider.UnknownStatus(), nil
+}
+
+func (f *fakeProvider) Logs(*tmpl.Template, string, provider.LogOptions) error { return nil }
+
+func (f *fakeProvider) Exec(*tmpl.Template, string, provider.ExecOptions) (int, error) {
+	return 0, nil
+}
+
+// newReapTestCLI returns a CLI with a fake provider on which the given
+// deployments are recorded with their expiry and scenario.
+func newReapTestCLI(t *testing.T, deployments []state.Deployment) (*CLI, *fakeProvider) {
+	t.Helper()
+
+	dir := t.TempDir()
+	sm, err := state.NewManager(
+		disk.NewConfig().WithDir(dir).WithFileName("deployments.db").WithBucketName("deployments"),
+		disk.NewConfig().WithDir(dir).WithFileName("attackers.db").WithBucketName("attackers"),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sm.Close() })
+
+	p := &fakeProvider{stateManager: sm, failing: make(map[string]bool)}
+	for _, deployment := range deployments {
+		require.NoError(t, sm.AddNewDeployment(p.Name(), deployment.TemplateID, deployment.Name))
+		require.NoError(t, sm.SetExpiry(p.Name(), deployment.Name, deployment.ExpiresAt))
+		if deployment.Scenario != nil {
+			require.NoError(t, sm.SetScenario(p.Name(), deployment.Name, *deployment.Scenario))
+		}
+	}
+
+	application := app.NewApp(
+		map[string]tmpl.Template{
+			"vt-dvwa":  {ID: "vt-dvwa"},
+			"vt-mysql": {ID: "vt-mysql"},
+		},
+		nil,
+		map[string]provider.Provider{p.Name(): p},
+		sm,
+		app.DefaultConfig(),
+	)
+	return &CLI{app: application}, p
+}
+
+// recordedNames returns the names of the deployments still recorded.
+func recordedNames(t *testing.T, c *CLI) []string {
+	t.Helper()
+
+	deployments, err := c.app.StateManager.ListDeployments()
+	require.NoError(t, err)
+
+	var names []string
+	for _, deployment := range deployments {
+		names = append(names, deployment.Name)
+	}
+	return names
+}
+
+func TestReap(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	c, p := newReapTestCLI(t, []state.Deployment{
+		{TemplateID: "vt-dvwa", Name: "expired", ExpiresAt: expired},
+		{TemplateID: "vt-dvwa", Name: "running", ExpiresAt: time.Now().Add(time.Hour)},
+		{TemplateID: "vt-dvwa", Name: "forever"},
+	})
+
+	require.NoError(t, c.reap(false))
+	assert.Equal(t, []string{"expired"}, p.stopped)
+	assert.ElementsMatch(t, []string{"running", "forever"}, recordedNames(t, c))
+}
+
+func TestReapDryRun(t *testing.T) {
+	c, p := newReapTestCLI(t, []state.Deployment{
+		{TemplateID: "vt-dvwa", Name: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+	})
+
+	require.NoError(t, c.reap(true))
+	assert.Empty(t, p.stopped)
+	assert.Equal(t, []string{"expired"}, recordedNames(t, c))
+}
+
+func TestReapScenario(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	scenario := func(target string) *state.ScenarioRef {
+		return &state.ScenarioRef{ID: "sqli-chain", Name: "sqli-chain", Target: target}
+	}
+	c, p := newReapTestCLI(t, []state.Deployment{
+		{TemplateID: "vt-mysql", Name: "sqli-chain-db", ExpiresAt: expired, Scenario: scenario("db")},
+		{TemplateID: "vt-dvwa", Name: "sqli-chain-web", ExpiresAt: expired, Scenario: scenario("web")},
+	})
+
+	require.NoError(t, c.reap(false))
+	assert.ElementsMatch(t, []string{"sqli-chain-db", "sqli-chain-web"}, p.stopped, "each target is stopped once")
+	assert.Empty(t, recordedNames(t, c))
+}
+
+func TestReapContinuesAfterFailure(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	c, p := newReapTestCLI(t, []state.Deployment{
+		{TemplateID: "vt-dvwa", Name: "broken", ExpiresAt: expired},
+		{TemplateID: "vt-dvwa", Name: "expired", ExpiresAt: expired},
+	})
+	p.failing["broken"] = true
+
+	err := c.reap(false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken on fake")
+	assert.Equal(t, []string{"expired"}, p.stopped)
+	assert.Equal(t, []string{"broken"}, recordedNames(t, c))
+}
+
+func TestReapWithoutTemplate(t *testing.T) {
+	c, p := newReapTestCLI(t, []state.Deployment{
+		{TemplateID: "vt-deleted", Name: "orphan", ExpiresAt: time.Now().Add(-time.Minute)},
+	})
+
+	require.NoError(t, c.reap(false))
+	assert.Empty(t, p.stopped)
+	assert.Equal(t, []string{"orphan"}, p.removed, "removed by its recorded name")
+	assert.Empty(t, recordedNames(t, c))
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/happyhackingspace/vt/internal/app"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// repositoryOutput is the machine-readable schema of a template repository.
+type repositoryOutput struct {
+	tmpl.Source `yaml:",inline"`
+	Templates   int `json:"templates" yaml:"templates"`
+}
+
+// repositoryListOutput is the machine-readable schema of the repo list command.
+type repositoryListOutput struct {
+	Repositories []repositoryOutput `json:"repositories" yaml:"repositories"`
+	Collisions   []tmpl.Collision   `json:"collisions" yaml:"collisions"`
+}
+
+// newRepoCommand creates the repo command.
+func (c *CLI) newRepoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repo",
+		Short: "Template repository operations",
+	}
+
+	cmd.AddCommand(c.newRepoListCommand())
+	cmd.AddCommand(c.newRepoAddCommand())
+	cmd.AddCommand(c.newRepoRemoveCommand())
+
+	return cmd
+}
+
+// newRepoListCommand creates the repo list command.
+func (c *CLI) newRepoListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the template repositories and the template IDs provided by several of them",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			templates, collisions, err := c.app.Config.LoadTemplates()
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			lock, err := tmpl.ReadLock(c.app.Config.LockPath())
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			sources := lock.Pin(c.app.Config.Sources())
+
+			counts := make(map[string]int, len(sources))
+			for _, template := range templates {
+				counts[template.Source]++
+			}
+
+			output := repositoryListOutput{Collisions: collisions}
+			t := table.NewWriter()
+			t.SetStyle(table.StyleDefault)
+			t.AppendHeader(table.Row{"Name", "Source", "Ref", "Commit", "Path", "Priority", "Templates"})
+			for _, source := range sources {
+				output.Repositories = append(output.Repositories, repositoryOutput{Source: source, Templates: counts[source.Name]})
+				t.AppendRow(table.Row{source.Name, source.Location(), source.Ref, shortCommit(source.Commit), source.Path, source.Priority, counts[source.Name]})
+			}
+
+			format := c.outputFormat()
+			if err := writeOutput(format, output, t); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if format == outputTable && len(collisions) > 0 {
+				renderCollisions(collisions)
+			}
+		},
+	}
+}
+
+// newRepoAddCommand creates the repo add command.
+func (c *CLI) newRepoAddCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <name> <git-url|directory>",
+		Short: "Register a git repository or a local directory as template repository",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			priority, err := cmd.Flags().GetInt("priority")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			ref, err := cmd.Flags().GetString("ref")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			repository := app.Repository{Name: args[0], URL: args[1], Priority: priority, Ref: ref}
+			if info, statErr := os.Stat(args[1]); statErr == nil && info.IsDir() {
+				if ref != "" {
+					log.Fatal().Msg("--ref can only be used with git repositories")
+				}
+				path, err := filepath.Abs(args[1])
+				if err != nil {
+					log.Fatal().Msgf("%v", err)
+				}
+				repository.URL, repository.Path = "", path
+			}
+
+			fileCfg := c.readConfigFile()
+			if slices.ContainsFunc(fileCfg.Repositories, func(r app.Repository) bool { return r.Name == repository.Name }) {
+				log.Fatal().Msgf("repository %s already exists", repository.Name)
+			}
+			fileCfg.Repositories = append(fileCfg.Repositories, repository)
+			if err := app.ValidateRepositories(fileCfg.Repositories); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			// fetch the repository before saving it, so that a wrong URL or ref is not persisted
+			updated := c.withRepositories(fileCfg.Repositories)
+			sources := updated.Sources()
+			source := sources[len(sources)-1]
+			if !source.IsLocal() {
+				if _, err := tmpl.UpdateSources([]tmpl.Source{source}); err != nil {
+					log.Fatal().Msgf("%v", err)
+				}
+			}
+			templates, _, err := tmpl.LoadSources([]tmpl.Source{source})
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if err := fileCfg.Save(); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			log.Info().Msgf("repository %s added with %d templates", repository.Name, len(templates))
+
+			// loading every repository locks the new one and reports the collisions it causes
+			if _, collisions, err := updated.LoadTemplates(); err == nil {
+				for _, collision := range collisions {
+					log.Warn().Msg(collision.String())
+				}
+			}
+		},
+	}
+
+	cmd.Flags().Int("priority", 0, "Priority of the repository, the highest wins when template IDs collide")
+	cmd.Flags().String("ref", "", "Branch, tag or commit of the git repository to check out (default branch when empty)")
+
+	return cmd
+}
+
+// newRepoRemoveCommand creates the repo remove command.
+func (c *CLI) newRepoRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Unregister a template repository and delete its clone",
+		Args:  cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			name := args[0]
+
+			fileCfg := c.readConfigFile()
+			index := slices.IndexFunc(fileCfg.Repositories, func(r app.Repository) bool { return r.Name == name })
+			if index < 0 {
+				log.Fatal().Msgf("repository %s not found", name)
+			}
+			if index == 0 {
+				log.Fatal().Msgf("repository %s is the primary repository and can not be removed", name)
+			}
+
+			source := c.withRepositories(fileCfg.Repositories).Sources()[index]
+			repository := fileCfg.Repositories[index]
+			fileCfg.Repositories = slices.Delete(fileCfg.Repositories, index, index+1)
+			if err := fileCfg.Save(); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			// only clones managed by vt are deleted, never a local template directory
+			if !source.IsLocal() && repository.Path == "" {
+				if err := os.RemoveAll(source.Path); err != nil {
+					log.Fatal().Msgf("failed to delete %s: %v", source.Path, err)
+				}
+			}
+
+			// drop the repository from the lock file
+			if _, _, err := c.withRepositories(fileCfg.Repositories).LoadTemplates(); err != nil {
+				log.Warn().Err(err).Msg("failed to update the template lock file")
+			}
+			log.Info().Msgf("repository %s removed", name)
+		},
+	}
+}
+
+// readConfigFile reads the configuration file for editing. When the file does
+// not list any repository yet, the default repositories are listed.
+func (c *CLI) readConfigFile() *app.Config {
+	if _, ok := os.LookupEnv(app.EnvRepositories); ok {
+		log.Warn().Msgf("%s is set and overrides the repositories of the configuration file", app.EnvRepositories)
+	}
+
+	fileCfg, err := app.ReadConfigFile(c.app.Config.File)
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+	if len(fileCfg.Repositories) == 0 {
+		fileCfg.Repositories = app.DefaultConfig().Repositories
+	}
+	return fileCfg
+}
+
+// withRepositories returns a copy of the running configuration using repositories.
+func (c *CLI) withRepositories(repositories []app.Repository) *app.Config {
+	cfg := *c.app.Config
+	cfg.Repositories = repositories
+	return &cfg
+}
+
+// renderCollisions prints the template IDs provided by several repositories
+// and the repository each one is used from.
+func renderCollisions(collisions []tmpl.Collision) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Template ID", "Used From", "Shadowed"})
+	for _, collision := range collisions {
+		t.AppendRow(table.Row{collision.ID, collision.Winner, strings.Join(collision.Shadowed, ", ")})
+	}
+	t.SetCaption("%d template IDs provided by several repositories", len(collisions))
+	t.Render()
+}
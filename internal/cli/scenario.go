@@ -0,0 +1,425 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// scenarioTargetOutput is the machine-readable schema of a scenario target.
+type scenarioTargetOutput struct {
+	Scenario   string           `json:"scenario" yaml:"scenario"`
+	ScenarioID string           `json:"scenario_id" yaml:"scenario_id"`
+	Target     string           `json:"target" yaml:"target"`
+	Provider   string           `json:"provider" yaml:"provider"`
+	Name       string           `json:"name" yaml:"name"`
+	TemplateID string           `json:"template_id" yaml:"template_id"`
+	State      string           `json:"state" yaml:"state"`
+	Endpoints  []endpointOutput `json:"endpoints" yaml:"endpoints"`
+	CreatedAt  time.Time        `json:"created_at" yaml:"created_at"`
+}
+
+// newScenarioCommand creates the scenario command.
+func (c *CLI) newScenarioCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Start and stop scenarios made of several templates as one unit",
+	}
+
+	cmd.AddCommand(c.newScenarioListCommand())
+	cmd.AddCommand(c.newScenarioStartCommand())
+	cmd.AddCommand(c.newScenarioStopCommand())
+	cmd.AddCommand(c.newScenarioPsCommand())
+
+	return cmd
+}
+
+// newScenarioListCommand creates the scenario list command.
+func (c *CLI) newScenarioListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List available scenarios",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			scenarios := make([]tmpl.Scenario, 0, len(c.app.Scenarios))
+			for _, scenario := range c.app.Scenarios {
+				scenarios = append(scenarios, scenario)
+			}
+			sort.Slice(scenarios, func(i, j int) bool {
+				return scenarios[i].ID < scenarios[j].ID
+			})
+
+			format := c.outputFormat()
+			if len(scenarios) == 0 && format == outputTable {
+				log.Info().Msg("there is no scenario in the template repositories")
+				return
+			}
+
+			t := tmpl.ScenarioListTable(c.app.Scenarios)
+			t.SetStyle(table.StyleDefault)
+			if err := writeOutput(format, scenarios, t); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+		},
+	}
+}
+
+// newScenarioStartCommand creates the scenario start command.
+func (c *CLI) newScenarioStartCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start --id <scenario-id> [--name <instance>]",
+		Short: "Start every target of a scenario in dependency order",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			p, scenario, name := c.resolveScenario(cmd)
+
+			wait, err := cmd.Flags().GetBool("wait")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			ttl, err := cmd.Flags().GetDuration("ttl")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			if ttl < 0 {
+				log.Fatal().Msgf("invalid ttl %s, must not be negative", ttl)
+			}
+
+			opts := provider.StartOptions{PortStrategy: provider.PortStrategyOffset}
+			if err := startIsolation(cmd, &opts); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if err := c.startScenario(p, scenario, name, opts, wait, timeout, ttl); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			log.Info().Msgf("%s scenario is running on %s as %s", scenario.ID, p.Name(), name)
+
+			for _, deployment := range c.scenarioDeployments(p.Name(), name) {
+				for _, endpoint := range deployment.Endpoints {
+					fmt.Printf("  %s/%s: %s\n", deployment.Scenario.Target, endpoint.Service, endpoint.URL())
+				}
+			}
+		},
+	}
+
+	c.addScenarioFlags(cmd)
+
+	cmd.Flags().Bool("wait", false,
+		"Wait until the readiness probes of every target pass, not only of the ones others depend on")
+
+	cmd.Flags().Duration("timeout", 5*time.Minute,
+		"Maximum time to wait for the readiness of each target, and for flags to be planted")
+
+	cmd.Flags().Duration("ttl", 0,
+		"Stop the scenario with vt reap once it ran for this long (e.g. 2h), 0 to keep it until stopped")
+
+	c.addIsolationFlags(cmd)
+
+	return cmd
+}
+
+// newScenarioStopCommand creates the scenario stop command.
+func (c *CLI) newScenarioStopCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop (--id <scenario-id> | --name <instance>)",
+		Short: "Stop every target of a running scenario in reverse start order",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			p, _, name := c.resolveScenario(cmd)
+
+			deployments := c.scenarioDeployments(p.Name(), name)
+			if len(deployments) == 0 {
+				log.Fatal().Msgf("scenario %s not found on %s", name, p.Name())
+			}
+
+			if err := c.stopScenario(p, deployments); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			log.Info().Msgf("%s scenario stopped on %s", name, p.Name())
+		},
+	}
+
+	c.addScenarioFlags(cmd)
+
+	return cmd
+}
+
+// newScenarioPsCommand creates the scenario ps command.
+func (c *CLI) newScenarioPsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ps",
+		Short: "List running scenarios and the status of their targets",
+		Args:  cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			deployments, err := c.app.StateManager.ListDeployments()
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			sort.Slice(deployments, func(i, j int) bool {
+				if deployments[i].Scenario == nil || deployments[j].Scenario == nil {
+					return deployments[i].Scenario != nil
+				}
+				if deployments[i].Scenario.Name != deployments[j].Scenario.Name {
+					return deployments[i].Scenario.Name < deployments[j].Scenario.Name
+				}
+				return deployments[i].CreatedAt.Before(deployments[j].CreatedAt)
+			})
+
+			t := table.NewWriter()
+			t.SetStyle(table.StyleDefault)
+			t.AppendHeader(table.Row{"Scenario", "Scenario ID", "Target", "Provider Name", "Name", "Template ID", "Status", "Endpoints"})
+
+			var outputs []scenarioTargetOutput
+			for _, deployment := range deployments {
+				if deployment.Scenario == nil {
+					continue
+				}
+
+				var status provider.Status
+				p, ok := c.app.GetProvider(deployment.ProviderName)
+				template, err := tmpl.GetByID(c.app.Templates, deployment.TemplateID)
+				if ok && err == nil {
+					status, err = p.Status(template, deployment.Name)
+				}
+				if err != nil {
+					log.Error().Msgf("%v", err)
+				}
+
+				t.AppendRow(table.Row{
+					deployment.Scenario.Name,
+					deployment.Scenario.ID,
+					deployment.Scenario.Target,
+					deployment.ProviderName,
+					deployment.Name,
+					deployment.TemplateID,
+					status.State,
+					strings.Join(endpointURLs(deployment.Endpoints), "\n"),
+				})
+
+				outputs = append(outputs, scenarioTargetOutput{
+					Scenario:   deployment.Scenario.Name,
+					ScenarioID: deployment.Scenario.ID,
+					Target:     deployment.Scenario.Target,
+					Provider:   deployment.ProviderName,
+					Name:       deployment.Name,
+					TemplateID: deployment.TemplateID,
+					State:      status.State,
+					Endpoints:  newEndpointOutputs(deployment.Endpoints),
+					CreatedAt:  deployment.CreatedAt,
+				})
+			}
+
+			format := c.outputFormat()
+			if len(outputs) == 0 && format == outputTable {
+				log.Info().Msg("there is no running scenario")
+				return
+			}
+
+			if err := writeOutput(format, outputs, t); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+		},
+	}
+}
+
+// addScenarioFlags registers the flags selecting a scenario instance.
+func (c *CLI) addScenarioFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("provider", "p", c.app.Config.DefaultProvider,
+		fmt.Sprintf("Specify the provider of the scenario (%s)",
+			strings.Join(c.providerNames(), ", ")))
+
+	cmd.Flags().String("id", "", "Scenario ID")
+
+	cmd.Flags().String("name", "",
+		"Name of the scenario instance, prefixing the instance names of its targets (defaults to a name derived from the scenario ID)")
+}
+
+// resolveScenario returns the provider, scenario and scenario instance name
+// selected by the scenario flags. The scenario is nil when only --name is
+// given, as stopping does not need its definition.
+func (c *CLI) resolveScenario(cmd *cobra.Command) (provider.Provider, *tmpl.Scenario, string) {
+	providerName, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	scenarioID, err := cmd.Flags().GetString("id")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	p, ok := c.app.GetProvider(providerName)
+	if !ok {
+		log.Fatal().Msgf("provider %s not found", providerName)
+	}
+
+	if name == "" && scenarioID == "" {
+		log.Fatal().Msg("either --id or --name must be specified")
+	}
+	if name == "" {
+		name = provider.DefaultInstanceName(scenarioID)
+	} else if err := provider.ValidateInstanceName(name); err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	if scenarioID == "" {
+		return p, nil, name
+	}
+
+	scenario, err := tmpl.GetScenarioByID(c.app.Scenarios, scenarioID)
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+	return p, scenario, name
+}
+
+// startScenario starts the targets of the scenario in dependency order. The
+// targets others depend on are waited for before their dependents start, all
+// of them with wait. When a target fails to start, the started ones are
+// stopped again.
+func (c *CLI) startScenario(p provider.Provider, scenario *tmpl.Scenario, name string, opts provider.StartOptions, wait bool, timeout, ttl time.Duration) error {
+	if scenario == nil {
+		return errors.New("--id is required to start a scenario")
+	}
+	if len(c.scenarioDeployments(p.Name(), name)) > 0 {
+		return fmt.Errorf("scenario %s is already running on %s", name, p.Name())
+	}
+	if _, ok := p.(provider.NetworkSharer); !ok && len(scenario.Networks) > 0 {
+		return fmt.Errorf("provider %s can not share networks between deployments", p.Name())
+	}
+
+	order, err := scenario.StartOrder()
+	if err != nil {
+		return err
+	}
+
+	templates := make(map[string]*tmpl.Template, len(order))
+	for _, target := range order {
+		template, err := tmpl.GetByID(c.app.Templates, target.Template)
+		if err != nil {
+			return fmt.Errorf("target %s: %w", target.TargetName(), err)
+		}
+		if err := provider.ValidateInstanceName(scenarioInstance(name, target)); err != nil {
+			return fmt.Errorf("target %s: %w", target.TargetName(), err)
+		}
+		templates[target.TargetName()] = template
+	}
+
+	dependencies := scenario.Dependencies()
+	for _, target := range order {
+		instance := scenarioInstance(name, target)
+		template := templates[target.TargetName()]
+
+		targetOpts := opts
+		targetOpts.Values = target.Values
+		targetOpts.Networks = scenarioNetworks(name, target.Networks)
+
+		ref := state.ScenarioRef{ID: scenario.ID, Name: name, Target: target.TargetName(), Networks: targetOpts.Networks}
+
+		log.Info().Msgf("starting target %s of %s as %s", target.TargetName(), name, instance)
+		err := c.startInstance(p, template, instance, targetOpts, wait || dependencies[target.TargetName()], timeout, ttl)
+		// a target that started but failed to become ready is recorded too, so that it is stopped with the others
+		if exist, _ := c.app.StateManager.DeploymentExist(p.Name(), instance); exist { //nolint:errcheck
+			if setErr := c.app.StateManager.SetScenario(p.Name(), instance, ref); err == nil {
+				err = setErr
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("target %s: %w", target.TargetName(), err)
+			log.Warn().Msgf("stopping the started targets of %s", name)
+			if stopErr := c.stopScenario(p, c.scenarioDeployments(p.Name(), name)); stopErr != nil {
+				return fmt.Errorf("%w (cleanup failed: %v)", err, stopErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// stopScenario stops the deployments of a scenario, the last started first,
+// and removes the shared networks they joined.
+func (c *CLI) stopScenario(p provider.Provider, deployments []state.Deployment) error {
+	var errs []error
+	networks := make(map[string]bool)
+	for i := len(deployments) - 1; i >= 0; i-- {
+		deployment := deployments[i]
+		for _, network := range deployment.Scenario.Networks {
+			networks[network] = true
+		}
+
+		log.Info().Msgf("stopping target %s of %s", deployment.Scenario.Target, deployment.Scenario.Name)
+		if err := c.stopDeployment(p, deployment); err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", deployment.Scenario.Target, err))
+		}
+	}
+
+	if sharer, ok := p.(provider.NetworkSharer); ok && len(networks) > 0 && len(errs) == 0 {
+		names := make([]string, 0, len(networks))
+		for network := range networks {
+			names = append(names, network)
+		}
+		sort.Strings(names)
+		if err := sharer.RemoveNetworks(names); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// scenarioDeployments returns the deployments of a scenario instance on a
+// provider, in start order.
+func (c *CLI) scenarioDeployments(providerName, name string) []state.Deployment {
+	deployments, err := c.app.StateManager.ListDeployments()
+	if err != nil {
+		log.Debug().Msgf("%v", err)
+		return nil
+	}
+
+	var result []state.Deployment
+	for _, deployment := range deployments {
+		if deployment.ProviderName == providerName && deployment.Scenario != nil && deployment.Scenario.Name == name {
+			result = append(result, deployment)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// scenarioInstance returns the instance name of a target of a scenario instance.
+func scenarioInstance(name string, target tmpl.ScenarioTarget) string {
+	return name + "-" + target.TargetName()
+}
+
+// scenarioNetworks returns the names of the shared networks of a scenario
+// instance, which must be unique on the engine.
+func scenarioNetworks(name string, networks []string) []string {
+	names := make([]string, 0, len(networks))
+	for _, network := range networks {
+		names = append(names, "vt-scenario-"+name+"-"+network)
+	}
+	return names
+}
@@ -1,12 +1,22 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/happyhackingspace/vt/internal/app"
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/ctf"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/readiness"
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 // newStartCommand creates the start command.
@@ -25,24 +35,92 @@ func (c *CLI) newStartCommand() *cobra.Command {
 				log.Fatal().Msgf("%v", err)
 			}
 
-			if len(templateID) == 0 {
+			tags, err := cmd.Flags().GetString("tags")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			portStrategy, err := cmd.Flags().GetString("port-strategy")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if !slices.Contains(provider.PortStrategies, portStrategy) {
+				log.Fatal().Msgf("invalid port strategy %q, must be one of: %s",
+					portStrategy, strings.Join(provider.PortStrategies, ", "))
+			}
+
+			if len(templateID) == 0 && len(tags) == 0 {
 				if err := cmd.Help(); err != nil {
 					log.Fatal().Msgf("%v", err)
 				}
 				return
 			}
 
-			provider, ok := c.app.GetProvider(providerName)
+			p, ok := c.app.GetProvider(providerName)
 			if !ok {
 				log.Fatal().Msgf("provider %s not found", providerName)
 			}
 
+			values, err := startValues(cmd)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			wait, err := cmd.Flags().GetBool("wait")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			ttl, err := cmd.Flags().GetDuration("ttl")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			if ttl < 0 {
+				log.Fatal().Msgf("invalid ttl %s, must not be negative", ttl)
+			}
+
+			opts := provider.StartOptions{PortStrategy: portStrategy, Values: values}
+			if err := startIsolation(cmd, &opts); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			// drop records of environments removed outside vt, so they can be started again
+			changes, err := c.removeMissing(p)
+			if err != nil {
+				log.Debug().Err(err).Msgf("failed to sync %s deployment state", p.Name())
+			}
+			for _, change := range changes {
+				log.Info().Msgf("deployment state of %s %s", change.Deployment.Name, change.Action)
+			}
+
+			if len(tags) > 0 {
+				c.startByTags(p, tags, opts, wait, timeout, ttl)
+				return
+			}
+
 			template, err := tmpl.GetByID(c.app.Templates, templateID)
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
 
-			err = provider.Start(template)
+			if name == "" {
+				name = provider.DefaultInstanceName(templateID)
+			} else if err := provider.ValidateInstanceName(name); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			err = c.startInstance(p, template, name, opts, wait, timeout, ttl)
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
@@ -54,24 +132,236 @@ func (c *CLI) newStartCommand() *cobra.Command {
 				}
 			}
 
-			log.Info().Msgf("%s template is running on %s", templateID, providerName)
+			outcome := "running"
+			if wait {
+				outcome = "ready"
+			}
+			if name == provider.DefaultInstanceName(templateID) {
+				log.Info().Msgf("%s template is %s on %s", templateID, outcome, providerName)
+			} else {
+				log.Info().Msgf("%s instance of %s template is %s on %s", name, templateID, outcome, providerName)
+			}
+
+			for _, endpoint := range c.deploymentEndpoints(providerName, name) {
+				fmt.Printf("  %s: %s\n", endpoint.Service, endpoint.URL())
+			}
 		},
 	}
 
-	cmd.Flags().StringP("provider", "p", "docker-compose",
+	cmd.Flags().StringP("provider", "p", c.app.Config.DefaultProvider,
 		fmt.Sprintf("Specify the provider for building a vulnerable environment (%s)",
 			strings.Join(c.providerNames(), ", ")))
 
 	cmd.Flags().String("id", "",
 		"Specify a template ID for targeted vulnerable environment")
 
+	cmd.Flags().String("tags", "",
+		"Start all templates matching the comma separated tags (e.g. sqli,xss)")
+
+	cmd.Flags().String("name", "",
+		"Name of the instance, allowing the same template to run several times (defaults to a name derived from the template ID)")
+
+	cmd.Flags().String("port-strategy", provider.PortStrategyOffset,
+		fmt.Sprintf("How to handle published host ports that are already in use, fail refuses to start (%s)",
+			strings.Join(provider.PortStrategies, ", ")))
+
+	cmd.Flags().Bool("wait", false,
+		"Wait until the readiness probes of the template pass before reporting success")
+
+	cmd.Flags().Duration("timeout", 5*time.Minute,
+		"Maximum time to wait for readiness with --wait, and for flags to be planted")
+
+	cmd.Flags().Duration("ttl", 0,
+		"Stop the environment with vt reap once it ran for this long (e.g. 2h), 0 to keep it until stopped")
+
+	cmd.Flags().StringArray("set", nil,
+		"Set a template variable (KEY=VALUE), can be repeated and overrides the values file")
+
+	cmd.Flags().String("values", "",
+		"Path of a YAML file mapping template variables to their values")
+
+	c.addIsolationFlags(cmd)
+
 	if err := cmd.MarkFlagRequired("provider"); err != nil {
 		log.Fatal().Msgf("%v", err)
 	}
 
-	if err := cmd.MarkFlagRequired("id"); err != nil {
+	cmd.MarkFlagsMutuallyExclusive("id", "tags")
+	cmd.MarkFlagsMutuallyExclusive("name", "tags")
+	cmd.MarkFlagsMutuallyExclusive("set", "tags")
+	cmd.MarkFlagsMutuallyExclusive("values", "tags")
+
+	return cmd
+}
+
+// addIsolationFlags registers the network isolation flags read by startIsolation.
+func (c *CLI) addIsolationFlags(cmd *cobra.Command) {
+	cmd.Flags().String("bind-address", c.app.Config.BindAddress,
+		"Host address published ports are bound to, empty to keep the addresses of the template")
+
+	cmd.Flags().Bool("allow-egress", c.app.Config.AllowEgress,
+		"Let the services reach the internet")
+
+	cmd.Flags().Bool("allow-unsafe", false,
+		"Start templates reaching into the host, such as privileged, host network or docker.sock mounting ones")
+}
+
+// startIsolation sets the network isolation options of opts from the flags.
+func startIsolation(cmd *cobra.Command, opts *provider.StartOptions) error {
+	bindAddress, err := cmd.Flags().GetString("bind-address")
+	if err != nil {
+		return err
+	}
+	if err := app.ValidateBindAddress(bindAddress); err != nil {
+		return err
+	}
+
+	allowEgress, err := cmd.Flags().GetBool("allow-egress")
+	if err != nil {
+		return err
+	}
+
+	allowUnsafe, err := cmd.Flags().GetBool("allow-unsafe")
+	if err != nil {
+		return err
+	}
+
+	opts.BindAddress = bindAddress
+	opts.AllowEgress = allowEgress
+	opts.AllowUnsafe = allowUnsafe
+	return nil
+}
+
+// startValues returns the template variable values of the values file,
+// overridden by the ones given with --set.
+func startValues(cmd *cobra.Command) (map[string]string, error) {
+	valuesFile, err := cmd.Flags().GetString("values")
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if valuesFile != "" {
+		content, err := os.ReadFile(valuesFile) // #nosec G304
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse values file %s: %w", valuesFile, err)
+		}
+	}
+
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid value %q, must be KEY=VALUE", set)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// startByTags starts every template matching the given tags and reports
+// the outcome of each one. Failures do not stop the remaining templates.
+func (c *CLI) startByTags(p provider.Provider, rawTags string, opts provider.StartOptions, wait bool, timeout, ttl time.Duration) {
+	templates, err := c.templatesByTags(rawTags)
+	if err != nil {
 		log.Fatal().Msgf("%v", err)
 	}
 
-	return cmd
+	results := make([]batchResult, 0, len(templates))
+	for i := range templates {
+		template := &templates[i]
+		log.Info().Msgf("starting %s on %s", template.ID, p.Name())
+
+		name := provider.DefaultInstanceName(template.ID)
+		if err := c.startInstance(p, template, name, opts, wait, timeout, ttl); err != nil {
+			log.Error().Err(err).Msgf("failed to start %s", template.ID)
+			results = append(results, batchResult{TemplateID: template.ID, Status: batchStatusFailed, Message: err.Error()})
+			continue
+		}
+
+		message := "running"
+		if urls := endpointURLs(c.deploymentEndpoints(p.Name(), name)); len(urls) > 0 {
+			message = strings.Join(urls, ", ")
+		}
+		results = append(results, batchResult{TemplateID: template.ID, Status: batchStatusSucceeded, Message: message})
+	}
+
+	if failed := renderBatchSummary("started", results); failed > 0 {
+		log.Fatal().Msgf("%d of %d templates failed to start", failed, len(results))
+	}
+}
+
+// startInstance starts an instance with freshly generated CTF flags, records
+// its expiry when ttl is positive, waits for it to be ready when asked to and
+// plants the flags living in services.
+func (c *CLI) startInstance(p provider.Provider, template *tmpl.Template, name string, opts provider.StartOptions, wait bool, timeout, ttl time.Duration) error {
+	flags, err := ctf.Generate(template)
+	if err != nil {
+		return err
+	}
+	opts.Flags = flags
+
+	if err := p.Start(template, name, opts); err != nil {
+		return err
+	}
+
+	if ttl > 0 {
+		if err := c.app.StateManager.SetExpiry(p.Name(), name, time.Now().Add(ttl)); err != nil {
+			return err
+		}
+	}
+
+	if wait {
+		if err := c.waitReady(p, template, name, timeout); err != nil {
+			return fmt.Errorf("%w, see vt status --name %s and vt logs --name %s", err, name, name)
+		}
+	}
+
+	if len(flags) > 0 {
+		log.Info().Msgf("planting %d flags in %s", len(flags), name)
+		if err := ctf.Inject(p, template, name, flags, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitReady blocks until the readiness probes of the instance pass, it
+// crashes or timeout elapses.
+func (c *CLI) waitReady(p provider.Provider, template *tmpl.Template, name string, timeout time.Duration) error {
+	log.Info().Msgf("waiting up to %s for %s to be ready", timeout, name)
+	target := readiness.Target{
+		Provider:  p,
+		Template:  template,
+		Instance:  name,
+		Endpoints: c.deploymentEndpoints(p.Name(), name),
+	}
+	return readiness.Wait(context.Background(), target, timeout, readiness.DefaultInterval)
+}
+
+// deploymentEndpoints returns the recorded endpoints of an instance, or nil when it has none.
+func (c *CLI) deploymentEndpoints(providerName, name string) []state.Endpoint {
+	deployment, err := c.app.StateManager.GetDeployment(providerName, name)
+	if err != nil {
+		log.Debug().Msgf("%v", err)
+		return nil
+	}
+	return deployment.Endpoints
+}
+
+// endpointURLs returns the URLs of the given endpoints.
+func endpointURLs(endpoints []state.Endpoint) []string {
+	urls := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		urls = append(urls, endpoint.URL())
+	}
+	return urls
 }
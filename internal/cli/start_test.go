@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartInstanceTTL(t *testing.T) {
+	c, p := newReapTestCLI(t, nil)
+	template := &tmpl.Template{ID: "vt-dvwa"}
+
+	before := time.Now()
+	require.NoError(t, c.startInstance(p, template, "temporary", provider.StartOptions{}, false, time.Minute, 2*time.Hour))
+	require.NoError(t, c.startInstance(p, template, "permanent", provider.StartOptions{}, false, time.Minute, 0))
+
+	deployment, err := c.app.StateManager.GetDeployment(p.Name(), "temporary")
+	require.NoError(t, err)
+	assert.False(t, deployment.ExpiresAt.Before(before.Add(2*time.Hour)))
+	assert.True(t, deployment.ExpiresAt.Before(time.Now().Add(2*time.Hour+time.Second)))
+
+	deployment, err = c.app.StateManager.GetDeployment(p.Name(), "permanent")
+	require.NoError(t, err)
+	assert.True(t, deployment.ExpiresAt.IsZero())
+}
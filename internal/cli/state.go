@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newStateCommand creates the state command.
+func (c *CLI) newStateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Deployment state operations",
+	}
+
+	cmd.AddCommand(c.newStateSyncCommand())
+
+	return cmd
+}
+
+// newStateSyncCommand creates the state sync command.
+func (c *CLI) newStateSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Reconcile deployment records with the environments found on the providers",
+		Run: func(cmd *cobra.Command, _ []string) {
+			providerName, err := cmd.Flags().GetString("provider")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			names := c.providerNames()
+			slices.Sort(names)
+			if providerName != "" {
+				if _, ok := c.app.GetProvider(providerName); !ok {
+					log.Fatal().Msgf("provider %s not found", providerName)
+				}
+				names = []string{providerName}
+			}
+
+			var changes []state.Change
+			failed := 0
+			for _, name := range names {
+				p, _ := c.app.GetProvider(name)
+				providerChanges, err := c.reconcile(p, dryRun)
+				if err != nil {
+					// an unreachable provider must not prevent syncing the others
+					log.Warn().Err(err).Msgf("failed to sync %s", name)
+					failed++
+					continue
+				}
+				changes = append(changes, providerChanges...)
+			}
+
+			if failed == len(names) {
+				log.Fatal().Msg("no provider could be reached")
+			}
+
+			if len(changes) == 0 {
+				log.Info().Msg("deployment state is in sync")
+				return
+			}
+
+			renderStateChanges(changes, dryRun)
+		},
+	}
+
+	cmd.Flags().StringP("provider", "p", "",
+		fmt.Sprintf("Only sync the given provider (%s)",
+			strings.Join(c.providerNames(), ", ")))
+
+	cmd.Flags().Bool("dry-run", false, "Only report the differences without changing the state")
+
+	return cmd
+}
+
+// reconcile discovers the instances of the provider and repairs its
+// deployment records. Providers that can not discover instances are left as is.
+func (c *CLI) reconcile(p provider.Provider, dryRun bool) ([]state.Change, error) {
+	discoverer, ok := p.(provider.Discoverer)
+	if !ok {
+		return nil, nil
+	}
+
+	instances, err := discoverer.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.app.StateManager.Reconcile(p.Name(), stateInstances(instances), dryRun)
+}
+
+// removeMissing removes the deployment records of the provider whose
+// environment is gone altogether. Providers that can not discover instances
+// are left as is.
+func (c *CLI) removeMissing(p provider.Provider) ([]state.Change, error) {
+	discoverer, ok := p.(provider.Discoverer)
+	if !ok {
+		return nil, nil
+	}
+
+	instances, err := discoverer.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.app.StateManager.RemoveMissing(p.Name(), stateInstances(instances))
+}
+
+// stateInstances converts discovered instances for comparison with the records.
+func stateInstances(instances []provider.Instance) []state.Instance {
+	converted := make([]state.Instance, 0, len(instances))
+	for _, instance := range instances {
+		converted = append(converted, state.Instance{
+			Name:       instance.Name,
+			TemplateID: instance.TemplateID,
+			Running:    instance.Running,
+			CreatedAt:  instance.CreatedAt,
+		})
+	}
+	return converted
+}
+
+// renderStateChanges prints the reconciliation changes as a table.
+func renderStateChanges(changes []state.Change, dryRun bool) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Provider Name", "Name", "Template ID", "Change"})
+
+	for _, change := range changes {
+		var description string
+		switch change.Action {
+		case state.ChangeRemoved:
+			description = "record removed, environment gone"
+		case state.ChangeAdopted:
+			description = fmt.Sprintf("%s without record, adopted", change.Deployment.Status)
+		case state.ChangeUpdated:
+			description = "status updated to " + change.Deployment.Status
+		}
+		t.AppendRow(table.Row{
+			change.Deployment.ProviderName,
+			change.Deployment.Name,
+			change.Deployment.TemplateID,
+			description,
+		})
+	}
+
+	if dryRun {
+		t.SetCaption("%d differences found (dry run, nothing changed)", len(changes))
+	} else {
+		t.SetCaption("%d differences repaired", len(changes))
+	}
+	t.Render()
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newStatusCommand creates the status command.
+func (c *CLI) newStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show detailed status of a vulnerable environment",
+		Run: func(cmd *cobra.Command, _ []string) {
+			p, template, name := c.resolveInstance(cmd)
+
+			status, err := p.Status(template, name)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if status.Services == nil {
+				status.Services = []provider.ServiceStatus{}
+			}
+
+			output := statusOutput{
+				Provider:   p.Name(),
+				Name:       name,
+				TemplateID: template.ID,
+				State:      status.State,
+				Services:   status.Services,
+			}
+			if err := writeOutput(c.outputFormat(), output, statusTable(name, status)); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+		},
+	}
+
+	c.addInstanceFlags(cmd)
+
+	return cmd
+}
+
+// statusOutput is the machine-readable schema of the status of an instance.
+type statusOutput struct {
+	Provider   string                   `json:"provider" yaml:"provider"`
+	Name       string                   `json:"name" yaml:"name"`
+	TemplateID string                   `json:"template_id" yaml:"template_id"`
+	State      string                   `json:"state" yaml:"state"`
+	Services   []provider.ServiceStatus `json:"services" yaml:"services"`
+}
+
+// statusTable returns the per-service status of a deployment as a table.
+func statusTable(name string, status provider.Status) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.AppendHeader(table.Row{"Service", "Container", "State", "Health", "Exit Code", "Restarts", "Ports", "Uptime"})
+
+	for _, service := range status.Services {
+		ports := make([]string, 0, len(service.Ports))
+		for _, port := range service.Ports {
+			ports = append(ports, port.String())
+		}
+
+		exitCode := ""
+		if service.State == "exited" {
+			exitCode = fmt.Sprint(service.ExitCode)
+		}
+
+		uptime := ""
+		if d := service.Uptime(); d > 0 {
+			uptime = d.String()
+		}
+
+		t.AppendRow(table.Row{
+			service.Name,
+			service.Container,
+			service.State,
+			service.Health,
+			exitCode,
+			service.RestartCount,
+			strings.Join(ports, "\n"),
+			uptime,
+		})
+	}
+
+	t.SetCaption("%s is %s (%s)", name, status.State, status.Summary())
+	return t
+}
@@ -2,8 +2,10 @@ package cli
 
 import (
 	"fmt"
-	"strings"
+	"sort"
 
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -25,39 +27,201 @@ func (c *CLI) newStopCommand() *cobra.Command {
 				log.Fatal().Msgf("%v", err)
 			}
 
-			provider, ok := c.app.GetProvider(providerName)
-			if !ok {
-				log.Fatal().Msgf("provider %s not found", providerName)
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
 			}
 
-			template, err := tmpl.GetByID(c.app.Templates, templateID)
+			tags, err := cmd.Flags().GetString("tags")
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
 
-			err = provider.Stop(template)
+			all, err := cmd.Flags().GetBool("all")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if all {
+				// without an explicit provider, everything is torn down
+				if !cmd.Flags().Changed("provider") {
+					providerName = ""
+				}
+				c.stopAll(providerName)
+				return
+			}
+
+			if len(templateID) == 0 && len(name) == 0 && len(tags) == 0 {
+				if err := cmd.Help(); err != nil {
+					log.Fatal().Msgf("%v", err)
+				}
+				return
+			}
+
+			if len(tags) > 0 {
+				p, ok := c.app.GetProvider(providerName)
+				if !ok {
+					log.Fatal().Msgf("provider %s not found", providerName)
+				}
+				c.stopByTags(p, tags)
+				return
+			}
+
+			p, template, name := c.resolveInstance(cmd)
+
+			err = p.Stop(template, name)
 			if err != nil {
 				log.Fatal().Msgf("%v", err)
 			}
 
-			log.Info().Msgf("%s template stopped on %s", templateID, providerName)
+			if name == template.ID || name == provider.DefaultInstanceName(template.ID) {
+				log.Info().Msgf("%s template stopped on %s", template.ID, providerName)
+			} else {
+				log.Info().Msgf("%s instance of %s template stopped on %s", name, template.ID, providerName)
+			}
 		},
 	}
 
-	cmd.Flags().StringP("provider", "p", "docker-compose",
-		fmt.Sprintf("Specify the provider for building a vulnerable environment (%s)",
-			strings.Join(c.providerNames(), ", ")))
+	c.addInstanceFlags(cmd)
+
+	cmd.Flags().String("tags", "",
+		"Stop all running instances of templates matching the comma separated tags (e.g. sqli,xss)")
+
+	cmd.Flags().Bool("all", false,
+		"Stop every recorded deployment, of all providers unless --provider is given")
+
+	cmd.MarkFlagsOneRequired("provider", "all")
 
-	cmd.Flags().String("id", "",
-		"Specify a template ID for targeted vulnerable environment")
+	cmd.MarkFlagsMutuallyExclusive("id", "tags")
+	cmd.MarkFlagsMutuallyExclusive("name", "tags")
+	cmd.MarkFlagsMutuallyExclusive("all", "id")
+	cmd.MarkFlagsMutuallyExclusive("all", "name")
+	cmd.MarkFlagsMutuallyExclusive("all", "tags")
+
+	return cmd
+}
 
-	if err := cmd.MarkFlagRequired("provider"); err != nil {
+// stopByTags stops every deployed instance of the templates matching the given
+// tags and reports the outcome of each one. Templates without a deployment are skipped.
+func (c *CLI) stopByTags(p provider.Provider, rawTags string) {
+	templates, err := c.templatesByTags(rawTags)
+	if err != nil {
 		log.Fatal().Msgf("%v", err)
 	}
 
-	if err := cmd.MarkFlagRequired("id"); err != nil {
+	deployments, err := c.app.StateManager.ListDeployments()
+	if err != nil {
 		log.Fatal().Msgf("%v", err)
 	}
 
-	return cmd
+	results := make([]batchResult, 0, len(templates))
+	for i := range templates {
+		template := &templates[i]
+
+		var instances []string
+		for _, deployment := range deployments {
+			if deployment.ProviderName == p.Name() && deployment.TemplateID == template.ID {
+				instances = append(instances, deployment.Name)
+			}
+		}
+
+		if len(instances) == 0 {
+			results = append(results, batchResult{TemplateID: template.ID, Status: batchStatusSkipped, Message: "not running"})
+			continue
+		}
+
+		for _, name := range instances {
+			log.Info().Msgf("stopping %s on %s", name, p.Name())
+			if err := p.Stop(template, name); err != nil {
+				log.Error().Err(err).Msgf("failed to stop %s", name)
+				results = append(results, batchResult{TemplateID: template.ID, Status: batchStatusFailed, Message: fmt.Sprintf("%s: %v", name, err)})
+				continue
+			}
+
+			results = append(results, batchResult{TemplateID: template.ID, Status: batchStatusSucceeded, Message: name + " stopped"})
+		}
+	}
+
+	if failed := renderBatchSummary("stopped", results); failed > 0 {
+		log.Fatal().Msgf("%d of %d instances failed to stop", failed, len(results))
+	}
+}
+
+// stopAll stops every recorded deployment of the provider, or of all providers
+// when providerName is empty, and reports the outcome of each one. The targets
+// of a scenario are stopped together so that its shared networks are removed.
+func (c *CLI) stopAll(providerName string) {
+	deployments, err := c.app.StateManager.ListDeployments()
+	if err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].CreatedAt.Before(deployments[j].CreatedAt)
+	})
+
+	var results []batchResult
+	stopped := make(map[string]bool)
+	for _, deployment := range deployments {
+		if providerName != "" && deployment.ProviderName != providerName {
+			continue
+		}
+
+		p, ok := c.app.GetProvider(deployment.ProviderName)
+		if !ok {
+			results = append(results, batchResult{TemplateID: deployment.TemplateID, Status: batchStatusFailed, Message: fmt.Sprintf("%s: provider %s not found", deployment.Name, deployment.ProviderName)})
+			continue
+		}
+
+		if deployment.Scenario != nil {
+			key := deployment.ProviderName + "/" + deployment.Scenario.Name
+			if stopped[key] {
+				continue
+			}
+			stopped[key] = true
+
+			name := deployment.Scenario.Name
+			log.Info().Msgf("stopping %s scenario on %s", name, p.Name())
+			if err := c.stopScenario(p, c.scenarioDeployments(p.Name(), name)); err != nil {
+				log.Error().Err(err).Msgf("failed to stop %s scenario", name)
+				results = append(results, batchResult{TemplateID: deployment.Scenario.ID, Status: batchStatusFailed, Message: fmt.Sprintf("%s: %v", name, err)})
+				continue
+			}
+			results = append(results, batchResult{TemplateID: deployment.Scenario.ID, Status: batchStatusSucceeded, Message: name + " scenario stopped"})
+			continue
+		}
+
+		log.Info().Msgf("stopping %s on %s", deployment.Name, p.Name())
+		if err := c.stopDeployment(p, deployment); err != nil {
+			log.Error().Err(err).Msgf("failed to stop %s", deployment.Name)
+			results = append(results, batchResult{TemplateID: deployment.TemplateID, Status: batchStatusFailed, Message: fmt.Sprintf("%s: %v", deployment.Name, err)})
+			continue
+		}
+		results = append(results, batchResult{TemplateID: deployment.TemplateID, Status: batchStatusSucceeded, Message: deployment.Name + " stopped"})
+	}
+
+	if len(results) == 0 {
+		log.Info().Msg("there is no running environment")
+		return
+	}
+
+	if failed := renderBatchSummary("stopped", results); failed > 0 {
+		log.Fatal().Msgf("%d of %d stops failed", failed, len(results))
+	}
+}
+
+// stopDeployment stops a recorded deployment through its provider. A
+// deployment whose template is not loaded anymore is removed by its recorded
+// name when the provider can do so.
+func (c *CLI) stopDeployment(p provider.Provider, deployment state.Deployment) error {
+	template, err := tmpl.GetByID(c.app.Templates, deployment.TemplateID)
+	if err == nil {
+		return p.Stop(template, deployment.Name)
+	}
+
+	remover, ok := p.(provider.Remover)
+	if !ok {
+		return fmt.Errorf("%w, %s can not be stopped without it", err, deployment.Name)
+	}
+	log.Warn().Msgf("template %s is not loaded, removing %s by its name", deployment.TemplateID, deployment.Name)
+	return remover.Remove(deployment.Name)
 }
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopAll(t *testing.T) {
+	c, p := newReapTestCLI(t, []state.Deployment{
+		{TemplateID: "vt-dvwa", Name: "dvwa"},
+		{TemplateID: "vt-deleted", Name: "orphan"},
+	})
+
+	c.stopAll("")
+	assert.Equal(t, []string{"dvwa"}, p.stopped)
+	assert.Equal(t, []string{"orphan"}, p.removed, "removed by its recorded name")
+	assert.Empty(t, recordedNames(t, c))
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/ctf"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/readiness"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/happyhackingspace/vt/pkg/verify"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// verifyInstanceSuffix is appended to the template ID to name the instance under verification.
+const verifyInstanceSuffix = "-verify"
+
+// newVerifyCommand creates the verify command.
+func (c *CLI) newVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify --id <template-id>",
+		Short: "Start a template, run its proof of concept and tear it down",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			providerName, err := cmd.Flags().GetString("provider")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			templateID, err := cmd.Flags().GetString("id")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			keep, err := cmd.Flags().GetBool("keep")
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			template, err := tmpl.GetByID(c.app.Templates, templateID)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+			if len(template.Verify) == 0 {
+				log.Fatal().Msgf("template %s has no verify steps", template.ID)
+			}
+
+			p, ok := c.app.GetProvider(providerName)
+			if !ok {
+				log.Fatal().Msgf("provider %s not found", providerName)
+			}
+
+			name := verifyInstanceName(template.ID)
+			if exist, _ := c.app.StateManager.DeploymentExist(p.Name(), name); exist { //nolint:errcheck
+				log.Fatal().Msgf("instance %s already exists, stop it with vt stop --name %s", name, name)
+			}
+
+			log.Info().Msgf("starting %s as %s on %s", template.ID, name, p.Name())
+			err = c.startInstance(p, template, name, provider.StartOptions{
+				PortStrategy: provider.PortStrategyOffset,
+				BindAddress:  c.app.Config.BindAddress,
+				AllowEgress:  c.app.Config.AllowEgress,
+			}, true, timeout, 0)
+			if err != nil {
+				c.teardownVerify(p, template, name, keep)
+				log.Fatal().Msgf("%v", err)
+			}
+
+			results, err := c.runVerify(p, template, name)
+			c.teardownVerify(p, template, name, keep)
+			if err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if err := writeOutput(c.outputFormat(), results, verifyResultsTable(results)); err != nil {
+				log.Fatal().Msgf("%v", err)
+			}
+
+			if !verify.Passed(results) {
+				failed := 0
+				for _, result := range results {
+					if !result.Passed {
+						failed++
+					}
+				}
+				log.Fatal().Msgf("%s is not exploitable: %d of %d steps failed", template.ID, failed, len(results))
+			}
+			log.Info().Msgf("%s is exploitable, all %d steps passed", template.ID, len(results))
+		},
+	}
+
+	cmd.Flags().StringP("provider", "p", c.app.Config.DefaultProvider,
+		fmt.Sprintf("Specify the provider to verify the template on (%s)",
+			strings.Join(c.providerNames(), ", ")))
+
+	cmd.Flags().String("id", "", "Template ID to verify")
+
+	cmd.Flags().Duration("timeout", 5*time.Minute,
+		"Maximum time to wait for the environment to be ready")
+
+	cmd.Flags().Bool("keep", false, "Keep the environment running after verification")
+
+	if err := cmd.MarkFlagRequired("id"); err != nil {
+		log.Fatal().Msgf("%v", err)
+	}
+
+	return cmd
+}
+
+// runVerify runs the verify steps against a started instance. Scripts get the
+// variable values and flags of the instance in their environment.
+func (c *CLI) runVerify(p provider.Provider, template *tmpl.Template, name string) ([]verify.Result, error) {
+	deployment, err := c.app.StateManager.GetDeployment(p.Name(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make([]string, 0, len(deployment.Values)+len(deployment.Flags))
+	for key, value := range deployment.Values {
+		env = append(env, key+"="+value)
+	}
+	slices.Sort(env)
+	env = append(env, ctf.Environment(deployment.Flags)...)
+
+	target := readiness.Target{Provider: p, Template: template, Instance: name, Endpoints: deployment.Endpoints}
+	return verify.Run(context.Background(), target, env)
+}
+
+// teardownVerify stops the instance under verification unless it must be kept.
+func (c *CLI) teardownVerify(p provider.Provider, template *tmpl.Template, name string, keep bool) {
+	if keep {
+		log.Info().Msgf("keeping %s, stop it with vt stop --name %s", name, name)
+		return
+	}
+	if err := p.Stop(template, name); err != nil {
+		log.Error().Err(err).Msgf("failed to stop %s", name)
+	}
+}
+
+// verifyInstanceName returns the name of the instance a template is verified
+// on, derived from the template ID so it is a valid instance name.
+func verifyInstanceName(templateID string) string {
+	name := provider.DefaultInstanceName(templateID)
+	return strings.TrimRight(name[:min(len(name), 60-len(verifyInstanceSuffix))], "-") + verifyInstanceSuffix
+}
+
+// verifyResultsTable renders the outcome of every verify step.
+func verifyResultsTable(results []verify.Result) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleDefault)
+	t.AppendHeader(table.Row{"Step", "Type", "Result", "Duration", "Message"})
+	for _, result := range results {
+		outcome := "pass"
+		if !result.Passed {
+			outcome = "fail"
+		}
+		t.AppendRow(table.Row{result.Step, result.Type, outcome, result.Duration, result.Message})
+	}
+	return t
+}
@@ -0,0 +1,45 @@
+package state
+
+import "time"
+
+// Attacker represents an attacker workstation attached to the networks of a deployment
+type Attacker struct {
+	ProviderName string
+	// Deployment is the instance name of the deployment the workstation is attached to
+	Deployment string
+	Image      string
+	Container  string
+	Networks   []string
+	// Hostnames are the names the services of the deployment are reachable by from the workstation
+	Hostnames []string
+	CreatedAt time.Time
+}
+
+// AddAttacker records a started attacker workstation
+func (m *Manager) AddAttacker(attacker Attacker) error {
+	if attacker.CreatedAt.IsZero() {
+		attacker.CreatedAt = time.Now()
+	}
+	return m.attackers.Set(deploymentKey(attacker.ProviderName, attacker.Deployment), attacker)
+}
+
+// GetAttacker returns the attacker workstation record of a deployment
+func (m *Manager) GetAttacker(providerName, deployment string) (Attacker, error) {
+	return m.attackers.Get(deploymentKey(providerName, deployment))
+}
+
+// RemoveAttacker deletes the attacker workstation record of a deployment
+func (m *Manager) RemoveAttacker(providerName, deployment string) error {
+	return m.attackers.Delete(deploymentKey(providerName, deployment))
+}
+
+// AttackerExist checks if a deployment has an attacker workstation record
+func (m *Manager) AttackerExist(providerName, deployment string) (bool, error) {
+	_, err := m.attackers.Get(deploymentKey(providerName, deployment))
+	return err == nil, err
+}
+
+// ListAttackers returns all attacker workstation records from storage
+func (m *Manager) ListAttackers() ([]Attacker, error) {
+	return m.attackers.GetAll()
+}
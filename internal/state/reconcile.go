@@ -0,0 +1,145 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+// Reconciliation actions reported by Reconcile
+const (
+	// ChangeRemoved marks a record whose instance is gone
+	ChangeRemoved = "removed"
+	// ChangeAdopted marks an instance that had no record
+	ChangeAdopted = "adopted"
+	// ChangeUpdated marks a record whose status changed
+	ChangeUpdated = "updated"
+)
+
+// Change is a difference found between the deployment records and a provider
+type Change struct {
+	Action     string
+	Deployment Deployment
+}
+
+// Instance is an instance found on a provider, compared with the records
+type Instance struct {
+	Name       string
+	TemplateID string
+	Running    bool
+	CreatedAt  time.Time
+}
+
+// instanceStatus returns the deployment status of a discovered instance.
+func instanceStatus(instance Instance) string {
+	if instance.Running {
+		return "running"
+	}
+	return "stopped"
+}
+
+// Reconcile compares the deployment records of a provider with the instances
+// found on it. Records of instances that are gone are removed, records of
+// instances that stopped or started again get their status updated, and
+// instances without a record are adopted. With dryRun the changes are only
+// reported.
+func (m *Manager) Reconcile(providerName string, instances []Instance, dryRun bool) ([]Change, error) {
+	deployments, err := m.ListDeployments()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]Instance, len(instances))
+	for _, instance := range instances {
+		found[instance.Name] = instance
+	}
+
+	var changes []Change
+	recorded := make(map[string]bool)
+	for _, deployment := range deployments {
+		if deployment.ProviderName != providerName {
+			continue
+		}
+		recorded[deployment.Name] = true
+
+		instance, ok := found[deployment.Name]
+		if !ok {
+			changes = append(changes, Change{Action: ChangeRemoved, Deployment: deployment})
+			continue
+		}
+		if status := instanceStatus(instance); deployment.Status != status {
+			deployment.Status = status
+			changes = append(changes, Change{Action: ChangeUpdated, Deployment: deployment})
+		}
+	}
+
+	for name, instance := range found {
+		if recorded[name] {
+			continue
+		}
+		changes = append(changes, Change{
+			Action: ChangeAdopted,
+			Deployment: Deployment{
+				ProviderName: providerName,
+				TemplateID:   instance.TemplateID,
+				Name:         instance.Name,
+				Status:       instanceStatus(instance),
+				CreatedAt:    instance.CreatedAt,
+			},
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Deployment.Name < changes[j].Deployment.Name
+	})
+
+	if dryRun {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		key := deploymentKey(providerName, change.Deployment.Name)
+		switch change.Action {
+		case ChangeRemoved:
+			err = m.store.Delete(key)
+		case ChangeAdopted, ChangeUpdated:
+			err = m.store.Set(key, change.Deployment)
+		}
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+// RemoveMissing removes the records of a provider whose instance was not found
+// on it at all. Records of instances that exist but are not running, such as
+// crashed or still starting ones, are kept as they are.
+func (m *Manager) RemoveMissing(providerName string, instances []Instance) ([]Change, error) {
+	deployments, err := m.ListDeployments()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		found[instance.Name] = true
+	}
+
+	var changes []Change
+	for _, deployment := range deployments {
+		if deployment.ProviderName != providerName || found[deployment.Name] {
+			continue
+		}
+		if err := m.store.Delete(deploymentKey(providerName, deployment.Name)); err != nil {
+			return changes, err
+		}
+		changes = append(changes, Change{Action: ChangeRemoved, Deployment: deployment})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Deployment.Name < changes[j].Deployment.Name
+	})
+
+	return changes, nil
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/store/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager returns a manager storing its records in a temporary directory.
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	dir := t.TempDir()
+	m, err := NewManager(
+		disk.NewConfig().WithDir(dir).WithFileName("deployments.db").WithBucketName("deployments"),
+		disk.NewConfig().WithDir(dir).WithFileName("attackers.db").WithBucketName("attackers"),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// deploymentNames returns the names of the recorded deployments of a provider.
+func deploymentNames(t *testing.T, m *Manager, providerName string) []string {
+	t.Helper()
+
+	deployments, err := m.ListDeployments()
+	require.NoError(t, err)
+
+	var names []string
+	for _, deployment := range deployments {
+		if deployment.ProviderName == providerName {
+			names = append(names, deployment.Name)
+		}
+	}
+	return names
+}
+
+func TestReconcile(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "running"))
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "exited"))
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "gone"))
+	require.NoError(t, m.AddNewDeployment("podman", "vt-dvwa", "other"))
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	instances := []Instance{
+		{Name: "running", TemplateID: "vt-dvwa", Running: true},
+		{Name: "exited", TemplateID: "vt-dvwa"},
+		{Name: "adopted", TemplateID: "vt-juice-shop", Running: true, CreatedAt: createdAt},
+		{Name: "adopted-stopped", TemplateID: "vt-juice-shop", CreatedAt: createdAt},
+	}
+
+	changes, err := m.Reconcile("docker-compose", instances, true)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	assert.Equal(t, ChangeAdopted, changes[0].Action)
+	assert.Equal(t, Deployment{ProviderName: "docker-compose", TemplateID: "vt-juice-shop", Name: "adopted", Status: "running", CreatedAt: createdAt}, changes[0].Deployment)
+	assert.Equal(t, ChangeAdopted, changes[1].Action)
+	assert.Equal(t, Deployment{ProviderName: "docker-compose", TemplateID: "vt-juice-shop", Name: "adopted-stopped", Status: "stopped", CreatedAt: createdAt}, changes[1].Deployment)
+	assert.Equal(t, ChangeUpdated, changes[2].Action)
+	assert.Equal(t, "exited", changes[2].Deployment.Name)
+	assert.Equal(t, "stopped", changes[2].Deployment.Status)
+	assert.Equal(t, ChangeRemoved, changes[3].Action)
+	assert.Equal(t, "gone", changes[3].Deployment.Name)
+
+	assert.ElementsMatch(t, []string{"running", "exited", "gone"}, deploymentNames(t, m, "docker-compose"),
+		"dry run must not change the records")
+
+	_, err = m.Reconcile("docker-compose", instances, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"running", "exited", "adopted", "adopted-stopped"}, deploymentNames(t, m, "docker-compose"),
+		"exited instances keep their record")
+	assert.Equal(t, []string{"other"}, deploymentNames(t, m, "podman"), "other providers are left alone")
+
+	exited, err := m.GetDeployment("docker-compose", "exited")
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", exited.Status)
+	assert.Equal(t, "vt-dvwa", exited.TemplateID)
+
+	changes, err = m.Reconcile("docker-compose", instances, false)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	instances[1].Running = true
+	changes, err = m.Reconcile("docker-compose", instances, false)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChangeUpdated, changes[0].Action)
+	assert.Equal(t, "running", changes[0].Deployment.Status)
+}
+
+func TestRemoveMissing(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "running"))
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "crashed"))
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "gone"))
+	require.NoError(t, m.AddNewDeployment("podman", "vt-dvwa", "gone"))
+
+	instances := []Instance{
+		{Name: "running", TemplateID: "vt-dvwa", Running: true},
+		{Name: "crashed", TemplateID: "vt-dvwa"},
+		{Name: "unrecorded", TemplateID: "vt-dvwa", Running: true},
+	}
+
+	changes, err := m.RemoveMissing("docker-compose", instances)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChangeRemoved, changes[0].Action)
+	assert.Equal(t, "gone", changes[0].Deployment.Name)
+
+	assert.ElementsMatch(t, []string{"running", "crashed"}, deploymentNames(t, m, "docker-compose"),
+		"instances that exist keep their record, whatever their state")
+	assert.Equal(t, []string{"gone"}, deploymentNames(t, m, "podman"))
+}
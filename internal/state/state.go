@@ -2,7 +2,10 @@
 package state
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/happyhackingspace/vt/pkg/store"
@@ -12,50 +15,204 @@ import (
 type Deployment struct {
 	ProviderName string
 	TemplateID   string
-	Status       string
-	CreatedAt    time.Time
+	// Name is the instance name, which defaults to the template ID
+	Name      string
+	Status    string
+	CreatedAt time.Time
+	Endpoints []Endpoint
+	// Values are the template variable values the instance was started with
+	Values map[string]string
+	// Flags are the CTF flags generated for the instance, keyed by flag name
+	Flags map[string]string
+	// Scenario ties the deployment to the scenario it is a target of, if any
+	Scenario *ScenarioRef
+	// ExpiresAt is when the deployment is stopped by vt reap, zero for never
+	ExpiresAt time.Time
 }
 
-// Manager provides storage operations for deployments
+// Expired reports whether the deployment has an expiry that is not after now
+func (d Deployment) Expired(now time.Time) bool {
+	return !d.ExpiresAt.IsZero() && !now.Before(d.ExpiresAt)
+}
+
+// ScenarioRef identifies the scenario instance a deployment is a target of
+type ScenarioRef struct {
+	ID string
+	// Name is the instance name of the scenario, which defaults to its ID
+	Name string
+	// Target is the name of the deployment within the scenario
+	Target string
+	// Networks are the shared networks the deployment joined
+	Networks []string
+}
+
+// Endpoint is an address where a service of a deployment can be reached from the host
+type Endpoint struct {
+	Service       string
+	HostIP        string
+	HostPort      int
+	ContainerPort int
+	Protocol      string
+}
+
+// URL returns a clickable URL for the endpoint, guessing the scheme from the container port
+func (e Endpoint) URL() string {
+	host := e.HostIP
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(e.HostPort))
+
+	switch {
+	case e.Protocol == "udp":
+		return "udp://" + address
+	case e.ContainerPort == 443 || e.ContainerPort == 8443:
+		return "https://" + address
+	default:
+		return "http://" + address
+	}
+}
+
+// Manager provides storage operations for deployments and their attacker workstations
 type Manager struct {
-	store store.Storage[Deployment]
+	store     store.Storage[Deployment]
+	attackers store.Storage[Attacker]
+
+	config          any
+	attackersConfig any
 }
 
-// NewManager creates a new manager with pre-defined disk storage configuration
-func NewManager(config any) (*Manager, error) {
-	store, err := store.NewStorage[Deployment](store.DiskStoreType, config)
-	if err != nil {
+// NewManager creates a new manager with pre-defined disk storage configurations
+// for the deployment and the attacker workstation records
+func NewManager(config, attackersConfig any) (*Manager, error) {
+	m := &Manager{config: config, attackersConfig: attackersConfig}
+	if err := m.Reopen(); err != nil {
 		return nil, err
 	}
-	return &Manager{store: store}, nil
+	return m, nil
 }
 
-// AddNewDeployment creates a new deployment record with running status
-func (m *Manager) AddNewDeployment(providerName, templateID string) error {
+// Close releases the storage, which other vt processes wait for while it is open
+func (m *Manager) Close() error {
+	return errors.Join(m.store.Close(), m.attackers.Close())
+}
+
+// Reopen opens the storage again after Close
+func (m *Manager) Reopen() error {
+	deployments, err := store.NewStorage[Deployment](store.DiskStoreType, m.config)
+	if err != nil {
+		return err
+	}
+	attackers, err := store.NewStorage[Attacker](store.DiskStoreType, m.attackersConfig)
+	if err != nil {
+		return errors.Join(err, deployments.Close())
+	}
+	m.store, m.attackers = deployments, attackers
+	return nil
+}
+
+// AddNewDeployment creates a new deployment record with running status for an instance of a template
+func (m *Manager) AddNewDeployment(providerName, templateID, name string) error {
 	deployment := Deployment{
 		ProviderName: providerName,
 		TemplateID:   templateID,
+		Name:         name,
 		Status:       "running",
 		CreatedAt:    time.Now(),
 	}
-	err := m.store.Set(fmt.Sprintf("%s:%s", deployment.ProviderName, deployment.TemplateID), deployment)
+	err := m.store.Set(deploymentKey(deployment.ProviderName, deployment.Name), deployment)
 	return err
 }
 
-// RemoveDeployment deletes a deployment record by provider name and template ID
-func (m *Manager) RemoveDeployment(providerName, templateID string) error {
-	err := m.store.Delete(fmt.Sprintf("%s:%s", providerName, templateID))
+// SetEndpoints records the reachable endpoints of an existing deployment
+func (m *Manager) SetEndpoints(providerName, name string, endpoints []Endpoint) error {
+	deployment, err := m.GetDeployment(providerName, name)
+	if err != nil {
+		return err
+	}
+	deployment.Endpoints = endpoints
+	return m.store.Set(deploymentKey(providerName, name), deployment)
+}
+
+// SetValues records the template variable values an existing deployment was started with
+func (m *Manager) SetValues(providerName, name string, values map[string]string) error {
+	deployment, err := m.GetDeployment(providerName, name)
+	if err != nil {
+		return err
+	}
+	deployment.Values = values
+	return m.store.Set(deploymentKey(providerName, name), deployment)
+}
+
+// SetFlags records the CTF flags generated for an existing deployment
+func (m *Manager) SetFlags(providerName, name string, flags map[string]string) error {
+	deployment, err := m.GetDeployment(providerName, name)
+	if err != nil {
+		return err
+	}
+	deployment.Flags = flags
+	return m.store.Set(deploymentKey(providerName, name), deployment)
+}
+
+// SetExpiry records when an existing deployment expires, zero for never
+func (m *Manager) SetExpiry(providerName, name string, expiresAt time.Time) error {
+	deployment, err := m.GetDeployment(providerName, name)
+	if err != nil {
+		return err
+	}
+	deployment.ExpiresAt = expiresAt
+	return m.store.Set(deploymentKey(providerName, name), deployment)
+}
+
+// SetScenario records the scenario an existing deployment is a target of
+func (m *Manager) SetScenario(providerName, name string, scenario ScenarioRef) error {
+	deployment, err := m.GetDeployment(providerName, name)
+	if err != nil {
+		return err
+	}
+	deployment.Scenario = &scenario
+	return m.store.Set(deploymentKey(providerName, name), deployment)
+}
+
+// GetDeployment returns the deployment record for the given provider and instance name
+func (m *Manager) GetDeployment(providerName, name string) (Deployment, error) {
+	deployment, err := m.store.Get(deploymentKey(providerName, name))
+	if err != nil {
+		return deployment, err
+	}
+	return withDefaultName(deployment), nil
+}
+
+// RemoveDeployment deletes a deployment record by provider name and instance name
+func (m *Manager) RemoveDeployment(providerName, name string) error {
+	err := m.store.Delete(deploymentKey(providerName, name))
 	return err
 }
 
-// DeploymentExist checks if a deployment exists for the given provider and template
-func (m *Manager) DeploymentExist(providerName, templateID string) (bool, error) {
-	_, err := m.store.Get(fmt.Sprintf("%s:%s", providerName, templateID))
+// DeploymentExist checks if a deployment exists for the given provider and instance name
+func (m *Manager) DeploymentExist(providerName, name string) (bool, error) {
+	_, err := m.store.Get(deploymentKey(providerName, name))
 	return err == nil, err
 }
 
 // ListDeployments returns all deployment records from storage
 func (m Manager) ListDeployments() ([]Deployment, error) {
 	deployments, err := m.store.GetAll()
+	for i := range deployments {
+		deployments[i] = withDefaultName(deployments[i])
+	}
 	return deployments, err
 }
+
+// deploymentKey returns the storage key of a deployment record
+func deploymentKey(providerName, name string) string {
+	return fmt.Sprintf("%s:%s", providerName, name)
+}
+
+// withDefaultName names records created before instances existed after their template
+func withDefaultName(deployment Deployment) Deployment {
+	if deployment.Name == "" {
+		deployment.Name = deployment.TemplateID
+	}
+	return deployment
+}
@@ -0,0 +1,52 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentExpired(t *testing.T) {
+	expiresAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		now       time.Time
+		expected  bool
+	}{
+		{name: "no expiry", now: expiresAt},
+		{name: "before expiry", expiresAt: expiresAt, now: expiresAt.Add(-time.Nanosecond)},
+		{name: "at expiry", expiresAt: expiresAt, now: expiresAt, expected: true},
+		{name: "after expiry", expiresAt: expiresAt, now: expiresAt.Add(time.Second), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := Deployment{ExpiresAt: tt.expiresAt}
+			assert.Equal(t, tt.expected, deployment.Expired(tt.now))
+		})
+	}
+}
+
+func TestSetExpiry(t *testing.T) {
+	m := newTestManager(t)
+	require.NoError(t, m.AddNewDeployment("docker-compose", "vt-dvwa", "dvwa"))
+
+	expiresAt := time.Now().Add(time.Hour).Round(0)
+	require.NoError(t, m.SetExpiry("docker-compose", "dvwa", expiresAt))
+
+	deployment, err := m.GetDeployment("docker-compose", "dvwa")
+	require.NoError(t, err)
+	assert.True(t, expiresAt.Equal(deployment.ExpiresAt))
+	assert.Equal(t, "vt-dvwa", deployment.TemplateID, "the rest of the record is kept")
+
+	require.NoError(t, m.SetExpiry("docker-compose", "dvwa", time.Time{}))
+	deployment, err = m.GetDeployment("docker-compose", "dvwa")
+	require.NoError(t, err)
+	assert.True(t, deployment.ExpiresAt.IsZero())
+
+	assert.Error(t, m.SetExpiry("docker-compose", "missing", expiresAt))
+}
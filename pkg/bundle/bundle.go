@@ -0,0 +1,345 @@
+// Package bundle packs templates and the container images they run into a
+// single archive, so that they can be deployed on hosts without network access.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+// Version is the bundle format version written to the manifest.
+const Version = 1
+
+// Names of the entries of a bundle archive.
+const (
+	ManifestName = "manifest.json"
+	ImagesName   = "images.tar"
+	TemplatesDir = "templates"
+)
+
+// Manifest describes the content of a bundle. It is the last entry of the
+// archive and holds the SHA-256 checksum of every other entry.
+type Manifest struct {
+	Version   int               `json:"version"`
+	CreatedAt time.Time         `json:"created_at"`
+	Provider  string            `json:"provider"`
+	Templates []Template        `json:"templates"`
+	Images    []string          `json:"images"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// Template is a template packed in a bundle.
+type Template struct {
+	ID string `json:"id"`
+	// Path is the template directory inside the archive.
+	Path string `json:"path"`
+}
+
+// Create writes a gzip compressed bundle of the templates to w. When images is
+// not empty, saveImages is called to write the image archive.
+func Create(w io.Writer, templates []tmpl.Template, providerName string, images []string, saveImages func(io.Writer) error) (*Manifest, error) {
+	manifest := &Manifest{
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Provider:  providerName,
+		Templates: make([]Template, 0, len(templates)),
+		Images:    images,
+		Checksums: make(map[string]string),
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, template := range templates {
+		archivePath, err := templateArchivePath(template)
+		if err != nil {
+			return nil, err
+		}
+		if err := addDirectory(tw, manifest, template.Dir, archivePath); err != nil {
+			return nil, fmt.Errorf("failed to add template %s: %w", template.ID, err)
+		}
+		manifest.Templates = append(manifest.Templates, Template{ID: template.ID, Path: archivePath})
+	}
+
+	if len(images) > 0 {
+		if err := addImages(tw, manifest, saveImages); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	header := &tar.Header{Name: ManifestName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+// Extract unpacks a bundle read from r into dir and verifies it against its
+// manifest. Templates end up in dir/templates and the image archive in
+// dir/images.tar.
+func Extract(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle archive: %w", err)
+	}
+	defer gz.Close() //nolint:errcheck
+
+	var manifest *Manifest
+	checksums := make(map[string]string)
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected entry %s in bundle", header.Name)
+		}
+		if !filepath.IsLocal(header.Name) {
+			return nil, fmt.Errorf("invalid entry path %s in bundle", header.Name)
+		}
+
+		if header.Name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+			}
+			continue
+		}
+
+		checksum, err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(header.Name)), header.FileInfo().Mode().Perm())
+		if err != nil {
+			return nil, err
+		}
+		checksums[header.Name] = checksum
+	}
+
+	if manifest == nil {
+		return nil, errors.New("bundle has no manifest")
+	}
+	if manifest.Version != Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+	if err := verifyChecksums(manifest.Checksums, checksums); err != nil {
+		return nil, err
+	}
+	// the manifest carries no checksum of its own, its paths are not trusted
+	for _, template := range manifest.Templates {
+		if !validTemplatePath(template.Path) {
+			return nil, fmt.Errorf("invalid path %s of template %s", template.Path, template.ID)
+		}
+	}
+
+	return manifest, nil
+}
+
+// validTemplatePath reports whether p is a clean templates/<category>/.../<id>
+// path, which stays inside the templates directory.
+func validTemplatePath(p string) bool {
+	if path.Clean(p) != p || !filepath.IsLocal(p) {
+		return false
+	}
+
+	parts := strings.Split(p, "/")
+	if len(parts) < 3 || parts[0] != TemplatesDir {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// TemplateDestination returns the directory a template of the bundle is
+// imported into under the repository directory target, which is strictly
+// below target.
+func TemplateDestination(target string, template Template) (string, error) {
+	if !validTemplatePath(template.Path) {
+		return "", fmt.Errorf("invalid path %s of template %s", template.Path, template.ID)
+	}
+
+	dest := filepath.Join(target, filepath.FromSlash(strings.TrimPrefix(template.Path, TemplatesDir+"/")))
+	rel, err := filepath.Rel(target, dest)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %s of template %s leaves the repository", template.Path, template.ID)
+	}
+	return dest, nil
+}
+
+// templateArchivePath returns the directory of a template inside the archive,
+// keeping its location within its repository so categories are preserved.
+func templateArchivePath(template tmpl.Template) (string, error) {
+	if template.Dir == "" {
+		return "", fmt.Errorf("directory of template %s is unknown", template.ID)
+	}
+
+	rel := template.ID
+	if template.RepoPath != "" {
+		var err error
+		if rel, err = filepath.Rel(template.RepoPath, template.Dir); err != nil {
+			return "", err
+		}
+	}
+	if !filepath.IsLocal(rel) || !strings.Contains(filepath.ToSlash(rel), "/") {
+		// templates must sit in a category directory to be loaded
+		rel = filepath.Join("bundle", template.ID)
+	}
+	return path.Join(TemplatesDir, filepath.ToSlash(rel)), nil
+}
+
+// addDirectory adds the regular files below dir to the archive under archivePath.
+// Hidden entries and symbolic links are skipped, as the template loader does.
+func addDirectory(tw *tar.Writer, manifest *Manifest, dir, archivePath string) error {
+	return filepath.WalkDir(dir, func(filePath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(filePath) // #nosec G304
+		if err != nil {
+			return err
+		}
+		defer file.Close() //nolint:errcheck
+
+		return addFile(tw, manifest, path.Join(archivePath, filepath.ToSlash(rel)), info, file)
+	})
+}
+
+// addImages saves the images to a temporary file, as the size of an archive
+// entry must be known before its content is written, and adds it to the archive.
+func addImages(tw *tar.Writer, manifest *Manifest, saveImages func(io.Writer) error) error {
+	file, err := os.CreateTemp("", "vt-images-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck
+	defer file.Close()           //nolint:errcheck
+
+	if err := saveImages(file); err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return addFile(tw, manifest, ImagesName, info, file)
+}
+
+// addFile writes a file entry and records its checksum in the manifest.
+func addFile(tw *tar.Writer, manifest *Manifest, name string, info os.FileInfo, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, hash), r); err != nil {
+		return err
+	}
+	manifest.Checksums[name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// extractFile writes the content of r to target and returns its checksum.
+func extractFile(r io.Reader, target string, mode os.FileMode) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return "", err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0600) // #nosec G304
+	if err != nil {
+		return "", err
+	}
+	defer file.Close() //nolint:errcheck
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), r); err != nil { // #nosec G110
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), file.Close()
+}
+
+// verifyChecksums compares the checksums of the extracted files with the
+// ones recorded in the manifest.
+func verifyChecksums(expected, actual map[string]string) error {
+	var problems []string
+	for name, checksum := range expected {
+		got, ok := actual[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is missing", name))
+		case got != checksum:
+			problems = append(problems, fmt.Sprintf("%s has checksum %s, expected %s", name, got, checksum))
+		}
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is not listed in the manifest", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("bundle verification failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
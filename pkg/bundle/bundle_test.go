@@ -0,0 +1,161 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestTemplate creates a template directory inside a category of repoPath.
+func createTestTemplate(t *testing.T, repoPath, category, id string) tmpl.Template {
+	t.Helper()
+	dir := filepath.Join(repoPath, category, id)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.yaml"), []byte("id: "+id+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte("services: {}\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref\n"), 0600))
+	return tmpl.Template{ID: id, Dir: dir, RepoPath: repoPath}
+}
+
+func TestCreateAndExtract(t *testing.T) {
+	repoPath := t.TempDir()
+	templates := []tmpl.Template{
+		createTestTemplate(t, repoPath, "web", "vt-dvwa"),
+		createTestTemplate(t, repoPath, "api", "vt-graphql"),
+	}
+
+	var archive bytes.Buffer
+	images := []string{"vulnerables/web-dvwa:latest"}
+	manifest, err := Create(&archive, templates, "docker-compose", images, func(w io.Writer) error {
+		_, err := w.Write([]byte("image layers"))
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Template{
+		{ID: "vt-dvwa", Path: "templates/web/vt-dvwa"},
+		{ID: "vt-graphql", Path: "templates/api/vt-graphql"},
+	}, manifest.Templates)
+	assert.Len(t, manifest.Checksums, 5, "two files per template and the image archive")
+	assert.NotContains(t, manifest.Checksums, "templates/web/vt-dvwa/.git/HEAD")
+
+	dir := t.TempDir()
+	extracted, err := Extract(bytes.NewReader(archive.Bytes()), dir)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Templates, extracted.Templates)
+	assert.Equal(t, images, extracted.Images)
+	assert.Equal(t, "docker-compose", extracted.Provider)
+
+	data, err := os.ReadFile(filepath.Join(dir, ImagesName))
+	require.NoError(t, err)
+	assert.Equal(t, "image layers", string(data))
+	assert.FileExists(t, filepath.Join(dir, "templates", "api", "vt-graphql", "docker-compose.yaml"))
+}
+
+func TestCreateWithoutImages(t *testing.T) {
+	template := createTestTemplate(t, t.TempDir(), "web", "vt-dvwa")
+
+	var archive bytes.Buffer
+	_, err := Create(&archive, []tmpl.Template{template}, "docker-compose", nil, func(io.Writer) error {
+		t.Fatal("images must not be saved when there are none")
+		return nil
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	_, err = Extract(&archive, dir)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, ImagesName))
+}
+
+// writeArchive writes a bundle with the given entries, the manifest last.
+func writeArchive(t *testing.T, files map[string]string, manifest Manifest) *bytes.Buffer {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	entries := []struct{ name, content string }{}
+	for name, content := range files {
+		entries = append(entries, struct{ name, content string }{name, content})
+	}
+	entries = append(entries, struct{ name, content string }{ManifestName, string(data)})
+
+	for _, entry := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0600, Size: int64(len(entry.content))}))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &archive
+}
+
+func TestExtractRejectsInvalidBundles(t *testing.T) {
+	checksum := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // empty content
+	tests := map[string]struct {
+		files    map[string]string
+		manifest Manifest
+	}{
+		"tampered file": {
+			files:    map[string]string{"templates/web/vt-dvwa/index.yaml": "changed"},
+			manifest: Manifest{Version: Version, Checksums: map[string]string{"templates/web/vt-dvwa/index.yaml": checksum}},
+		},
+		"missing file": {
+			manifest: Manifest{Version: Version, Checksums: map[string]string{ImagesName: checksum}},
+		},
+		"unlisted file": {
+			files:    map[string]string{ImagesName: ""},
+			manifest: Manifest{Version: Version},
+		},
+		"path traversal": {
+			files:    map[string]string{"../outside": ""},
+			manifest: Manifest{Version: Version, Checksums: map[string]string{"../outside": checksum}},
+		},
+		"unknown version": {
+			manifest: Manifest{Version: Version + 1},
+		},
+		// the manifest has no checksum, "templates/.." would replace the repositories directory
+		"template path leaving the templates": {
+			manifest: Manifest{Version: Version, Templates: []Template{{ID: "vt-evil", Path: "templates/.."}}},
+		},
+		"template path without category": {
+			manifest: Manifest{Version: Version, Templates: []Template{{ID: "vt-evil", Path: "templates/vt-evil"}}},
+		},
+		"unclean template path": {
+			manifest: Manifest{Version: Version, Templates: []Template{{ID: "vt-evil", Path: "templates/web/../../.."}}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := Extract(writeArchive(t, tt.files, tt.manifest), filepath.Join(dir, "bundle"))
+			assert.Error(t, err)
+			assert.NoFileExists(t, filepath.Join(dir, "outside"))
+		})
+	}
+}
+
+func TestTemplateDestination(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repositories", "bundle")
+
+	dest, err := TemplateDestination(target, Template{ID: "vt-dvwa", Path: "templates/web/vt-dvwa"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(target, "web", "vt-dvwa"), dest)
+
+	for _, p := range []string{"templates/..", "templates/web/..", "templates/./vt-dvwa", "templates//web", "templates", "other/web/vt-dvwa", "/templates/web/vt-dvwa"} {
+		_, err := TemplateDestination(target, Template{ID: "vt-evil", Path: p})
+		assert.Error(t, err, p)
+	}
+}
@@ -0,0 +1,113 @@
+// Package ctf generates the CTF flags of a deployment, plants the ones that
+// live inside running services and verifies submissions.
+package ctf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+// FlagEnv is the environment variable holding the flag in flag commands.
+const FlagEnv = "VT_FLAG"
+
+// injectInterval is the delay between two injection attempts.
+const injectInterval = 2 * time.Second
+
+// writeFileCommand writes its second argument to the file named by its first one.
+var writeFileCommand = []string{"/bin/sh", "-c", `mkdir -p "$(dirname "$1")" && printf '%s\n' "$2" > "$1"`, "vt-flag"}
+
+// Generate returns a new random flag for every flag of the template, keyed by flag name.
+func Generate(template *tmpl.Template) (map[string]string, error) {
+	flags := make(map[string]string, len(template.Flags))
+	for _, flag := range template.Flags {
+		secret := make([]byte, 16)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate flag %s: %w", flag.Name, err)
+		}
+		flags[flag.Name] = fmt.Sprintf("vt{%s}", hex.EncodeToString(secret))
+	}
+	return flags, nil
+}
+
+// Inject plants the file and command flags of the template in the running
+// instance. Services may still be starting, so every flag is retried until
+// it is planted or timeout elapses.
+func Inject(p provider.Provider, template *tmpl.Template, instance string, flags map[string]string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for _, flag := range template.Flags {
+		value, ok := flags[flag.Name]
+		if !ok || flag.Env != "" {
+			continue
+		}
+
+		opts := provider.ExecOptions{Service: flag.Service, Command: flag.Command, Env: []string{FlagEnv + "=" + value}}
+		if flag.File != "" {
+			opts.Command = append(append([]string{}, writeFileCommand...), flag.File, value)
+		}
+
+		for {
+			err := execFlag(p, template, instance, opts)
+			if err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("failed to plant flag %s: %w", flag.Name, err)
+			}
+			time.Sleep(injectInterval)
+		}
+	}
+	return nil
+}
+
+// execFlag runs a flag command, which must exit successfully.
+func execFlag(p provider.Provider, template *tmpl.Template, instance string, opts provider.ExecOptions) error {
+	exitCode, err := p.Exec(template, instance, opts)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("command exited with code %d", exitCode)
+	}
+	return nil
+}
+
+// Environment returns the flags as VT_FLAG_<NAME> environment variables,
+// sorted by name, for scripts checking that a flag can be captured.
+func Environment(flags map[string]string) []string {
+	env := make([]string, 0, len(flags))
+	for name, value := range flags {
+		key := FlagEnv + "_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Verify returns the name of the flag matching submission. Surrounding
+// whitespace of the submission is ignored.
+func Verify(flags map[string]string, submission string) (string, bool) {
+	submission = strings.TrimSpace(submission)
+
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	match := ""
+	for _, name := range names {
+		if subtle.ConstantTimeCompare([]byte(flags[name]), []byte(submission)) == 1 {
+			match = name
+		}
+	}
+	return match, match != ""
+}
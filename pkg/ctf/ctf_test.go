@@ -0,0 +1,88 @@
+package ctf
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// execProvider records exec calls and fails the first failures of them.
+type execProvider struct {
+	provider.Provider
+	failures int
+	calls    []provider.ExecOptions
+}
+
+func (e *execProvider) Exec(_ *tmpl.Template, _ string, opts provider.ExecOptions) (int, error) {
+	e.calls = append(e.calls, opts)
+	if e.failures > 0 {
+		e.failures--
+		return 0, errors.New("service is not running")
+	}
+	return 0, nil
+}
+
+func testTemplate() *tmpl.Template {
+	return &tmpl.Template{
+		ID: "vt-dvwa",
+		Flags: []tmpl.Flag{
+			{Name: "user", Env: "USER_FLAG"},
+			{Name: "root", Service: "web", File: "/root/flag.txt"},
+			{Name: "db", Service: "db", Command: []string{"sh", "-c", "mysql -e \"INSERT INTO flags VALUES('$VT_FLAG')\""}},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	template := testTemplate()
+
+	flags, err := Generate(template)
+	require.NoError(t, err)
+	require.Len(t, flags, 3)
+	for _, flag := range flags {
+		assert.Regexp(t, regexp.MustCompile(`^vt\{[0-9a-f]{32}\}$`), flag)
+	}
+
+	again, err := Generate(template)
+	require.NoError(t, err)
+	assert.NotEqual(t, flags["user"], again["user"])
+}
+
+func TestInject(t *testing.T) {
+	template := testTemplate()
+	flags := map[string]string{"user": "vt{user}", "root": "vt{root}", "db": "vt{db}"}
+	p := &execProvider{}
+
+	require.NoError(t, Inject(p, template, "vt-dvwa", flags, time.Second))
+	require.Len(t, p.calls, 2)
+
+	assert.Equal(t, "web", p.calls[0].Service)
+	assert.Equal(t, []string{"/root/flag.txt", "vt{root}"}, p.calls[0].Command[len(p.calls[0].Command)-2:])
+
+	assert.Equal(t, "db", p.calls[1].Service)
+	assert.Equal(t, template.Flags[2].Command, p.calls[1].Command)
+	assert.Equal(t, []string{"VT_FLAG=vt{db}"}, p.calls[1].Env)
+}
+
+func TestInjectGivesUp(t *testing.T) {
+	p := &execProvider{failures: 100}
+	err := Inject(p, testTemplate(), "vt-dvwa", map[string]string{"root": "vt{root}"}, 0)
+	assert.EqualError(t, err, "failed to plant flag root: service is not running")
+}
+
+func TestVerify(t *testing.T) {
+	flags := map[string]string{"user": "vt{user}", "root": "vt{root}"}
+
+	name, ok := Verify(flags, " vt{root}\n")
+	assert.True(t, ok)
+	assert.Equal(t, "root", name)
+
+	_, ok = Verify(flags, "vt{guess}")
+	assert.False(t, ok)
+}
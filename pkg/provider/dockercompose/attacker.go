@@ -0,0 +1,195 @@
+package dockercompose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	clicontainer "github.com/docker/cli/cli/command/container"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+// attackerLabel marks attacker workstations with the instance they are attached to.
+const attackerLabel = "vt.attacker"
+
+// attackerCommand keeps the workstation running until it is removed.
+var attackerCommand = []string{"/bin/sh", "-c", "trap 'exit 0' INT TERM; while :; do sleep 3600 & wait $!; done"}
+
+// attackerName returns the container name of the attacker workstation of an instance.
+func attackerName(instance string) string {
+	return projectName(instance) + "-attacker"
+}
+
+// StartAttacher starts the attacker workstation of the instance, attached to
+// every network of its compose project.
+func (d *DockerCompose) StartAttacher(template *tmpl.Template, instance string, opts provider.AttackerOptions) error {
+	exist, err := d.stateManager.DeploymentExist(d.Name(), instance)
+	if err != nil || !exist {
+		return fmt.Errorf("deployment not exist")
+	}
+
+	exist, _ = d.stateManager.AttackerExist(d.Name(), instance) //nolint:errcheck
+	if exist {
+		return fmt.Errorf("attacker already running")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	project, err := d.loadProject(template, instance)
+	if err != nil {
+		return err
+	}
+	networks := projectNetworks(project)
+	if len(networks) == 0 {
+		return fmt.Errorf("%s has no network to attach the attacker to", instance)
+	}
+
+	ctx := context.Background()
+	if err := ensureAttackerImage(ctx, dockerCli, opts.Image); err != nil {
+		return err
+	}
+
+	name := attackerName(instance)
+	apiClient := dockerCli.Client()
+	created, err := apiClient.ContainerCreate(ctx,
+		&container.Config{
+			Image:      opts.Image,
+			Hostname:   "attacker",
+			Entrypoint: attackerCommand,
+			Labels:     map[string]string{attackerLabel: instance, templateLabel: template.ID, providerLabel: d.name},
+		},
+		&container.HostConfig{NetworkMode: container.NetworkMode(networks[0])},
+		nil, nil, name)
+	if err != nil {
+		return err
+	}
+
+	err = startAttacherContainer(ctx, dockerCli, created.ID, networks[1:])
+	if err != nil {
+		if removeErr := apiClient.ContainerRemove(ctx, created.ID, container.RemoveOptions{Force: true}); removeErr != nil {
+			return fmt.Errorf("%w (cleanup failed: %v)", err, removeErr)
+		}
+		return err
+	}
+
+	return d.stateManager.AddAttacker(state.Attacker{
+		ProviderName: d.Name(),
+		Deployment:   instance,
+		Image:        opts.Image,
+		Container:    name,
+		Networks:     networks,
+		Hostnames:    serviceHostnames(project),
+	})
+}
+
+// StopAttacher removes the attacker workstation of the instance.
+func (d *DockerCompose) StopAttacher(instance string) error {
+	exist, _ := d.stateManager.AttackerExist(d.Name(), instance) //nolint:errcheck
+	if !exist {
+		return fmt.Errorf("attacker not exist")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	return d.removeAttacker(dockerCli, instance)
+}
+
+// ExecAttacher runs a command in the attacker workstation of the instance.
+func (d *DockerCompose) ExecAttacher(instance string, opts provider.ExecOptions) (int, error) {
+	attacker, err := d.stateManager.GetAttacker(d.Name(), instance)
+	if err != nil {
+		return 0, fmt.Errorf("attacker not exist")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return 0, err
+	}
+
+	execOpts := clicontainer.NewExecOptions()
+	execOpts.Command = opts.Command
+	execOpts.TTY = opts.Tty
+	execOpts.Interactive = opts.Interactive
+	execOpts.User = opts.User
+	for _, env := range opts.Env {
+		if err := execOpts.Env.Set(env); err != nil {
+			return 0, err
+		}
+	}
+
+	err = clicontainer.RunExec(context.Background(), dockerCli, attacker.Container, execOpts)
+	var statusErr cli.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode, nil
+	}
+	return 0, err
+}
+
+// removeAttacker removes the attacker workstation of the instance and its
+// record, if it has one. A container removed outside vt is ignored.
+func (d *DockerCompose) removeAttacker(dockerCli command.Cli, instance string) error {
+	attacker, err := d.stateManager.GetAttacker(d.Name(), instance)
+	if err != nil {
+		return nil
+	}
+
+	err = dockerCli.Client().ContainerRemove(context.Background(), attacker.Container, container.RemoveOptions{Force: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+
+	return d.stateManager.RemoveAttacker(d.Name(), instance)
+}
+
+// startAttacherContainer connects a created workstation to the remaining
+// networks and starts it.
+func startAttacherContainer(ctx context.Context, dockerCli command.Cli, id string, networks []string) error {
+	apiClient := dockerCli.Client()
+	for _, network := range networks {
+		if err := apiClient.NetworkConnect(ctx, network, id, nil); err != nil {
+			return err
+		}
+	}
+	return apiClient.ContainerStart(ctx, id, container.StartOptions{})
+}
+
+// serviceHostnames returns the names the services of a project are reachable
+// by from its networks: service names, container names and network aliases.
+func serviceHostnames(project *types.Project) []string {
+	seen := make(map[string]bool)
+	for name, service := range project.Services {
+		seen[name] = true
+		if service.ContainerName != "" {
+			seen[service.ContainerName] = true
+		}
+		for _, network := range service.Networks {
+			if network == nil {
+				continue
+			}
+			for _, alias := range network.Aliases {
+				seen[alias] = true
+			}
+		}
+	}
+
+	hostnames := make([]string, 0, len(seen))
+	for hostname := range seen {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
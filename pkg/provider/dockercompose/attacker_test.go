@@ -0,0 +1,31 @@
+package dockercompose
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceHostnames(t *testing.T) {
+	project := &types.Project{
+		Name: "vt-compose-test",
+		Services: types.Services{
+			"web": {
+				Name:          "web",
+				ContainerName: "dvwa",
+				Networks: map[string]*types.ServiceNetworkConfig{
+					"front": {Aliases: []string{"app", "dvwa"}},
+					"back":  nil,
+				},
+			},
+			"db": {Name: "db"},
+		},
+	}
+
+	assert.Equal(t, []string{"app", "db", "dvwa", "web"}, serviceHostnames(project))
+}
+
+func TestAttackerName(t *testing.T) {
+	assert.Equal(t, "vt-compose-student-1-attacker", attackerName("student-1"))
+}
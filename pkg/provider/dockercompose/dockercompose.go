@@ -2,63 +2,180 @@
 package dockercompose
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/docker/client"
 	"github.com/happyhackingspace/vt/internal/state"
 	"github.com/happyhackingspace/vt/pkg/provider"
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
 )
 
-var _ provider.Provider = &DockerCompose{}
+var (
+	_ provider.Provider      = &DockerCompose{}
+	_ provider.Discoverer    = &DockerCompose{}
+	_ provider.ImageArchiver = &DockerCompose{}
+	_ provider.ScriptRunner  = &DockerCompose{}
+	_ provider.Attacher      = &DockerCompose{}
+	_ provider.NetworkSharer = &DockerCompose{}
+	_ provider.Pruner        = &DockerCompose{}
+	_ provider.Remover       = &DockerCompose{}
+)
+
+// ProviderName is the name under which the Docker Compose provider is registered.
+const ProviderName = "docker-compose"
 
 // DockerCompose implements the Provider interface using Docker Compose.
 type DockerCompose struct {
-	stateManager *state.Manager
+	stateManager  *state.Manager
+	templatesPath string
+	name          string
+	host          string
+}
+
+// NewDockerCompose creates a new DockerCompose provider with the given state
+// manager, reading templates from templatesPath.
+func NewDockerCompose(sm *state.Manager, templatesPath string) *DockerCompose {
+	return &DockerCompose{
+		stateManager:  sm,
+		templatesPath: templatesPath,
+		name:          ProviderName,
+	}
+}
+
+// WithName sets the provider name used for deployment records and template
+// provider lookups, and returns the DockerCompose for chaining.
+func (d *DockerCompose) WithName(name string) *DockerCompose {
+	d.name = name
+	return d
 }
 
-// NewDockerCompose creates a new DockerCompose provider with the given state manager.
-func NewDockerCompose(sm *state.Manager) *DockerCompose {
-	return &DockerCompose{stateManager: sm}
+// WithHost sets the engine API endpoint (e.g. unix:///run/podman/podman.sock)
+// and returns the DockerCompose for chaining. An empty host uses the Docker defaults.
+func (d *DockerCompose) WithHost(host string) *DockerCompose {
+	d.host = host
+	return d
 }
 
 // Name returns the provider name.
 func (d *DockerCompose) Name() string {
-	return "docker-compose"
+	return d.name
 }
 
 // Start launches the vulnerable target environment using Docker Compose.
-func (d *DockerCompose) Start(template *tmpl.Template) error {
-	exist, _ := d.stateManager.DeploymentExist(d.Name(), template.ID) //nolint:errcheck
+// The project is isolated as opts require, joins the shared networks of opts,
+// and published host ports that are already in use are remapped according to
+// the port strategy.
+func (d *DockerCompose) Start(template *tmpl.Template, instance string, opts provider.StartOptions) error {
+	exist, _ := d.stateManager.DeploymentExist(d.Name(), instance) //nolint:errcheck
 	if exist {
 		return fmt.Errorf("already running")
 	}
 
-	dockerCli, err := createDockerCLI()
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	values, err := template.ResolveVariables(opts.Values)
+	if err != nil {
+		return err
+	}
+
+	project, err := loadComposeProject(*template, instance, d.name, d.templatesPath, withFlags(template, values, opts.Flags))
+	if err != nil {
+		return err
+	}
+
+	err = checkUnsafeServices(project, opts)
+	if err != nil {
+		return err
+	}
+
+	err = isolateProject(project, opts)
 	if err != nil {
 		return err
 	}
 
-	project, err := loadComposeProject(*template)
+	err = joinSharedNetworks(dockerCli, project, d.name, opts)
 	if err != nil {
 		return err
 	}
 
+	labelResources(project, d.name)
+
+	if host := dockerCli.Client().DaemonHost(); localDaemon(host) {
+		err = resolvePortConflicts(project, opts.PortStrategy, hostPortAvailable)
+		if err != nil {
+			return err
+		}
+	} else if opts.PortStrategy != provider.PortStrategyFail {
+		log.Warn().Msgf("the ports of the engine at %s can not be probed from here, busy host ports fail the start", host)
+	}
+
 	err = runComposeUp(dockerCli, project)
 	if err != nil {
 		return err
 	}
 
-	err = d.stateManager.AddNewDeployment(d.Name(), template.ID)
+	err = d.stateManager.AddNewDeployment(d.Name(), template.ID, instance)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = d.stateManager.SetValues(d.Name(), instance, values)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Flags) > 0 {
+		err = d.stateManager.SetFlags(d.Name(), instance, opts.Flags)
+		if err != nil {
+			return err
+		}
+	}
+
+	services, err := runComposeStatus(dockerCli, project)
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to resolve endpoints of %s", instance)
+		return nil
+	}
+
+	return d.stateManager.SetEndpoints(d.Name(), instance, endpoints(services))
+}
+
+// Stop shuts down the vulnerable target environment using Docker Compose,
+// along with its attacker workstation.
+func (d *DockerCompose) Stop(template *tmpl.Template, instance string) error {
+	exist, err := d.stateManager.DeploymentExist(d.Name(), instance)
+	if err != nil {
+		return err
+	}
+
+	if !exist {
+		return fmt.Errorf("deployment not exist")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	project, err := d.loadProject(template, instance)
+	if err != nil {
+		return err
+	}
+
+	return d.down(dockerCli, instance, project)
 }
 
-// Stop shuts down the vulnerable target environment using Docker Compose.
-func (d *DockerCompose) Stop(template *tmpl.Template) error {
-	exist, err := d.stateManager.DeploymentExist(d.Name(), template.ID)
+// Remove shuts down an instance whose template is not loaded anymore, finding
+// its containers, networks and volumes by their compose project label.
+func (d *DockerCompose) Remove(instance string) error {
+	exist, err := d.stateManager.DeploymentExist(d.Name(), instance)
 	if err != nil {
 		return err
 	}
@@ -67,22 +184,36 @@ func (d *DockerCompose) Stop(template *tmpl.Template) error {
 		return fmt.Errorf("deployment not exist")
 	}
 
-	dockerCli, err := createDockerCLI()
+	dockerCli, err := createDockerCLI(d.host)
 	if err != nil {
 		return err
 	}
 
-	project, err := loadComposeProject(*template)
+	return d.down(dockerCli, instance, nil)
+}
+
+// down removes the attacker workstation and the compose project of an
+// instance, then its deployment record. project may be nil when the template
+// of the instance is not available.
+func (d *DockerCompose) down(dockerCli command.Cli, instance string, project *types.Project) error {
+	// an attached workstation would keep the project networks from being removed
+	err := d.removeAttacker(dockerCli, instance)
 	if err != nil {
 		return err
 	}
 
-	err = runComposeDown(dockerCli, project)
+	err = runComposeDown(dockerCli, projectName(instance), project)
 	if err != nil {
 		return err
 	}
 
-	err = d.stateManager.RemoveDeployment(d.Name(), template.ID)
+	// the publish network of isolated projects is not part of the loaded project
+	err = dockerCli.Client().NetworkRemove(context.Background(), projectName(instance)+"_"+publishNetwork)
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+
+	err = d.stateManager.RemoveDeployment(d.Name(), instance)
 	if err != nil {
 		return err
 	}
@@ -91,25 +222,92 @@ func (d *DockerCompose) Stop(template *tmpl.Template) error {
 }
 
 // Status returns status the vulnerable target environment using Docker Compose.
-func (d *DockerCompose) Status(template *tmpl.Template) (string, error) {
-	dockerCli, err := createDockerCLI()
+func (d *DockerCompose) Status(template *tmpl.Template, instance string) (provider.Status, error) {
+	dockerCli, err := createDockerCLI(d.host)
 	if err != nil {
-		return "unknown", err
+		return provider.UnknownStatus(), err
 	}
 
-	project, err := loadComposeProject(*template)
+	project, err := d.loadProject(template, instance)
 	if err != nil {
-		return "unknown", err
+		return provider.UnknownStatus(), err
 	}
 
-	running, err := runComposeStats(dockerCli, project)
+	services, err := runComposeStatus(dockerCli, project)
 	if err != nil {
-		return "unknown", err
+		return provider.UnknownStatus(), err
+	}
+
+	return provider.Status{
+		State:    provider.AggregateState(services),
+		Services: services,
+	}, nil
+}
+
+// Logs streams the logs of the vulnerable target environment using Docker Compose.
+func (d *DockerCompose) Logs(template *tmpl.Template, instance string, opts provider.LogOptions) error {
+	exist, err := d.stateManager.DeploymentExist(d.Name(), instance)
+	if err != nil || !exist {
+		return fmt.Errorf("deployment not exist")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
 	}
 
-	if !running {
-		return "unknown", err
+	project, err := d.loadProject(template, instance)
+	if err != nil {
+		return err
+	}
+
+	return runComposeLogs(dockerCli, project, opts)
+}
+
+// Exec runs a command inside a service of the vulnerable target environment using Docker Compose.
+// It returns the exit code of the command.
+func (d *DockerCompose) Exec(template *tmpl.Template, instance string, opts provider.ExecOptions) (int, error) {
+	exist, err := d.stateManager.DeploymentExist(d.Name(), instance)
+	if err != nil || !exist {
+		return 0, fmt.Errorf("deployment not exist")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return 0, err
+	}
+
+	project, err := d.loadProject(template, instance)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Service == "" {
+		opts.Service, err = defaultService(project)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return runComposeExec(dockerCli, project, opts)
+}
+
+// loadProject loads the compose project of a deployed instance with the
+// variable values and flags it was started with.
+func (d *DockerCompose) loadProject(template *tmpl.Template, instance string) (*types.Project, error) {
+	var values, flags map[string]string
+	if deployment, err := d.stateManager.GetDeployment(d.Name(), instance); err == nil {
+		values, flags = deployment.Values, deployment.Flags
+	}
+	return loadComposeProject(*template, instance, d.name, d.templatesPath, withFlags(template, template.VariableValues(values), flags))
+}
+
+// Discover lists the vt-owned compose projects running on the engine.
+func (d *DockerCompose) Discover() ([]provider.Instance, error) {
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return nil, err
 	}
 
-	return "running", err
+	return discoverProjects(dockerCli, d.name)
 }
@@ -0,0 +1,170 @@
+package dockercompose
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+// Images returns the images of the template compose services, sorted and
+// without duplicates. Services without an image use the name compose gives
+// to the image it builds for the default instance of the template. Every
+// allowed value of the template variables is covered, as variables commonly
+// select the image version. The image of the proxies publishing the ports of
+// isolated projects is included.
+func (d *DockerCompose) Images(template *tmpl.Template) ([]string, error) {
+	valueSets := []map[string]string{template.VariableValues(nil)}
+	for _, variable := range template.Variables {
+		for _, allowed := range variable.Allowed {
+			valueSets = append(valueSets, template.VariableValues(map[string]string{variable.Name: allowed}))
+		}
+	}
+
+	images := []string{publishImage}
+	for _, values := range valueSets {
+		project, err := loadComposeProject(*template, template.ID, d.name, d.templatesPath, values)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range project.Services {
+			images = append(images, api.GetImageNameOrDefault(service, project.Name))
+		}
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+// SaveImages writes the images to w in the docker save format, pulling the
+// images missing on the engine first.
+func (d *DockerCompose) SaveImages(images []string, w io.Writer) error {
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, image := range images {
+		if err := ensureImage(ctx, dockerCli, image); err != nil {
+			return fmt.Errorf("%w, images built by a template must be built before bundling", err)
+		}
+	}
+
+	reader, err := dockerCli.Client().ImageSave(ctx, images)
+	if err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	defer reader.Close() //nolint:errcheck
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// LoadImages loads an archive in the docker save format into the engine.
+func (d *DockerCompose) LoadImages(r io.Reader) error {
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	response, err := dockerCli.Client().ImageLoad(context.Background(), r, true)
+	if err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	defer response.Body.Close() //nolint:errcheck
+
+	if err := jsonmessage.DisplayJSONMessagesStream(response.Body, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	return nil
+}
+
+// ensureImage pulls image unless it is already present on the engine.
+func ensureImage(ctx context.Context, dockerCli command.Cli, image string) error {
+	_, _, err := dockerCli.Client().ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	reader, err := dockerCli.Client().ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", image, err)
+	}
+	defer reader.Close() //nolint:errcheck
+
+	if err := jsonmessage.DisplayJSONMessagesStream(reader, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", image, err)
+	}
+	return nil
+}
+
+// attackerDockerfile builds provider.DefaultAttackerImage.
+//
+//go:embed attacker.Dockerfile
+var attackerDockerfile []byte
+
+// ensureAttackerImage makes the attacker image available on the engine. The
+// default toolbox is built from attackerDockerfile, other images are pulled.
+func ensureAttackerImage(ctx context.Context, dockerCli command.Cli, image string) error {
+	if image != provider.DefaultAttackerImage {
+		return ensureImage(ctx, dockerCli, image)
+	}
+
+	_, _, err := dockerCli.Client().ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	buildContext, err := dockerfileContext(attackerDockerfile)
+	if err != nil {
+		return err
+	}
+	response, err := dockerCli.Client().ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Tags:        []string{image},
+		Dockerfile:  "Dockerfile",
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build %s: %w", image, err)
+	}
+	defer response.Body.Close() //nolint:errcheck
+
+	if err := jsonmessage.DisplayJSONMessagesStream(response.Body, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to build %s: %w", image, err)
+	}
+	return nil
+}
+
+// dockerfileContext returns a build context holding only dockerfile.
+func dockerfileContext(dockerfile []byte) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0o644, Size: int64(len(dockerfile))}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(dockerfile); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,66 @@
+package dockercompose
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImages(t *testing.T) {
+	templatesPath := t.TempDir()
+	dir := filepath.Join(templatesPath, "web", "vt-dvwa")
+	require.NoError(t, os.MkdirAll(dir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.yaml"), []byte(`id: vt-dvwa
+info:
+  name: DVWA
+  author: vt
+  description: test
+  type: Lab
+  targets: [php]
+  tags: [web]
+providers:
+  docker-compose:
+    path: docker-compose.yaml
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(`services:
+  web:
+    image: vulnerables/web-dvwa:latest
+  worker:
+    image: vulnerables/web-dvwa:latest
+  db:
+    image: mariadb:10
+  seed:
+    build: .
+`), 0600))
+
+	template := &tmpl.Template{ID: "vt-dvwa"}
+	images, err := NewDockerCompose(nil, templatesPath).Images(template)
+	require.NoError(t, err)
+	assert.Equal(t, []string{publishImage, "mariadb:10", "vt-compose-vt-dvwa-seed", "vulnerables/web-dvwa:latest"}, images)
+}
+
+func TestAttackerDockerfileContext(t *testing.T) {
+	for _, tool := range []string{"curl", "nmap", "sqlmap"} {
+		assert.Contains(t, string(attackerDockerfile), tool)
+	}
+
+	buildContext, err := dockerfileContext(attackerDockerfile)
+	require.NoError(t, err)
+
+	tr := tar.NewReader(buildContext)
+	header, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Dockerfile", header.Name)
+	content, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, attackerDockerfile, content)
+
+	_, err = tr.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,210 @@
+package dockercompose
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/happyhackingspace/vt/pkg/provider"
+)
+
+// masqueradeOption is the bridge driver option controlling the source NAT
+// that gives containers outbound access.
+const masqueradeOption = "com.docker.network.bridge.enable_ip_masquerade"
+
+const (
+	// publishNetwork is the key of the network the publishing proxies of an
+	// isolated project publish ports on, its only network that is not internal.
+	publishNetwork = "vt-publish"
+	// publishLabel marks publishing proxies with the service they publish.
+	publishLabel = "vt.publish"
+	// publishSuffix is appended to the service name to name its publishing proxy.
+	publishSuffix = "-vt-publish"
+	// publishImage runs the publishing proxies.
+	publishImage = "docker.io/alpine/socat:latest"
+)
+
+// checkUnsafeServices refuses projects with services that reach into the
+// host, unless opts allow it.
+func checkUnsafeServices(project *types.Project, opts provider.StartOptions) error {
+	if opts.AllowUnsafe {
+		return nil
+	}
+
+	names := project.ServiceNames()
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		service := project.Services[name]
+
+		var settings []string
+		if service.NetworkMode == "host" {
+			settings = append(settings, "host networking")
+		}
+		if service.Privileged {
+			settings = append(settings, "privileged mode")
+		}
+		if service.Pid == "host" {
+			settings = append(settings, "the host PID namespace")
+		}
+		if service.Ipc == "host" {
+			settings = append(settings, "the host IPC namespace")
+		}
+		if service.UserNSMode == "host" {
+			settings = append(settings, "the host user namespace")
+		}
+		for _, capability := range service.CapAdd {
+			if provider.DangerousCapability(capability) {
+				settings = append(settings, "the "+capability+" capability")
+			}
+		}
+		if len(service.Devices) > 0 {
+			settings = append(settings, "host devices")
+		}
+		for _, volume := range service.Volumes {
+			if volume.Type == types.VolumeTypeBind && !withinDir(volume.Source, project.WorkingDir) {
+				settings = append(settings, "a bind mount of "+volume.Source)
+			}
+		}
+		if len(settings) > 0 {
+			problems = append(problems, fmt.Sprintf("service %s requests %s", name, strings.Join(settings, " and ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s, pass --allow-unsafe to start it anyway", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// withinDir reports whether path is dir or inside it. Templates may bind
+// their own files, but not other paths of the host.
+func withinDir(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isolateProject binds the published ports of the project to the bind
+// address of opts and, unless egress is allowed, cuts its networks off the
+// internet by making all of them internal. The engine does not publish ports
+// of containers on internal networks only, so the ports of each publishing
+// service are moved to a proxy forwarding them to the service. The proxy is
+// the only container on the publish network, which has no outbound NAT either.
+func isolateProject(project *types.Project, opts provider.StartOptions) error {
+	for name, service := range project.Services {
+		if opts.BindAddress != "" {
+			for i := range service.Ports {
+				service.Ports[i].HostIP = opts.BindAddress
+			}
+			project.Services[name] = service
+		}
+
+		if opts.AllowEgress {
+			continue
+		}
+		if service.NetworkMode == "bridge" || service.NetworkMode == "default" {
+			return fmt.Errorf("service %s uses the network of the engine, which can not be isolated, pass --allow-egress to start it anyway", name)
+		}
+	}
+
+	if opts.AllowEgress {
+		return nil
+	}
+
+	for key, network := range project.Networks {
+		if network.External {
+			return fmt.Errorf("network %s is external and can not be isolated, pass --allow-egress to start it anyway", key)
+		}
+		if network.Driver != "" && network.Driver != "bridge" {
+			return fmt.Errorf("network %s uses the %s driver and can not be isolated, pass --allow-egress to start it anyway", key, network.Driver)
+		}
+		network.Internal = true
+		project.Networks[key] = network
+	}
+
+	published := false
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+		if len(service.Ports) == 0 || service.NetworkMode != "" {
+			continue
+		}
+		project.Services[name+publishSuffix] = publishingProxy(project, service)
+		service.Ports = nil
+		project.Services[name] = service
+		published = true
+	}
+
+	if published {
+		project.Networks[publishNetwork] = types.NetworkConfig{
+			Name:       project.Name + "_" + publishNetwork,
+			Driver:     "bridge",
+			DriverOpts: types.Options{masqueradeOption: "false"},
+		}
+	}
+	return nil
+}
+
+// publishingProxy returns a service publishing the ports of service and
+// forwarding every connection to the same port of the service. It joins the
+// networks of the service to reach it, and the publish network.
+func publishingProxy(project *types.Project, service types.ServiceConfig) types.ServiceConfig {
+	networks := map[string]*types.ServiceNetworkConfig{publishNetwork: nil}
+	for key := range service.Networks {
+		networks[key] = nil
+	}
+	if len(service.Networks) == 0 {
+		networks["default"] = nil
+		if _, ok := project.Networks["default"]; !ok {
+			project.Networks["default"] = types.NetworkConfig{Name: project.Name + "_default", Internal: true}
+		}
+	}
+
+	name := service.Name + publishSuffix
+	labels := make(types.Labels, len(service.Labels)+1)
+	for key, value := range service.Labels {
+		labels[key] = value
+	}
+	labels[api.ServiceLabel] = name
+	labels[api.ConfigHashLabel] = name
+	labels[publishLabel] = service.Name
+
+	return types.ServiceConfig{
+		Name:       name,
+		Image:      publishImage,
+		Entrypoint: types.ShellCommand{"/bin/sh", "-c", forwardScript(service)},
+		Ports:      service.Ports,
+		Networks:   networks,
+		Labels:     labels,
+		Restart:    types.RestartPolicyUnlessStopped,
+		DependsOn: types.DependsOnConfig{
+			service.Name: {Condition: types.ServiceConditionStarted, Required: true},
+		},
+	}
+}
+
+// forwardScript returns the shell script of a publishing proxy, running a
+// socat forwarder for every container port the service publishes.
+func forwardScript(service types.ServiceConfig) string {
+	seen := make(map[string]bool)
+	var forwarders []string
+	for _, port := range service.Ports {
+		protocol := "TCP"
+		if strings.EqualFold(port.Protocol, "udp") {
+			protocol = "UDP"
+		}
+		forwarder := fmt.Sprintf("socat %s-LISTEN:%d,fork,reuseaddr %s:%s:%d &", protocol, port.Target, protocol, service.Name, port.Target)
+		if seen[forwarder] {
+			continue
+		}
+		seen[forwarder] = true
+		forwarders = append(forwarders, forwarder)
+	}
+	return strings.Join(append(forwarders, "wait"), " ")
+}
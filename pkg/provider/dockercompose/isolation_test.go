@@ -0,0 +1,152 @@
+package dockercompose
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIsolationProject() *types.Project {
+	return &types.Project{
+		Name: "vt-compose-test",
+		Services: types.Services{
+			"web": {
+				Name:     "web",
+				Ports:    []types.ServicePortConfig{{Target: 80, Published: "8080", Protocol: "tcp", HostIP: "0.0.0.0"}},
+				Networks: map[string]*types.ServiceNetworkConfig{"front": nil, "back": nil},
+			},
+			"db": {
+				Name:     "db",
+				Networks: map[string]*types.ServiceNetworkConfig{"back": nil},
+			},
+		},
+		Networks: types.Networks{
+			"front": {Name: "vt-compose-test_front"},
+			"back":  {Name: "vt-compose-test_back"},
+		},
+	}
+}
+
+func TestIsolateProject(t *testing.T) {
+	project := newIsolationProject()
+
+	require.NoError(t, isolateProject(project, provider.StartOptions{BindAddress: "127.0.0.1"}))
+
+	for _, key := range []string{"front", "back"} {
+		assert.True(t, project.Networks[key].Internal, key)
+	}
+	assert.Empty(t, project.Services["web"].Ports, "ports are moved to the publishing proxy")
+
+	proxy, ok := project.Services["web"+publishSuffix]
+	require.True(t, ok)
+	assert.Equal(t, publishImage, proxy.Image)
+	assert.Equal(t, "127.0.0.1", proxy.Ports[0].HostIP)
+	assert.Equal(t, "8080", proxy.Ports[0].Published)
+	assert.Equal(t, types.ShellCommand{"/bin/sh", "-c", "socat TCP-LISTEN:80,fork,reuseaddr TCP:web:80 & wait"}, proxy.Entrypoint)
+	assert.ElementsMatch(t, []string{"front", "back", publishNetwork}, slices.Collect(maps.Keys(proxy.Networks)))
+	assert.Equal(t, "web", proxy.Labels[publishLabel])
+	assert.Contains(t, proxy.DependsOn, "web")
+
+	publish := project.Networks[publishNetwork]
+	assert.False(t, publish.Internal, "the engine only publishes ports on networks that are not internal")
+	assert.Equal(t, "false", publish.DriverOpts[masqueradeOption])
+	assert.Equal(t, "vt-compose-test_"+publishNetwork, publish.Name)
+
+	project = newIsolationProject()
+	require.NoError(t, isolateProject(project, provider.StartOptions{}))
+	assert.Equal(t, "0.0.0.0", project.Services["web"+publishSuffix].Ports[0].HostIP, "an empty bind address keeps the template address")
+}
+
+func TestIsolateProjectDefaultNetwork(t *testing.T) {
+	project := &types.Project{
+		Name: "vt-compose-test",
+		Services: types.Services{
+			"web": {
+				Name: "web",
+				Ports: []types.ServicePortConfig{
+					{Target: 80, Published: "8080", Protocol: "tcp"},
+					{Target: 53, Published: "5353", Protocol: "udp"},
+				},
+			},
+		},
+		Networks: types.Networks{},
+	}
+
+	require.NoError(t, isolateProject(project, provider.StartOptions{}))
+
+	assert.True(t, project.Networks["default"].Internal)
+	proxy := project.Services["web"+publishSuffix]
+	assert.Contains(t, proxy.Networks, "default")
+	assert.Equal(t, "socat TCP-LISTEN:80,fork,reuseaddr TCP:web:80 & socat UDP-LISTEN:53,fork,reuseaddr UDP:web:53 & wait", proxy.Entrypoint[2])
+}
+
+func TestIsolateProjectWithoutPorts(t *testing.T) {
+	project := newIsolationProject()
+	web := project.Services["web"]
+	web.Ports = nil
+	project.Services["web"] = web
+
+	require.NoError(t, isolateProject(project, provider.StartOptions{}))
+	assert.Len(t, project.Services, 2)
+	assert.NotContains(t, project.Networks, publishNetwork)
+}
+
+func TestIsolateProjectAllowEgress(t *testing.T) {
+	project := newIsolationProject()
+	project.Networks["front"] = types.NetworkConfig{Name: "proxy", External: true}
+
+	require.NoError(t, isolateProject(project, provider.StartOptions{BindAddress: "10.0.0.5", AllowEgress: true}))
+	assert.Equal(t, "10.0.0.5", project.Services["web"].Ports[0].HostIP)
+	assert.Empty(t, project.Networks["back"].DriverOpts)
+
+	err := isolateProject(project, provider.StartOptions{})
+	assert.EqualError(t, err, "network front is external and can not be isolated, pass --allow-egress to start it anyway")
+}
+
+func TestCheckUnsafeServices(t *testing.T) {
+	project := newIsolationProject()
+	assert.NoError(t, checkUnsafeServices(project, provider.StartOptions{}))
+
+	web := project.Services["web"]
+	web.NetworkMode = "host"
+	web.Privileged = true
+	project.Services["web"] = web
+
+	err := checkUnsafeServices(project, provider.StartOptions{})
+	assert.EqualError(t, err, "service web requests host networking and privileged mode, pass --allow-unsafe to start it anyway")
+	assert.NoError(t, checkUnsafeServices(project, provider.StartOptions{AllowUnsafe: true}))
+}
+
+func TestCheckUnsafeServicesHostAccess(t *testing.T) {
+	project := newIsolationProject()
+	project.WorkingDir = "/templates/vt-dvwa"
+
+	web := project.Services["web"]
+	web.Volumes = []types.ServiceVolumeConfig{
+		{Type: types.VolumeTypeBind, Source: "/templates/vt-dvwa/init.sql", Target: "/docker-entrypoint-initdb.d/init.sql"},
+		{Type: types.VolumeTypeVolume, Source: "data", Target: "/var/lib/mysql"},
+	}
+	web.CapAdd = []string{"NET_BIND_SERVICE"}
+	project.Services["web"] = web
+	assert.NoError(t, checkUnsafeServices(project, provider.StartOptions{}), "binding files of the template is allowed")
+
+	db := project.Services["db"]
+	db.Ipc = "host"
+	db.UserNSMode = "host"
+	db.CapAdd = []string{"SYS_ADMIN"}
+	db.Devices = []string{"/dev/kmsg"}
+	db.Volumes = []types.ServiceVolumeConfig{
+		{Type: types.VolumeTypeBind, Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"},
+		{Type: types.VolumeTypeBind, Source: "/templates/vt-dvwa-other", Target: "/data"},
+	}
+	project.Services["db"] = db
+
+	err := checkUnsafeServices(project, provider.StartOptions{})
+	assert.EqualError(t, err, "service db requests the host IPC namespace and the host user namespace and the SYS_ADMIN capability "+
+		"and host devices and a bind mount of /var/run/docker.sock and a bind mount of /templates/vt-dvwa-other, pass --allow-unsafe to start it anyway")
+}
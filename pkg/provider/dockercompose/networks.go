@@ -0,0 +1,88 @@
+package dockercompose
+
+import (
+	"context"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/cli/cli/command"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/happyhackingspace/vt/pkg/provider"
+)
+
+// sharedLabel marks the shared networks created by vt.
+const sharedLabel = "vt.shared"
+
+// joinSharedNetworks creates the shared networks of opts that do not exist
+// yet and makes every service of the project join them. Like the networks of
+// the project, they are internal unless egress is allowed, and labelled with
+// the provider creating them.
+func joinSharedNetworks(dockerCli command.Cli, project *types.Project, providerName string, opts provider.StartOptions) error {
+	if len(opts.Networks) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	apiClient := dockerCli.Client()
+	for _, name := range opts.Networks {
+		_, err := apiClient.NetworkInspect(ctx, name, dockertypes.NetworkInspectOptions{})
+		if err == nil {
+			continue
+		}
+		if !client.IsErrNotFound(err) {
+			return err
+		}
+
+		_, err = apiClient.NetworkCreate(ctx, name, dockertypes.NetworkCreate{
+			Driver:   "bridge",
+			Internal: !opts.AllowEgress,
+			Labels:   map[string]string{sharedLabel: "true", providerLabel: providerName},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	addSharedNetworks(project, opts.Networks)
+	return nil
+}
+
+// addSharedNetworks adds the networks to the project as external networks,
+// joined by every service that does not use the network stack of another.
+func addSharedNetworks(project *types.Project, names []string) {
+	if project.Networks == nil {
+		project.Networks = make(types.Networks)
+	}
+	for _, name := range names {
+		project.Networks[name] = types.NetworkConfig{Name: name, External: true}
+	}
+
+	for key, service := range project.Services {
+		if service.NetworkMode != "" {
+			continue
+		}
+		if service.Networks == nil {
+			service.Networks = make(map[string]*types.ServiceNetworkConfig)
+		}
+		for _, name := range names {
+			service.Networks[name] = nil
+		}
+		project.Services[key] = service
+	}
+}
+
+// RemoveNetworks removes the shared networks with the given names.
+func (d *DockerCompose) RemoveNetworks(names []string) error {
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		err := dockerCli.Client().NetworkRemove(context.Background(), name)
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package dockercompose
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSharedNetworks(t *testing.T) {
+	project := newIsolationProject()
+	project.Services["proxy"] = types.ServiceConfig{Name: "proxy", NetworkMode: "service:web"}
+
+	addSharedNetworks(project, []string{"vt-scenario-lab-internal"})
+
+	assert.True(t, bool(project.Networks["vt-scenario-lab-internal"].External))
+	assert.Contains(t, project.Services["web"].Networks, "vt-scenario-lab-internal")
+	assert.Contains(t, project.Services["web"].Networks, "front", "own networks are kept")
+	assert.Contains(t, project.Services["db"].Networks, "vt-scenario-lab-internal")
+	assert.Empty(t, project.Services["proxy"].Networks, "services sharing a network stack are left alone")
+}
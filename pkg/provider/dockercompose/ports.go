@@ -0,0 +1,166 @@
+package dockercompose
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/rs/zerolog/log"
+)
+
+const maxPort = 65535
+
+// portChecker reports whether the host port can be bound on the given address.
+type portChecker func(hostIP string, port int, protocol string) bool
+
+// hostPortAvailable binds the host port briefly to find out whether it is in use.
+func hostPortAvailable(hostIP string, port int, protocol string) bool {
+	address := net.JoinHostPort(hostIP, strconv.Itoa(port))
+
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close() //nolint:errcheck
+		return true
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return false
+	}
+	_ = listener.Close() //nolint:errcheck
+	return true
+}
+
+// randomHostPort asks the kernel for a free host port.
+func randomHostPort(hostIP, protocol string) (int, error) {
+	address := net.JoinHostPort(hostIP, "0")
+
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close() //nolint:errcheck
+		return conn.LocalAddr().(*net.UDPAddr).Port, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close() //nolint:errcheck
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// localDaemon reports whether the engine listening at host publishes its
+// ports on this machine, where they can be probed. Ports of an engine reached
+// over the network are bound on another host.
+func localDaemon(host string) bool {
+	u, err := url.Parse(host)
+	if err != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "unix", "npipe":
+		return true
+	case "tcp", "http", "https":
+		if u.Hostname() == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(u.Hostname())
+		return ip != nil && ip.IsLoopback()
+	default:
+		return false
+	}
+}
+
+// resolvePortConflicts remaps the published ports of the project that are
+// already in use on the host according to strategy. Port ranges and ports
+// left to the engine to choose are not touched.
+func resolvePortConflicts(project *types.Project, strategy string, available portChecker) error {
+	if strategy == "" {
+		strategy = provider.PortStrategyOffset
+	}
+
+	switch strategy {
+	case provider.PortStrategyOffset, provider.PortStrategyRandom, provider.PortStrategyFail:
+	default:
+		return fmt.Errorf("unknown port strategy %q", strategy)
+	}
+
+	names := project.ServiceNames()
+	sort.Strings(names)
+
+	reserved := make(map[string]bool)
+	isFree := func(hostIP string, port int, protocol string) bool {
+		return !reserved[fmt.Sprintf("%d/%s", port, protocol)] && available(hostIP, port, protocol)
+	}
+
+	for _, name := range names {
+		service := project.Services[name]
+		owner := name
+		if target := service.Labels[publishLabel]; target != "" {
+			owner = target
+		}
+		for i, port := range service.Ports {
+			published, err := strconv.Atoi(port.Published)
+			if err != nil || published == 0 {
+				continue
+			}
+
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+
+			hostPort := published
+			if !isFree(port.HostIP, published, protocol) {
+				hostPort, err = freeHostPort(port.HostIP, published, protocol, strategy, isFree)
+				if err != nil {
+					return fmt.Errorf("service %s: %w", owner, err)
+				}
+				log.Warn().Msgf("host port %d of service %s is in use, publishing it on %d instead", published, owner, hostPort)
+				service.Ports[i].Published = strconv.Itoa(hostPort)
+			}
+
+			reserved[fmt.Sprintf("%d/%s", hostPort, protocol)] = true
+		}
+		project.Services[name] = service
+	}
+
+	return nil
+}
+
+// freeHostPort picks a replacement for the host port that is in use.
+func freeHostPort(hostIP string, port int, protocol, strategy string, isFree portChecker) (int, error) {
+	switch strategy {
+	case provider.PortStrategyRandom:
+		for range 10 {
+			candidate, err := randomHostPort(hostIP, protocol)
+			if err != nil {
+				return 0, err
+			}
+			if isFree(hostIP, candidate, protocol) {
+				return candidate, nil
+			}
+		}
+		return 0, fmt.Errorf("no free host port found to replace %d", port)
+	case provider.PortStrategyOffset:
+		for candidate := port + 1; candidate <= maxPort; candidate++ {
+			if isFree(hostIP, candidate, protocol) {
+				return candidate, nil
+			}
+		}
+		return 0, fmt.Errorf("no free host port found above %d", port)
+	default:
+		return 0, fmt.Errorf("host port %d is already in use", port)
+	}
+}
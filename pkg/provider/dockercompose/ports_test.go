@@ -0,0 +1,128 @@
+package dockercompose
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPortsProject() *types.Project {
+	return &types.Project{
+		Name: "vt-compose-test",
+		Services: types.Services{
+			"web": {
+				Name: "web",
+				Ports: []types.ServicePortConfig{
+					{Target: 80, Published: "80", Protocol: "tcp"},
+					{Target: 53, Published: "5353", Protocol: "udp"},
+				},
+			},
+			"db": {
+				Name: "db",
+				Ports: []types.ServicePortConfig{
+					{Target: 3306, Published: "3306", Protocol: "tcp"},
+					{Target: 9000, Published: "9000-9010", Protocol: "tcp"},
+				},
+			},
+		},
+	}
+}
+
+// occupied returns a port checker reporting the given tcp ports as in use.
+func occupied(ports ...int) portChecker {
+	return func(_ string, port int, protocol string) bool {
+		if protocol != "tcp" {
+			return true
+		}
+		for _, p := range ports {
+			if p == port {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestResolvePortConflictsOffset(t *testing.T) {
+	project := newPortsProject()
+
+	require.NoError(t, resolvePortConflicts(project, provider.PortStrategyOffset, occupied(80, 81, 3306)))
+
+	assert.Equal(t, "82", project.Services["web"].Ports[0].Published)
+	assert.Equal(t, "5353", project.Services["web"].Ports[1].Published)
+	assert.Equal(t, "3307", project.Services["db"].Ports[0].Published)
+	assert.Equal(t, "9000-9010", project.Services["db"].Ports[1].Published)
+}
+
+func TestResolvePortConflictsAvoidsPortsOfSameProject(t *testing.T) {
+	project := newPortsProject()
+	db := project.Services["db"]
+	db.Ports[0].Published = "81"
+	project.Services["db"] = db
+
+	// db is resolved first and keeps 81, so web has to skip it
+	require.NoError(t, resolvePortConflicts(project, provider.PortStrategyOffset, occupied(80)))
+
+	assert.Equal(t, "81", project.Services["db"].Ports[0].Published)
+	assert.Equal(t, "82", project.Services["web"].Ports[0].Published)
+}
+
+func TestResolvePortConflictsRandom(t *testing.T) {
+	project := newPortsProject()
+
+	require.NoError(t, resolvePortConflicts(project, provider.PortStrategyRandom, occupied(80)))
+
+	published, err := strconv.Atoi(project.Services["web"].Ports[0].Published)
+	require.NoError(t, err)
+	assert.NotEqual(t, 80, published)
+	assert.Positive(t, published)
+	assert.Equal(t, "3306", project.Services["db"].Ports[0].Published)
+}
+
+func TestResolvePortConflictsFail(t *testing.T) {
+	project := newPortsProject()
+
+	err := resolvePortConflicts(project, provider.PortStrategyFail, occupied(3306))
+	assert.EqualError(t, err, "service db: host port 3306 is already in use")
+
+	assert.NoError(t, resolvePortConflicts(newPortsProject(), provider.PortStrategyFail, occupied()))
+}
+
+func TestResolvePortConflictsDefaultsToOffset(t *testing.T) {
+	project := newPortsProject()
+
+	require.NoError(t, resolvePortConflicts(project, "", occupied(80)))
+	assert.Equal(t, "81", project.Services["web"].Ports[0].Published)
+}
+
+func TestLocalDaemon(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "unix:///var/run/docker.sock", expected: true},
+		{host: "unix:///run/user/1000/podman/podman.sock", expected: true},
+		{host: "npipe:////./pipe/docker_engine", expected: true},
+		{host: "tcp://127.0.0.1:2375", expected: true},
+		{host: "tcp://localhost:2376", expected: true},
+		{host: "tcp://[::1]:2375", expected: true},
+		{host: "tcp://192.168.1.20:2376", expected: false},
+		{host: "tcp://docker.example.com:2376", expected: false},
+		{host: "ssh://user@lab.example.com", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.expected, localDaemon(tt.host))
+		})
+	}
+}
+
+func TestResolvePortConflictsUnknownStrategy(t *testing.T) {
+	err := resolvePortConflicts(newPortsProject(), "nearest", occupied())
+	assert.EqualError(t, err, `unknown port strategy "nearest"`)
+}
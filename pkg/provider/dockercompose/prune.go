@@ -0,0 +1,245 @@
+package dockercompose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/happyhackingspace/vt/pkg/provider"
+)
+
+// pruneOrder is the order resources are removed in, users before what they use.
+var pruneOrder = []string{provider.ResourceContainer, provider.ResourceNetwork, provider.ResourceVolume, provider.ResourceImage}
+
+// inventory holds the vt-labelled resources of an engine.
+type inventory struct {
+	containers []dockertypes.Container
+	networks   []dockertypes.NetworkResource
+	volumes    []*volume.Volume
+	images     []image.Summary
+}
+
+// Orphans lists the containers, networks, volumes and built images of vt
+// compose projects, attacker workstations and script sidecars whose instance
+// is not referenced, and the shared networks no deployment joined.
+func (d *DockerCompose) Orphans(refs provider.References) ([]provider.Resource, error) {
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	apiClient := dockerCli.Client()
+	projectFilter := filters.NewArgs(filters.Arg("label", api.ProjectLabel))
+
+	var inv inventory
+	listed := make(map[string]bool)
+	for _, label := range []string{api.ProjectLabel, attackerLabel, instanceLabel} {
+		containers, err := apiClient.ContainerList(ctx, container.ListOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("label", label)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		// project containers carry the instance label too
+		for _, c := range containers {
+			if !listed[c.ID] {
+				listed[c.ID] = true
+				inv.containers = append(inv.containers, c)
+			}
+		}
+	}
+
+	for _, label := range []string{api.ProjectLabel, sharedLabel} {
+		networks, err := apiClient.NetworkList(ctx, dockertypes.NetworkListOptions{
+			Filters: filters.NewArgs(filters.Arg("label", label)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		inv.networks = append(inv.networks, networks...)
+	}
+
+	volumes, err := apiClient.VolumeList(ctx, volume.ListOptions{Filters: projectFilter})
+	if err != nil {
+		return nil, err
+	}
+	inv.volumes = volumes.Volumes
+
+	inv.images, err = apiClient.ImageList(ctx, dockertypes.ImageListOptions{Filters: projectFilter})
+	if err != nil {
+		return nil, err
+	}
+
+	return findOrphans(inv, d.name, refs), nil
+}
+
+// Prune removes the given resources, containers first.
+func (d *DockerCompose) Prune(resources []provider.Resource) error {
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	apiClient := dockerCli.Client()
+
+	var errs []error
+	for _, kind := range pruneOrder {
+		for _, resource := range resources {
+			if resource.Kind != kind {
+				continue
+			}
+
+			var err error
+			switch kind {
+			case provider.ResourceContainer:
+				err = apiClient.ContainerRemove(ctx, resource.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+			case provider.ResourceNetwork:
+				err = apiClient.NetworkRemove(ctx, resource.ID)
+			case provider.ResourceVolume:
+				err = apiClient.VolumeRemove(ctx, resource.ID, true)
+			case provider.ResourceImage:
+				_, err = apiClient.ImageRemove(ctx, resource.ID, dockertypes.ImageRemoveOptions{PruneChildren: true})
+			}
+			if err != nil && !client.IsErrNotFound(err) {
+				errs = append(errs, fmt.Errorf("%s %s: %w", kind, resource.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// findOrphans returns the resources of inv whose instance or shared network is
+// not referenced, sorted by kind and name. Resources labelled with another
+// provider sharing the engine are left alone, as are the projects with
+// containers of another provider, for resources created before they were
+// labelled.
+func findOrphans(inv inventory, providerName string, refs provider.References) []provider.Resource {
+	var orphans []provider.Resource
+	foreign := make(map[string]bool)
+	seen := make(map[string]bool)
+
+	add := func(kind, id, name, instance string) {
+		if seen[kind+"/"+id] {
+			return
+		}
+		seen[kind+"/"+id] = true
+		orphans = append(orphans, provider.Resource{Kind: kind, ID: id, Name: name, Instance: instance})
+	}
+
+	for _, c := range inv.containers {
+		if foreignResource(c.Labels, providerName) {
+			foreign[c.Labels[api.ProjectLabel]] = true
+		}
+	}
+
+	for _, c := range inv.containers {
+		if foreignResource(c.Labels, providerName) {
+			continue
+		}
+
+		instance := c.Labels[attackerLabel]
+		if instance == "" {
+			project := c.Labels[api.ProjectLabel]
+			switch {
+			case strings.HasPrefix(project, projectPrefix):
+				instance = c.Labels[instanceLabel]
+				if instance == "" {
+					instance = strings.TrimPrefix(project, projectPrefix)
+				}
+			case project == "" && c.Labels[instanceLabel] != "":
+				// script sidecars are not part of the project
+				instance = c.Labels[instanceLabel]
+			default:
+				continue
+			}
+		}
+		if !refs.Instances[instance] {
+			add(provider.ResourceContainer, c.ID, containerName(c), instance)
+		}
+	}
+
+	for _, n := range inv.networks {
+		if foreignResource(n.Labels, providerName) {
+			continue
+		}
+		if n.Labels[sharedLabel] != "" {
+			if !refs.Networks[n.Name] {
+				add(provider.ResourceNetwork, n.ID, n.Name, "")
+			}
+			continue
+		}
+		if instance, ok := projectInstance(n.Labels, foreign); ok && !refs.Instances[instance] {
+			add(provider.ResourceNetwork, n.ID, n.Name, instance)
+		}
+	}
+
+	for _, v := range inv.volumes {
+		if foreignResource(v.Labels, providerName) {
+			continue
+		}
+		if instance, ok := projectInstance(v.Labels, foreign); ok && !refs.Instances[instance] {
+			add(provider.ResourceVolume, v.Name, v.Name, instance)
+		}
+	}
+
+	for _, i := range inv.images {
+		if foreignResource(i.Labels, providerName) {
+			continue
+		}
+		if instance, ok := projectInstance(i.Labels, foreign); ok && !refs.Instances[instance] {
+			name := i.ID
+			if len(i.RepoTags) > 0 {
+				name = i.RepoTags[0]
+			}
+			add(provider.ResourceImage, i.ID, name, instance)
+		}
+	}
+
+	rank := make(map[string]int, len(pruneOrder))
+	for i, kind := range pruneOrder {
+		rank[kind] = i
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return rank[orphans[i].Kind] < rank[orphans[j].Kind]
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans
+}
+
+// foreignResource reports whether a resource is labelled with another provider.
+func foreignResource(labels map[string]string, providerName string) bool {
+	owner := labels[providerLabel]
+	return owner != "" && owner != providerName
+}
+
+// projectInstance returns the instance of a resource labelled with a vt
+// compose project that is not foreign.
+func projectInstance(labels map[string]string, foreign map[string]bool) (string, bool) {
+	project := labels[api.ProjectLabel]
+	if !strings.HasPrefix(project, projectPrefix) || foreign[project] {
+		return "", false
+	}
+	return strings.TrimPrefix(project, projectPrefix), true
+}
+
+// containerName returns the name of a listed container without its leading slash.
+func containerName(c dockertypes.Container) string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
@@ -0,0 +1,74 @@
+package dockercompose
+
+import (
+	"testing"
+
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOrphans(t *testing.T) {
+	inv := inventory{
+		containers: []dockertypes.Container{
+			{ID: "c1", Names: []string{"/vt-compose-kept-web-1"}, Labels: map[string]string{api.ProjectLabel: "vt-compose-kept", instanceLabel: "kept", providerLabel: ProviderName}},
+			{ID: "c2", Names: []string{"/vt-compose-gone-web-1"}, Labels: map[string]string{api.ProjectLabel: "vt-compose-gone", instanceLabel: "gone", providerLabel: ProviderName}},
+			{ID: "c3", Names: []string{"/vt-compose-gone-attacker"}, Labels: map[string]string{attackerLabel: "gone", providerLabel: ProviderName}},
+			{ID: "c4", Names: []string{"/vt-compose-other-web-1"}, Labels: map[string]string{api.ProjectLabel: "vt-compose-other", instanceLabel: "other", providerLabel: "podman"}},
+			{ID: "c5", Names: []string{"/myapp-web-1"}, Labels: map[string]string{api.ProjectLabel: "myapp"}},
+			{ID: "c6", Names: []string{"/eager_verify"}, Labels: map[string]string{instanceLabel: "gone-verify-1a2b", providerLabel: ProviderName}},
+			{ID: "c7", Names: []string{"/brave_verify"}, Labels: map[string]string{instanceLabel: "kept", providerLabel: ProviderName}},
+			{ID: "c8", Names: []string{"/calm_verify"}, Labels: map[string]string{instanceLabel: "pod-verify", providerLabel: "podman"}},
+		},
+		networks: []dockertypes.NetworkResource{
+			{ID: "n1", Name: "vt-compose-gone_default", Labels: map[string]string{api.ProjectLabel: "vt-compose-gone"}},
+			{ID: "n2", Name: "vt-compose-kept_default", Labels: map[string]string{api.ProjectLabel: "vt-compose-kept"}},
+			{ID: "n3", Name: "vt-compose-other_default", Labels: map[string]string{api.ProjectLabel: "vt-compose-other"}},
+			{ID: "n4", Name: "vt-scenario-lab-internal", Labels: map[string]string{sharedLabel: "true"}},
+			{ID: "n5", Name: "vt-scenario-old-internal", Labels: map[string]string{sharedLabel: "true"}},
+			{ID: "n1", Name: "vt-compose-gone_default", Labels: map[string]string{api.ProjectLabel: "vt-compose-gone"}},
+			// left behind by podman on the same engine, without any container
+			{ID: "n6", Name: "vt-compose-stale_default", Labels: map[string]string{api.ProjectLabel: "vt-compose-stale", providerLabel: "podman"}},
+			{ID: "n7", Name: "vt-scenario-pod-internal", Labels: map[string]string{sharedLabel: "true", providerLabel: "podman"}},
+		},
+		volumes: []*volume.Volume{
+			{Name: "vt-compose-gone_data", Labels: map[string]string{api.ProjectLabel: "vt-compose-gone"}},
+			{Name: "myapp_data", Labels: map[string]string{api.ProjectLabel: "myapp"}},
+			{Name: "vt-compose-stale_data", Labels: map[string]string{api.ProjectLabel: "vt-compose-stale", providerLabel: "podman"}},
+		},
+		images: []image.Summary{
+			{ID: "sha256:1", RepoTags: []string{"vt-compose-gone-web:latest"}, Labels: map[string]string{api.ProjectLabel: "vt-compose-gone"}},
+			{ID: "sha256:2", Labels: map[string]string{api.ProjectLabel: "vt-compose-kept"}},
+			{ID: "sha256:3", Labels: map[string]string{api.ProjectLabel: "vt-compose-stale", providerLabel: "podman"}},
+		},
+	}
+
+	refs := provider.References{
+		Instances: map[string]bool{"kept": true},
+		Networks:  map[string]bool{"vt-scenario-lab-internal": true},
+	}
+
+	assert.Equal(t, []provider.Resource{
+		{Kind: provider.ResourceContainer, ID: "c6", Name: "eager_verify", Instance: "gone-verify-1a2b"},
+		{Kind: provider.ResourceContainer, ID: "c3", Name: "vt-compose-gone-attacker", Instance: "gone"},
+		{Kind: provider.ResourceContainer, ID: "c2", Name: "vt-compose-gone-web-1", Instance: "gone"},
+		{Kind: provider.ResourceNetwork, ID: "n1", Name: "vt-compose-gone_default", Instance: "gone"},
+		{Kind: provider.ResourceNetwork, ID: "n5", Name: "vt-scenario-old-internal"},
+		{Kind: provider.ResourceVolume, ID: "vt-compose-gone_data", Name: "vt-compose-gone_data", Instance: "gone"},
+		{Kind: provider.ResourceImage, ID: "sha256:1", Name: "vt-compose-gone-web:latest", Instance: "gone"},
+	}, findOrphans(inv, ProviderName, refs))
+}
+
+func TestFindOrphans_NothingReferenced(t *testing.T) {
+	inv := inventory{
+		images: []image.Summary{{ID: "sha256:1", Labels: map[string]string{api.ProjectLabel: "vt-compose-gone"}}},
+	}
+
+	orphans := findOrphans(inv, ProviderName, provider.References{})
+
+	assert.Len(t, orphans, 1)
+	assert.Equal(t, "sha256:1", orphans[0].Name, "untagged images are named by ID")
+}
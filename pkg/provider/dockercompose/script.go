@@ -0,0 +1,121 @@
+package dockercompose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+// RunScript runs a script in a throwaway container attached to the networks
+// of the instance, where services are reachable by their name.
+func (d *DockerCompose) RunScript(template *tmpl.Template, instance string, opts provider.ScriptOptions) (int, error) {
+	exist, err := d.stateManager.DeploymentExist(d.Name(), instance)
+	if err != nil {
+		return 0, err
+	}
+	if !exist {
+		return 0, fmt.Errorf("deployment not exist")
+	}
+
+	dockerCli, err := createDockerCLI(d.host)
+	if err != nil {
+		return 0, err
+	}
+
+	project, err := d.loadProject(template, instance)
+	if err != nil {
+		return 0, err
+	}
+	networks := projectNetworks(project)
+	if len(networks) == 0 {
+		return 0, fmt.Errorf("%s has no network to attach the script to", instance)
+	}
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	if err := ensureImage(ctx, dockerCli, opts.Image); err != nil {
+		return 0, err
+	}
+
+	// labelled so that prune finds the sidecar when vt is killed while it runs
+	labels := map[string]string{instanceLabel: instance, templateLabel: template.ID, providerLabel: d.name}
+
+	apiClient := dockerCli.Client()
+	created, err := apiClient.ContainerCreate(ctx,
+		&container.Config{Image: opts.Image, Entrypoint: []string{"/bin/sh", "-c", opts.Script}, Env: opts.Env, Labels: labels},
+		&container.HostConfig{NetworkMode: container.NetworkMode(networks[0])},
+		nil, nil, "")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		// the run context may have expired, removal must still happen
+		_ = apiClient.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true}) //nolint:errcheck
+	}()
+
+	for _, network := range networks[1:] {
+		if err := apiClient.NetworkConnect(ctx, network, created.ID, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	waitCh, errCh := apiClient.ContainerWait(ctx, created.ID, container.WaitConditionNextExit)
+	if err := apiClient.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return 0, err
+	}
+
+	var exitCode int
+	select {
+	case response := <-waitCh:
+		if response.Error != nil {
+			return 0, errors.New(response.Error.Message)
+		}
+		exitCode = int(response.StatusCode)
+	case err := <-errCh:
+		return 0, err
+	}
+
+	if opts.Output != nil {
+		reader, err := apiClient.ContainerLogs(ctx, created.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+		if err != nil {
+			return exitCode, err
+		}
+		defer reader.Close() //nolint:errcheck
+		if _, err := stdcopy.StdCopy(opts.Output, opts.Output, reader); err != nil && !errors.Is(err, io.EOF) {
+			return exitCode, err
+		}
+	}
+
+	return exitCode, nil
+}
+
+// projectNetworks returns the names of the networks of a compose project, the
+// default network first.
+func projectNetworks(project *types.Project) []string {
+	var names []string
+	for key, network := range project.Networks {
+		if key == "default" {
+			continue
+		}
+		names = append(names, network.Name)
+	}
+	sort.Strings(names)
+
+	if network, ok := project.Networks["default"]; ok {
+		names = append([]string{network.Name}, names...)
+	}
+	return names
+}
@@ -3,25 +3,47 @@ package dockercompose
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/compose-spec/compose-go/v2/loader"
 	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/flags"
+	"github.com/docker/compose/v2/cmd/formatter"
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/docker/compose/v2/pkg/compose"
-	"github.com/happyhackingspace/vt/internal/app"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
 )
 
-func createDockerCLI() (command.Cli, error) {
+const (
+	projectPrefix = "vt-compose-"
+
+	templateLabel = "vt.template"
+	instanceLabel = "vt.instance"
+	providerLabel = "vt.provider"
+)
+
+// projectName returns the compose project name of a template instance.
+func projectName(instance string) string {
+	return projectPrefix + instance
+}
+
+func createDockerCLI(host string) (command.Cli, error) {
 	dockerCli, err := command.NewDockerCli()
 	if err != nil {
 		return nil, err
 	}
 
 	opts := flags.NewClientOptions()
+	if host != "" {
+		opts.Hosts = []string{host}
+	}
 	err = dockerCli.Initialize(opts)
 	if err != nil {
 		return nil, err
@@ -30,14 +52,31 @@ func createDockerCLI() (command.Cli, error) {
 	return dockerCli, nil
 }
 
-func loadComposeProject(template tmpl.Template) (*types.Project, error) {
-	cfg := app.DefaultConfig()
-	composePath, workingDir, err := tmpl.GetDockerComposePath(template.ID, cfg.TemplatesPath)
+// withFlags returns the variable values along with the env flags of the template.
+func withFlags(template *tmpl.Template, values, flags map[string]string) map[string]string {
+	environment := template.FlagEnvironment(flags)
+	for name, value := range values {
+		environment[name] = value
+	}
+	return environment
+}
+
+// loadComposeProject loads the compose project of a template instance. Every
+// instance gets its own project, and therefore its own containers and networks.
+// The template variable values are available to the compose interpolation.
+func loadComposeProject(template tmpl.Template, instance, providerName, templatesPath string, values map[string]string) (*types.Project, error) {
+	composePath, workingDir, err := tmpl.GetComposePath(template.ID, template.RepositoryPath(templatesPath), providerName)
 	if err != nil {
 		return nil, err
 	}
 
-	projectName := fmt.Sprintf("vt-compose-%s", template.ID)
+	projectName := projectName(instance)
+
+	environment := make(map[string]string, len(values)+1)
+	for name, value := range values {
+		environment[name] = value
+	}
+	environment["COMPOSE_PROJECT_NAME"] = projectName
 
 	configDetails := types.ConfigDetails{
 		WorkingDir: workingDir,
@@ -46,9 +85,7 @@ func loadComposeProject(template tmpl.Template) (*types.Project, error) {
 				Filename: composePath,
 			},
 		},
-		Environment: map[string]string{
-			"COMPOSE_PROJECT_NAME": projectName,
-		},
+		Environment: environment,
 	}
 
 	project, err := loader.LoadWithContext(
@@ -77,6 +114,9 @@ func loadComposeProject(template tmpl.Template) (*types.Project, error) {
 		serviceCopy.Labels["com.docker.compose.project.config_files"] = composePath
 		serviceCopy.Labels["com.docker.compose.config-hash"] = name
 		serviceCopy.Labels["com.docker.compose.oneoff"] = "False"
+		serviceCopy.Labels[templateLabel] = template.ID
+		serviceCopy.Labels[instanceLabel] = instance
+		serviceCopy.Labels[providerLabel] = providerName
 		updatedServices[name] = serviceCopy
 	}
 	project.Services = updatedServices
@@ -84,6 +124,36 @@ func loadComposeProject(template tmpl.Template) (*types.Project, error) {
 	return project, nil
 }
 
+// labelResources labels the networks, volumes and built images of the project
+// with the provider, like its containers, so that a provider pruning a shared
+// engine leaves the resources of the other alone. External resources are not
+// created by the project and keep their labels.
+func labelResources(project *types.Project, providerName string) {
+	for key, network := range project.Networks {
+		if network.External {
+			continue
+		}
+		network.Labels = network.Labels.Add(providerLabel, providerName)
+		project.Networks[key] = network
+	}
+
+	for key, volume := range project.Volumes {
+		if volume.External {
+			continue
+		}
+		volume.Labels = volume.Labels.Add(providerLabel, providerName)
+		project.Volumes[key] = volume
+	}
+
+	for name, service := range project.Services {
+		if service.Build == nil {
+			continue
+		}
+		service.Build.Labels = service.Build.Labels.Add(providerLabel, providerName)
+		project.Services[name] = service
+	}
+}
+
 func runComposeUp(dockerCli command.Cli, project *types.Project) error {
 	composeService := compose.NewComposeService(dockerCli)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
@@ -117,12 +187,12 @@ func runComposeUp(dockerCli command.Cli, project *types.Project) error {
 	return nil
 }
 
-func runComposeDown(dockerCli command.Cli, project *types.Project) error {
+func runComposeDown(dockerCli command.Cli, name string, project *types.Project) error {
 	composeService := compose.NewComposeService(dockerCli)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	err := composeService.Down(ctx, project.Name, api.DownOptions{
+	err := composeService.Down(ctx, name, api.DownOptions{
 		Project:       project,
 		RemoveOrphans: true,
 		Volumes:       true,
@@ -134,7 +204,7 @@ func runComposeDown(dockerCli command.Cli, project *types.Project) error {
 	return nil
 }
 
-func runComposeStats(dockerCli command.Cli, project *types.Project) (bool, error) {
+func runComposeStatus(dockerCli command.Cli, project *types.Project) ([]provider.ServiceStatus, error) {
 	composeService := compose.NewComposeService(dockerCli)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
@@ -143,16 +213,211 @@ func runComposeStats(dockerCli command.Cli, project *types.Project) (bool, error
 		Project: project,
 		All:     true,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]provider.ServiceStatus, 0, len(summary))
+	published := make(map[string][]provider.PortMapping)
+	for _, container := range summary {
+		// publishing proxies are reported through the service they publish
+		if target := container.Labels[publishLabel]; target != "" {
+			published[target] = append(published[target], portMappings(container.Publishers)...)
+			continue
+		}
+
+		service := provider.ServiceStatus{
+			Name:      container.Service,
+			Container: container.Name,
+			State:     container.State,
+			Health:    container.Health,
+			ExitCode:  container.ExitCode,
+			Ports:     portMappings(container.Publishers),
+		}
+
+		inspect, err := dockerCli.Client().ContainerInspect(ctx, container.ID)
+		if err != nil {
+			return nil, err
+		}
+		if inspect.ContainerJSONBase != nil {
+			service.RestartCount = inspect.RestartCount
+			if inspect.State != nil {
+				service.StartedAt, _ = time.Parse(time.RFC3339Nano, inspect.State.StartedAt) //nolint:errcheck
+			}
+		}
+
+		services = append(services, service)
+	}
+
+	for i := range services {
+		services[i].Ports = append(services[i].Ports, published[services[i].Name]...)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Container < services[j].Container
+	})
+
+	return services, nil
+}
+
+// portMappings converts the published ports of a container, skipping unpublished ones.
+func portMappings(publishers api.PortPublishers) []provider.PortMapping {
+	var ports []provider.PortMapping
+	for _, publisher := range publishers {
+		if publisher.PublishedPort == 0 {
+			continue
+		}
+		ports = append(ports, provider.PortMapping{
+			HostIP:        publisher.URL,
+			HostPort:      publisher.PublishedPort,
+			ContainerPort: publisher.TargetPort,
+			Protocol:      publisher.Protocol,
+		})
+	}
+	return ports
+}
 
+// endpoints returns the host endpoints of the published ports of the given services.
+// Ports published on both IPv4 and IPv6 are reported once.
+func endpoints(services []provider.ServiceStatus) []state.Endpoint {
+	var result []state.Endpoint
+	seen := make(map[string]bool)
+	for _, service := range services {
+		for _, port := range service.Ports {
+			key := fmt.Sprintf("%s/%d/%d/%s", service.Name, port.HostPort, port.ContainerPort, port.Protocol)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			result = append(result, state.Endpoint{
+				Service:       service.Name,
+				HostIP:        port.HostIP,
+				HostPort:      port.HostPort,
+				ContainerPort: port.ContainerPort,
+				Protocol:      port.Protocol,
+			})
+		}
+	}
+	return result
+}
+
+func runComposeLogs(dockerCli command.Cli, project *types.Project, opts provider.LogOptions) error {
+	composeService := compose.NewComposeService(dockerCli)
+
+	// following logs runs until interrupted, so it must not be bound by a timeout
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if opts.Follow {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Minute)
+	}
+	defer cancel()
+
+	output := opts.Output
+	if output == nil {
+		output = dockerCli.Out()
+	}
+
+	consumer := formatter.NewLogConsumer(ctx, output, output, false, true, false)
+	return composeService.Logs(ctx, project.Name, consumer, api.LogOptions{
+		Project:    project,
+		Services:   opts.Services,
+		Tail:       opts.Tail,
+		Since:      opts.Since,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	})
+}
+
+// discoverProjects lists the vt-owned compose projects of the engine by the
+// labels set by loadComposeProject. Projects created before instances existed
+// only carry the compose project label, their instance is named after the template.
+func discoverProjects(dockerCli command.Cli, providerName string) ([]provider.Instance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	containers, err := dockerCli.Client().ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", api.ProjectLabel)),
+	})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	for i := 0; i < len(summary); i++ {
-		if summary[i].State != "running" {
-			return false, nil
+	instances := make(map[string]*provider.Instance)
+	for _, c := range containers {
+		project := c.Labels[api.ProjectLabel]
+		if !strings.HasPrefix(project, projectPrefix) {
+			continue
+		}
+		if owner := c.Labels[providerLabel]; owner != "" && owner != providerName {
+			continue
+		}
+
+		name := c.Labels[instanceLabel]
+		if name == "" {
+			name = strings.TrimPrefix(project, projectPrefix)
+		}
+
+		templateID := c.Labels[templateLabel]
+		if templateID == "" {
+			templateID = name
+		}
+
+		createdAt := time.Unix(c.Created, 0)
+		instance, ok := instances[name]
+		if !ok {
+			instance = &provider.Instance{Name: name, TemplateID: templateID, CreatedAt: createdAt}
+			instances[name] = instance
 		}
+		if c.State == "running" {
+			instance.Running = true
+		}
+		if createdAt.Before(instance.CreatedAt) {
+			instance.CreatedAt = createdAt
+		}
+	}
+
+	result := make([]provider.Instance, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, *instance)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// defaultService returns the only service of the project, failing when the
+// project defines several services and the caller has to pick one.
+func defaultService(project *types.Project) (string, error) {
+	names := project.ServiceNames()
+	if len(names) == 1 {
+		return names[0], nil
 	}
 
-	return true, nil
+	sort.Strings(names)
+	return "", fmt.Errorf("template defines multiple services (%s), specify one with --service", strings.Join(names, ", "))
+}
+
+func runComposeExec(dockerCli command.Cli, project *types.Project, opts provider.ExecOptions) (int, error) {
+	composeService := compose.NewComposeService(dockerCli)
+
+	if _, err := project.GetService(opts.Service); err != nil {
+		return 0, err
+	}
+
+	return composeService.Exec(context.Background(), project.Name, api.RunOptions{
+		Project:     project,
+		Service:     opts.Service,
+		Command:     opts.Command,
+		Tty:         opts.Tty,
+		Interactive: opts.Interactive,
+		User:        opts.User,
+		Environment: opts.Env,
+		Index:       1,
+	})
 }
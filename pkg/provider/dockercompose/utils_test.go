@@ -0,0 +1,77 @@
+package dockercompose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadComposeProjectInterpolatesVariables(t *testing.T) {
+	templatesPath := t.TempDir()
+	dir := filepath.Join(templatesPath, "web", "vt-test")
+	require.NoError(t, os.MkdirAll(dir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.yaml"), []byte(`id: vt-test
+info:
+  name: Test
+  author: vt
+  description: test
+  type: Lab
+  targets: [php]
+  tags: [web]
+variables:
+  - name: DB_VERSION
+    default: "11"
+  - name: FLAG
+    required: true
+providers:
+  docker-compose:
+    path: docker-compose.yaml
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(`services:
+  db:
+    image: mariadb:${DB_VERSION}
+    environment:
+      FLAG: ${FLAG}
+`), 0600))
+
+	template := tmpl.Template{ID: "vt-test"}
+	project, err := loadComposeProject(template, "vt-test", ProviderName, templatesPath, map[string]string{"DB_VERSION": "10", "FLAG": "vt{x}"})
+	require.NoError(t, err)
+
+	service := project.Services["db"]
+	assert.Equal(t, "mariadb:10", service.Image)
+	require.NotNil(t, service.Environment["FLAG"])
+	assert.Equal(t, "vt{x}", *service.Environment["FLAG"])
+}
+
+func TestLabelResources(t *testing.T) {
+	project := &types.Project{
+		Name: "vt-compose-test",
+		Services: types.Services{
+			"web": {Name: "web", Build: &types.BuildConfig{Context: "."}},
+			"db":  {Name: "db", Image: "mariadb"},
+		},
+		Networks: types.Networks{
+			"default": {Name: "vt-compose-test_default", Labels: types.Labels{"team": "red"}},
+			"shared":  {Name: "vt-scenario-lab-internal", External: true},
+		},
+		Volumes: types.Volumes{
+			"data":  {Name: "vt-compose-test_data"},
+			"cache": {Name: "cache", External: true},
+		},
+	}
+
+	labelResources(project, "podman")
+
+	assert.Equal(t, types.Labels{"team": "red", providerLabel: "podman"}, project.Networks["default"].Labels)
+	assert.Empty(t, project.Networks["shared"].Labels, "external networks are not created by the project")
+	assert.Equal(t, types.Labels{providerLabel: "podman"}, project.Volumes["data"].Labels)
+	assert.Empty(t, project.Volumes["cache"].Labels)
+	assert.Equal(t, types.Labels{providerLabel: "podman"}, project.Services["web"].Build.Labels)
+	assert.Nil(t, project.Services["db"].Build)
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// findExecTarget returns the running pod and container matching service.
+// An empty service is accepted when the namespace runs a single container.
+func findExecTarget(ctx context.Context, clientset kubernetes.Interface, namespace, service string) (string, string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", "", err
+	}
+
+	type target struct{ pod, container string }
+	var targets []target
+	names := make(map[string]bool)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != "" && pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			names[container.Name] = true
+			if service == "" || container.Name == service {
+				targets = append(targets, target{pod: pod.Name, container: container.Name})
+			}
+		}
+	}
+
+	if service == "" && len(names) > 1 {
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		return "", "", fmt.Errorf("template defines multiple services (%s), specify one with --service", strings.Join(sorted, ", "))
+	}
+
+	if len(targets) == 0 {
+		if service == "" {
+			return "", "", fmt.Errorf("no running containers found in namespace %s", namespace)
+		}
+		return "", "", fmt.Errorf("no running container found for service %q", service)
+	}
+
+	return targets[0].pod, targets[0].container, nil
+}
+
+// execInContainer runs the command in the given container and returns its exit code.
+func execInContainer(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	restConfig *rest.Config,
+	namespace, podName, containerName string,
+	opts provider.ExecOptions,
+) (int, error) {
+	command := opts.Command
+	if len(opts.Env) > 0 {
+		command = append(append([]string{"env"}, opts.Env...), command...)
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdin:     opts.Interactive,
+			Stdout:    true,
+			Stderr:    !opts.Tty,
+			TTY:       opts.Tty,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		return 0, err
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdout: os.Stdout,
+		Tty:    opts.Tty,
+	}
+	if opts.Interactive {
+		streamOpts.Stdin = os.Stdin
+	}
+	if !opts.Tty {
+		streamOpts.Stderr = os.Stderr
+	}
+
+	stdinFd := int(os.Stdin.Fd()) // #nosec G115
+	if opts.Tty && term.IsTerminal(stdinFd) {
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return 0, err
+		}
+		defer term.Restore(stdinFd, oldState) //nolint:errcheck
+	}
+
+	err = executor.StreamWithContext(ctx, streamOpts)
+	var exitErr utilexec.CodeExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
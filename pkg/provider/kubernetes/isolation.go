@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// egressPolicyName is the name of the network policy cutting an instance off the internet.
+const egressPolicyName = "vt-deny-egress"
+
+// podSpec returns the pod spec of the manifest objects running pods.
+func podSpec(object runtime.Object) (string, *corev1.PodSpec) {
+	switch obj := object.(type) {
+	case *corev1.Pod:
+		return obj.Name, &obj.Spec
+	case *appsv1.Deployment:
+		return obj.Name, &obj.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return obj.Name, &obj.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return obj.Name, &obj.Spec.Template.Spec
+	case *batchv1.Job:
+		return obj.Name, &obj.Spec.Template.Spec
+	}
+	return "", nil
+}
+
+// exposesNodes reports whether bindAddress asks for ports reachable from
+// other hosts, which NodePort and LoadBalancer services always are.
+func exposesNodes(bindAddress string) bool {
+	ip := net.ParseIP(bindAddress)
+	return ip != nil && !ip.IsLoopback()
+}
+
+// checkUnsafeObjects refuses manifests with pods that reach into the node,
+// unless opts allow it. NodePort and LoadBalancer services, which listen on
+// every node, are refused too unless the bind address is not a loopback one.
+func checkUnsafeObjects(objects []runtime.Object, opts provider.StartOptions) error {
+	if opts.AllowUnsafe {
+		return nil
+	}
+
+	var problems, exposed []string
+	for _, object := range objects {
+		if service, ok := object.(*corev1.Service); ok {
+			switch service.Spec.Type {
+			case corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+				if !exposesNodes(opts.BindAddress) {
+					exposed = append(exposed, fmt.Sprintf("Service %s is a %s service listening on every node", service.Name, service.Spec.Type))
+				}
+			}
+			continue
+		}
+
+		name, spec := podSpec(object)
+		if spec == nil {
+			continue
+		}
+
+		var settings []string
+		if spec.HostNetwork {
+			settings = append(settings, "host networking")
+		}
+		if spec.HostPID {
+			settings = append(settings, "the host PID namespace")
+		}
+		if spec.HostIPC {
+			settings = append(settings, "the host IPC namespace")
+		}
+		privileged := false
+		for _, container := range append(spec.InitContainers, spec.Containers...) {
+			if container.SecurityContext == nil {
+				continue
+			}
+			if container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged && !privileged {
+				settings = append(settings, "privileged mode")
+				privileged = true
+			}
+			if container.SecurityContext.Capabilities != nil {
+				for _, capability := range container.SecurityContext.Capabilities.Add {
+					if provider.DangerousCapability(string(capability)) {
+						settings = append(settings, "the "+string(capability)+" capability")
+					}
+				}
+			}
+		}
+		for _, volume := range spec.Volumes {
+			if volume.HostPath != nil {
+				settings = append(settings, "a host path volume of "+volume.HostPath.Path)
+			}
+		}
+
+		if len(settings) > 0 {
+			kind := object.GetObjectKind().GroupVersionKind().Kind
+			problems = append(problems, fmt.Sprintf("%s %s requests %s", kind, name, strings.Join(settings, " and ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s, pass --allow-unsafe to start it anyway", strings.Join(problems, "; "))
+	}
+	if len(exposed) > 0 {
+		return fmt.Errorf("%s, pass a non-loopback --bind-address or --allow-unsafe to expose it, or use a ClusterIP service and kubectl port-forward", strings.Join(exposed, "; "))
+	}
+	return nil
+}
+
+// egressPolicy returns a network policy limiting the outbound traffic of
+// every pod of the namespace to the namespace itself and cluster DNS. It is
+// only enforced by network plugins supporting network policies.
+func egressPolicy(namespace string) *networkingv1.NetworkPolicy {
+	dnsPort := intstr.FromInt32(53)
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+
+	return &networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: egressPolicyName, Namespace: namespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+				{
+					To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Protocol: &udp, Port: &dnsPort},
+						{Protocol: &tcp, Port: &dnsPort},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,540 @@
+// Package kubernetes provides Kubernetes provider implementation for managing vulnerable target environments.
+// Each deployment is applied into its own namespace, which is deleted when the deployment is stopped.
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	_ provider.Provider     = &Kubernetes{}
+	_ provider.Discoverer   = &Kubernetes{}
+	_ provider.ScriptRunner = &Kubernetes{}
+	_ provider.Pruner       = &Kubernetes{}
+	_ provider.Remover      = &Kubernetes{}
+)
+
+// ProviderName is the name under which the Kubernetes provider is registered.
+const ProviderName = "kubernetes"
+
+// operationTimeout bounds every call made against the cluster API.
+const operationTimeout = 5 * time.Minute
+
+// Kubernetes implements the Provider interface using a Kubernetes cluster.
+type Kubernetes struct {
+	stateManager  *state.Manager
+	templatesPath string
+	clientset     kubernetes.Interface
+	restConfig    *rest.Config
+}
+
+// NewKubernetes creates a new Kubernetes provider with the given state manager,
+// reading templates from templatesPath. The cluster client is created lazily
+// from the kubeconfig or in-cluster configuration.
+func NewKubernetes(sm *state.Manager, templatesPath string) *Kubernetes {
+	return &Kubernetes{stateManager: sm, templatesPath: templatesPath}
+}
+
+// WithClientset sets the clientset used to talk to the cluster and returns the Kubernetes for chaining.
+func (k *Kubernetes) WithClientset(clientset kubernetes.Interface) *Kubernetes {
+	k.clientset = clientset
+	return k
+}
+
+// Name returns the provider name.
+func (k *Kubernetes) Name() string {
+	return ProviderName
+}
+
+// Start applies the template manifests into a namespace dedicated to the instance.
+// NodePort and LoadBalancer services listen on every node whatever the bind
+// address, so they are refused unless it is a non-loopback address or unsafe
+// templates are allowed. Node ports are allocated by the cluster, so the port
+// strategy does not apply. Egress is limited by a network policy.
+func (k *Kubernetes) Start(template *tmpl.Template, instance string, opts provider.StartOptions) error {
+	exist, _ := k.stateManager.DeploymentExist(k.Name(), instance) //nolint:errcheck
+	if exist {
+		return fmt.Errorf("already running")
+	}
+
+	if len(opts.Networks) > 0 {
+		return fmt.Errorf("provider %s can not share networks between deployments", k.Name())
+	}
+
+	values, err := template.ResolveVariables(opts.Values)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	environment := template.FlagEnvironment(opts.Flags)
+	for name, value := range values {
+		environment[name] = value
+	}
+
+	objects, err := loadTemplateManifests(*template, k.templatesPath, environment)
+	if err != nil {
+		return err
+	}
+
+	err = checkUnsafeObjects(objects, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	namespace := namespaceName(instance)
+	err = createNamespace(ctx, clientset, namespace, template.ID, instance)
+	if err != nil {
+		return err
+	}
+
+	if !opts.AllowEgress {
+		objects = append(objects, egressPolicy(namespace))
+	}
+
+	for _, object := range objects {
+		err = applyObject(ctx, clientset, namespace, object)
+		if err != nil {
+			if cleanupErr := deleteNamespace(ctx, clientset, namespace); cleanupErr != nil {
+				return fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
+			}
+			return err
+		}
+	}
+
+	err = k.recordDeployment(template.ID, instance, values, opts.Flags)
+	if err != nil {
+		// a namespace without a record would only be found again by prune
+		if cleanupErr := deleteNamespace(ctx, clientset, namespace); cleanupErr != nil {
+			return fmt.Errorf("%w (cleanup failed: %v)", err, cleanupErr)
+		}
+		return err
+	}
+
+	endpoints, err := serviceEndpoints(ctx, clientset, namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to resolve endpoints of %s", instance)
+		return nil
+	}
+
+	return k.stateManager.SetEndpoints(k.Name(), instance, endpoints)
+}
+
+// recordDeployment records the deployment of an instance with its variable
+// values and flags. A partially written record is removed again on failure.
+func (k *Kubernetes) recordDeployment(templateID, instance string, values, flags map[string]string) error {
+	err := k.stateManager.AddNewDeployment(k.Name(), templateID, instance)
+	if err != nil {
+		return err
+	}
+
+	err = k.stateManager.SetValues(k.Name(), instance, values)
+	if err == nil && len(flags) > 0 {
+		err = k.stateManager.SetFlags(k.Name(), instance, flags)
+	}
+	if err != nil {
+		if removeErr := k.stateManager.RemoveDeployment(k.Name(), instance); removeErr != nil {
+			return fmt.Errorf("%w (cleanup failed: %v)", err, removeErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Stop deletes the namespace holding the instance resources.
+func (k *Kubernetes) Stop(_ *tmpl.Template, instance string) error {
+	return k.Remove(instance)
+}
+
+// Remove deletes the namespace of an instance, which needs no template.
+func (k *Kubernetes) Remove(instance string) error {
+	exist, err := k.stateManager.DeploymentExist(k.Name(), instance)
+	if err != nil {
+		return err
+	}
+
+	if !exist {
+		return fmt.Errorf("deployment not exist")
+	}
+
+	clientset, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	err = deleteNamespace(ctx, clientset, namespaceName(instance))
+	if err != nil {
+		return err
+	}
+
+	err = k.stateManager.RemoveDeployment(k.Name(), instance)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Status reports the state and readiness of the containers in the instance
+// namespace. The containers of the template workloads without a pod are
+// reported missing.
+func (k *Kubernetes) Status(template *tmpl.Template, instance string) (provider.Status, error) {
+	clientset, err := k.client()
+	if err != nil {
+		return provider.UnknownStatus(), err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	pods, err := clientset.CoreV1().Pods(namespaceName(instance)).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return provider.UnknownStatus(), err
+	}
+
+	var services []provider.ServiceStatus
+	for _, pod := range pods.Items {
+		services = append(services, podServiceStatuses(pod)...)
+	}
+
+	expected, err := k.expectedServices(template, instance)
+	if err != nil {
+		log.Debug().Err(err).Msgf("failed to list the services of %s", instance)
+	}
+	services = append(services, missingServices(expected, services)...)
+
+	return provider.Status{
+		State:    provider.AggregateState(services),
+		Services: services,
+	}, nil
+}
+
+// Logs streams the logs of the containers running in the instance namespace.
+// Services are matched against container names.
+func (k *Kubernetes) Logs(template *tmpl.Template, instance string, opts provider.LogOptions) error {
+	exist, err := k.stateManager.DeploymentExist(k.Name(), instance)
+	if err != nil || !exist {
+		return fmt.Errorf("deployment not exist")
+	}
+
+	clientset, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if opts.Follow {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), operationTimeout)
+	}
+	defer cancel()
+
+	return streamPodLogs(ctx, clientset, namespaceName(instance), opts)
+}
+
+// Exec runs a command inside the container named after the requested service.
+// It returns the exit code of the command.
+func (k *Kubernetes) Exec(template *tmpl.Template, instance string, opts provider.ExecOptions) (int, error) {
+	exist, err := k.stateManager.DeploymentExist(k.Name(), instance)
+	if err != nil || !exist {
+		return 0, fmt.Errorf("deployment not exist")
+	}
+
+	clientset, err := k.client()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	namespace := namespaceName(instance)
+	podName, containerName, err := findExecTarget(ctx, clientset, namespace, opts.Service)
+	if err != nil {
+		return 0, err
+	}
+
+	if k.restConfig == nil {
+		return 0, fmt.Errorf("exec requires a connection to a live cluster")
+	}
+
+	return execInContainer(ctx, clientset, k.restConfig, namespace, podName, containerName, opts)
+}
+
+// Discover lists the namespaces created by vt. An instance is running when
+// any of its pods is running.
+func (k *Kubernetes) Discover() ([]provider.Instance, error) {
+	clientset, err := k.client()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: managedByLabel + "=" + managedByValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]provider.Instance, 0, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		if namespace.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+
+		name := namespace.Labels[instanceLabel]
+		if name == "" {
+			name = namespace.Labels[templateLabel]
+		}
+
+		pods, err := clientset.CoreV1().Pods(namespace.Name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		running := false
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == corev1.PodRunning {
+				running = true
+				break
+			}
+		}
+
+		instances = append(instances, provider.Instance{
+			Name:       name,
+			TemplateID: namespace.Labels[templateLabel],
+			Running:    running,
+			CreatedAt:  namespace.CreationTimestamp.Time,
+		})
+	}
+
+	return instances, nil
+}
+
+// Orphans lists the namespaces created by vt for instances refs do not
+// reference. Every resource of an instance lives in its namespace.
+func (k *Kubernetes) Orphans(refs provider.References) ([]provider.Resource, error) {
+	clientset, err := k.client()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: managedByLabel + "=" + managedByValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []provider.Resource
+	for _, namespace := range namespaces.Items {
+		if namespace.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+
+		name := namespace.Labels[instanceLabel]
+		if name == "" {
+			name = namespace.Labels[templateLabel]
+		}
+		if refs.Instances[name] {
+			continue
+		}
+
+		orphans = append(orphans, provider.Resource{
+			Kind:     provider.ResourceNamespace,
+			ID:       namespace.Name,
+			Name:     namespace.Name,
+			Instance: name,
+		})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans, nil
+}
+
+// Prune deletes the namespaces listed by Orphans.
+func (k *Kubernetes) Prune(resources []provider.Resource) error {
+	clientset, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, resource := range resources {
+		if resource.Kind != provider.ResourceNamespace {
+			errs = append(errs, fmt.Errorf("%s %s: unsupported resource kind", resource.Kind, resource.Name))
+			continue
+		}
+		if err := deleteNamespace(ctx, clientset, resource.ID); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", resource.Kind, resource.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// client returns the configured clientset, creating one from the environment on first use.
+func (k *Kubernetes) client() (kubernetes.Interface, error) {
+	if k.clientset != nil {
+		return k.clientset, nil
+	}
+
+	clientset, restConfig, err := createClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	k.clientset = clientset
+	k.restConfig = restConfig
+	return clientset, nil
+}
+
+// podServiceStatuses converts the container statuses of a pod into service statuses.
+// Readiness is reported as the health of running containers.
+func podServiceStatuses(pod corev1.Pod) []provider.ServiceStatus {
+	ports := make(map[string][]provider.PortMapping)
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			ports[container.Name] = append(ports[container.Name], provider.PortMapping{
+				ContainerPort: int(port.ContainerPort),
+				Protocol:      strings.ToLower(string(port.Protocol)),
+			})
+		}
+	}
+
+	statuses := make([]provider.ServiceStatus, 0, len(pod.Spec.Containers))
+	reported := make(map[string]bool, len(pod.Status.ContainerStatuses))
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		reported[containerStatus.Name] = true
+		service := provider.ServiceStatus{
+			Name:         containerStatus.Name,
+			Container:    pod.Name + "/" + containerStatus.Name,
+			State:        "created",
+			RestartCount: int(containerStatus.RestartCount),
+			Ports:        ports[containerStatus.Name],
+		}
+
+		switch {
+		case containerStatus.State.Running != nil:
+			service.State = "running"
+			service.StartedAt = containerStatus.State.Running.StartedAt.Time
+			service.Health = provider.HealthStarting
+			if containerStatus.Ready {
+				service.Health = provider.HealthHealthy
+			}
+		case containerStatus.State.Terminated != nil:
+			service.State = "exited"
+			service.ExitCode = int(containerStatus.State.Terminated.ExitCode)
+		case containerStatus.State.Waiting != nil && containerStatus.RestartCount > 0:
+			service.State = "restarting"
+		case pod.Status.Phase == corev1.PodPending:
+			service.State = "pending"
+		}
+
+		statuses = append(statuses, service)
+	}
+
+	// the containers of a pod waiting to be scheduled have no status yet
+	if pod.Status.Phase == corev1.PodPending {
+		for _, container := range pod.Spec.Containers {
+			if reported[container.Name] {
+				continue
+			}
+			statuses = append(statuses, provider.ServiceStatus{
+				Name:      container.Name,
+				Container: pod.Name + "/" + container.Name,
+				State:     "pending",
+				Ports:     ports[container.Name],
+			})
+		}
+	}
+
+	return statuses
+}
+
+// expectedServices returns the names of the containers run by the long-running
+// workloads of the template. Jobs are left out, their pods may be gone once
+// they completed.
+func (k *Kubernetes) expectedServices(template *tmpl.Template, instance string) ([]string, error) {
+	var values, flags map[string]string
+	if deployment, err := k.stateManager.GetDeployment(k.Name(), instance); err == nil {
+		values, flags = deployment.Values, deployment.Flags
+	}
+
+	environment := template.FlagEnvironment(flags)
+	for name, value := range template.VariableValues(values) {
+		environment[name] = value
+	}
+
+	objects, err := loadTemplateManifests(*template, k.templatesPath, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, object := range objects {
+		if _, ok := object.(*batchv1.Job); ok {
+			continue
+		}
+		if _, spec := podSpec(object); spec != nil {
+			for _, container := range spec.Containers {
+				names = append(names, container.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// missingServices returns a missing status for each expected service that has
+// no container among services.
+func missingServices(expected []string, services []provider.ServiceStatus) []provider.ServiceStatus {
+	found := make(map[string]bool, len(services))
+	for _, service := range services {
+		found[service.Name] = true
+	}
+
+	var missing []provider.ServiceStatus
+	for _, name := range expected {
+		if found[name] {
+			continue
+		}
+		found[name] = true
+		missing = append(missing, provider.ServiceStatus{Name: name, State: "missing"})
+	}
+	return missing
+}
@@ -0,0 +1,571 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/store/disk"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testIndex = `
+id: vt-k8s-lab
+
+info:
+  name: Kubernetes Lab
+  author: hhsteam
+  type: Lab
+  targets:
+    - nginx
+  tags:
+    - test
+
+providers:
+  kubernetes:
+    path: "k8s/kustomization.yaml"
+`
+
+const testKustomization = `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - app.yaml
+`
+
+const testManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: NodePort
+  selector:
+    app: web
+  ports:
+    - port: 80
+      targetPort: 80
+      nodePort: 30080
+`
+
+// setupProvider writes a kubernetes template into a temporary home directory
+// and returns a provider backed by a fake clientset.
+func setupProvider(t *testing.T) (*Kubernetes, *fake.Clientset, *tmpl.Template) {
+	t.Helper()
+
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	templateDir := filepath.Join(homeDir, "vt-templates", "labs", "vt-k8s-lab")
+	require.NoError(t, os.MkdirAll(filepath.Join(templateDir, "k8s"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "index.yaml"), []byte(testIndex), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "k8s", "kustomization.yaml"), []byte(testKustomization), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "k8s", "app.yaml"), []byte(testManifest), 0644))
+
+	template, err := tmpl.LoadTemplate(templateDir)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	sm, err := state.NewManager(
+		disk.NewConfig().WithDir(dir).WithFileName("test.db").WithBucketName("deployments"),
+		disk.NewConfig().WithDir(dir).WithFileName("attackers.db").WithBucketName("attackers"),
+	)
+	require.NoError(t, err)
+
+	clientset := fake.NewClientset()
+	return NewKubernetes(sm, filepath.Join(homeDir, "vt-templates")).WithClientset(clientset), clientset, &template
+}
+
+// exposedOptions lets the node port service of the test template start.
+var exposedOptions = provider.StartOptions{BindAddress: "0.0.0.0"}
+
+func TestKubernetesLifecycle(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+	namespace := namespaceName(template.ID)
+
+	_, err := clientset.CoreV1().Nodes().Create(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.5"}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, k.Start(template, template.ID, exposedOptions))
+	assert.EqualError(t, k.Start(template, template.ID, provider.StartOptions{}), "already running")
+
+	deployment, err := k.stateManager.GetDeployment(ProviderName, template.ID)
+	require.NoError(t, err)
+	require.Len(t, deployment.Endpoints, 1)
+	assert.Equal(t, "http://10.0.0.5:30080", deployment.Endpoints[0].URL())
+
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, template.ID, ns.Labels[templateLabel])
+
+	_, err = clientset.AppsV1().Deployments(namespace).Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.CoreV1().Services(namespace).Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.NetworkingV1().NetworkPolicies(namespace).Get(ctx, egressPolicyName, metav1.GetOptions{})
+	assert.NoError(t, err, "egress is denied unless allowed")
+
+	exist, err := k.stateManager.DeploymentExist(ProviderName, template.ID)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	require.NoError(t, k.Stop(template, template.ID))
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	assert.Error(t, err)
+
+	exist, _ = k.stateManager.DeploymentExist(ProviderName, template.ID) //nolint:errcheck
+	assert.False(t, exist)
+	assert.Error(t, k.Stop(template, template.ID))
+}
+
+func TestKubernetesStartCleansUpWhenRecordFails(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	require.NoError(t, k.stateManager.Close())
+
+	err := k.Start(template, template.ID, exposedOptions)
+	require.Error(t, err)
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, namespaces.Items, "the namespace is deleted when the deployment can not be recorded")
+}
+
+func TestKubernetesInstances(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+
+	require.NoError(t, k.Start(template, "alice", exposedOptions))
+	require.NoError(t, k.Start(template, "bob", exposedOptions))
+
+	for _, instance := range []string{"alice", "bob"} {
+		ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespaceName(instance), metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, template.ID, ns.Labels[templateLabel])
+		assert.Equal(t, instance, ns.Labels[instanceLabel])
+
+		deployment, err := k.stateManager.GetDeployment(ProviderName, instance)
+		require.NoError(t, err)
+		assert.Equal(t, template.ID, deployment.TemplateID)
+		assert.Equal(t, instance, deployment.Name)
+	}
+
+	require.NoError(t, k.Stop(template, "alice"))
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, namespaceName("bob"), metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	deployments, err := k.stateManager.ListDeployments()
+	require.NoError(t, err)
+	require.Len(t, deployments, 1)
+	assert.Equal(t, "bob", deployments[0].Name)
+}
+
+func TestKubernetesDiscoverAndReconcile(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+
+	runningPod := func(namespace string) {
+		_, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	// alice is recorded and running
+	require.NoError(t, k.Start(template, "alice", exposedOptions))
+	runningPod(namespaceName("alice"))
+
+	// bob is running without a record
+	require.NoError(t, createNamespace(ctx, clientset, namespaceName("bob"), template.ID, "bob"))
+	runningPod(namespaceName("bob"))
+
+	// carol is recorded but gone
+	require.NoError(t, k.stateManager.AddNewDeployment(ProviderName, template.ID, "carol"))
+
+	discovered, err := k.Discover()
+	require.NoError(t, err)
+	require.Len(t, discovered, 2)
+	var instances []state.Instance
+	for _, instance := range discovered {
+		assert.Equal(t, template.ID, instance.TemplateID)
+		assert.True(t, instance.Running)
+		instances = append(instances, state.Instance{Name: instance.Name, TemplateID: instance.TemplateID, Running: instance.Running, CreatedAt: instance.CreatedAt})
+	}
+
+	changes, err := k.stateManager.Reconcile(ProviderName, instances, true)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, state.ChangeAdopted, changes[0].Action)
+	assert.Equal(t, "bob", changes[0].Deployment.Name)
+	assert.Equal(t, state.ChangeRemoved, changes[1].Action)
+	assert.Equal(t, "carol", changes[1].Deployment.Name)
+
+	exist, _ := k.stateManager.DeploymentExist(ProviderName, "carol") //nolint:errcheck
+	assert.True(t, exist, "dry run must not change the records")
+
+	_, err = k.stateManager.Reconcile(ProviderName, instances, false)
+	require.NoError(t, err)
+
+	deployments, err := k.stateManager.ListDeployments()
+	require.NoError(t, err)
+	names := make([]string, 0, len(deployments))
+	for _, deployment := range deployments {
+		names = append(names, deployment.Name)
+	}
+	assert.ElementsMatch(t, []string{"alice", "bob"}, names)
+
+	changes, err = k.stateManager.Reconcile(ProviderName, instances, false)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestKubernetesOrphansAndPrune(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+
+	require.NoError(t, k.Start(template, "alice", exposedOptions))
+	require.NoError(t, createNamespace(ctx, clientset, namespaceName("bob"), template.ID, "bob"))
+
+	orphans, err := k.Orphans(provider.References{Instances: map[string]bool{"alice": true}})
+	require.NoError(t, err)
+	assert.Equal(t, []provider.Resource{
+		{Kind: provider.ResourceNamespace, ID: namespaceName("bob"), Name: namespaceName("bob"), Instance: "bob"},
+	}, orphans)
+
+	require.NoError(t, k.Prune(orphans))
+
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, namespaceName("bob"), metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, namespaceName("alice"), metav1.GetOptions{})
+	assert.NoError(t, err, "referenced namespaces are kept")
+}
+
+func TestKubernetesStatus(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+	namespace := namespaceName(template.ID)
+
+	status, err := k.Status(template, template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, provider.StateStopped, status.State)
+	assert.Equal(t, []provider.ServiceStatus{{Name: "web", State: "missing"}}, status.Services)
+
+	containerStatuses := map[string]corev1.ContainerStatus{
+		"web-1": {
+			Name:  "web",
+			Ready: true,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		},
+		"db-1": {
+			Name:         "db",
+			RestartCount: 3,
+			State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		},
+	}
+	for name, containerStatus := range containerStatuses {
+		_, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{containerStatus}},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	status, err = k.Status(template, template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, provider.StatePartial, status.State)
+	assert.Len(t, status.Services, 2)
+
+	for _, service := range status.Services {
+		switch service.Name {
+		case "web":
+			assert.Equal(t, "running", service.State)
+			assert.Equal(t, provider.HealthHealthy, service.Health)
+		case "db":
+			assert.Equal(t, "restarting", service.State)
+			assert.Equal(t, 3, service.RestartCount)
+		}
+	}
+}
+
+func TestKubernetesStatusPending(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+	namespace := namespaceName(template.ID)
+
+	// not scheduled yet, so without container statuses
+	_, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "nginx"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	status, err := k.Status(template, template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, provider.StateStarting, status.State)
+	require.Len(t, status.Services, 1)
+	assert.Equal(t, "pending", status.Services[0].State)
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, "web-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "web",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+	}}
+	_, err = clientset.CoreV1().Pods(namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	status, err = k.Status(template, template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, provider.StateStarting, status.State)
+	require.Len(t, status.Services, 1)
+	assert.Equal(t, "pending", status.Services[0].State)
+}
+
+func TestKubernetesStatusMissingService(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+	namespace := namespaceName(template.ID)
+
+	// the web deployment of the template has no pod
+	_, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sidecar-1", Namespace: namespace},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "sidecar",
+				Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	status, err := k.Status(template, template.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, provider.StatePartial, status.State)
+	assert.Contains(t, status.Services, provider.ServiceStatus{Name: "web", State: "missing"})
+}
+
+func TestLoadKustomizationRejectsUnsupportedFields(t *testing.T) {
+	dir := t.TempDir()
+	kustomizationPath := filepath.Join(dir, "kustomization.yaml")
+	err := os.WriteFile(kustomizationPath, []byte("resources: []\npatches: []\n"), 0644)
+	require.NoError(t, err)
+
+	_, err = loadManifests(kustomizationPath, 0, nil)
+	assert.ErrorContains(t, err, `field "patches" is not supported`)
+}
+
+func TestNamespaceName(t *testing.T) {
+	assert.Equal(t, "vt-vt-dvwa", namespaceName("vt-dvwa"))
+	assert.Equal(t, "vt-cve-2025-1-x", namespaceName("CVE-2025_1.x"))
+	assert.Len(t, namespaceName(strings.Repeat("a", 100)), maxNamespaceLength)
+}
+
+func TestKubernetesLogs(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+	ctx := context.Background()
+	namespace := namespaceName(template.ID)
+
+	var output bytes.Buffer
+	assert.EqualError(t, k.Logs(template, template.ID, provider.LogOptions{Output: &output}), "deployment not exist")
+
+	require.NoError(t, k.Start(template, template.ID, exposedOptions))
+	_, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: namespace},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "web"}, {Name: "sidecar"}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, k.Logs(template, template.ID, provider.LogOptions{Services: []string{"web"}, Tail: "10", Output: &output}))
+	assert.Equal(t, "web-1/web | fake logs\n", output.String())
+
+	err = k.Logs(template, template.ID, provider.LogOptions{Services: []string{"db"}, Output: &output})
+	assert.ErrorContains(t, err, "no containers found")
+
+	err = k.Logs(template, template.ID, provider.LogOptions{Tail: "last", Output: &output})
+	assert.ErrorContains(t, err, "invalid tail value")
+}
+
+func TestFindExecTarget(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset()
+	namespace := "vt-lab"
+
+	_, _, err := findExecTarget(ctx, clientset, namespace, "")
+	assert.ErrorContains(t, err, "no running containers")
+
+	_, err = clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	pod, container, err := findExecTarget(ctx, clientset, namespace, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "web-1", pod)
+	assert.Equal(t, "web", container)
+
+	_, err = clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	_, _, err = findExecTarget(ctx, clientset, namespace, "")
+	assert.EqualError(t, err, "template defines multiple services (db, web), specify one with --service")
+
+	pod, container, err = findExecTarget(ctx, clientset, namespace, "db")
+	assert.NoError(t, err)
+	assert.Equal(t, "db-1", pod)
+	assert.Equal(t, "db", container)
+
+	_, _, err = findExecTarget(ctx, clientset, namespace, "cache")
+	assert.ErrorContains(t, err, `no running container found for service "cache"`)
+}
+
+func TestDecodeManifestFileSubstitutesVariables(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "deployment.yaml")
+	err := os.WriteFile(manifestPath, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  flag: ${FLAG}
+  shell: $HOME ${UNDECLARED}
+`), 0644)
+	require.NoError(t, err)
+
+	objects, err := decodeManifestFile(manifestPath, map[string]string{"FLAG": "vt{x}"})
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	configMap, ok := objects[0].(*corev1.ConfigMap)
+	require.True(t, ok)
+	assert.Equal(t, "vt{x}", configMap.Data["flag"])
+	assert.Equal(t, "$HOME ${UNDECLARED}", configMap.Data["shell"])
+}
+
+func TestWaitScriptExit(t *testing.T) {
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vt-script-abcde", Namespace: "vt-lab"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  scriptContainerName,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 3}},
+		}}},
+	})
+
+	exitCode, err := waitScriptExit(context.Background(), clientset, "vt-lab", "vt-script-abcde")
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+
+	_, err = clientset.CoreV1().Pods("vt-lab").Create(context.Background(), &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vt-script-fghij", Namespace: "vt-lab"},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = waitScriptExit(ctx, clientset, "vt-lab", "vt-script-fghij")
+	assert.ErrorContains(t, err, "script did not finish")
+}
+
+func TestCheckUnsafeObjects(t *testing.T) {
+	privileged := true
+	objects := []runtime.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				HostNetwork: true,
+				Containers:  []corev1.Container{{Name: "web", SecurityContext: &corev1.SecurityContext{Privileged: &privileged}}},
+			}}},
+		},
+	}
+
+	err := checkUnsafeObjects(objects, provider.StartOptions{})
+	assert.EqualError(t, err, "Deployment web requests host networking and privileged mode, pass --allow-unsafe to start it anyway")
+	assert.NoError(t, checkUnsafeObjects(objects, provider.StartOptions{AllowUnsafe: true}))
+	assert.NoError(t, checkUnsafeObjects(objects[:1], provider.StartOptions{}))
+
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "agent"},
+		Spec: corev1.PodSpec{
+			HostIPC: true,
+			Containers: []corev1.Container{{Name: "agent", SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_BIND_SERVICE", "SYS_ADMIN"}},
+			}}},
+			Volumes: []corev1.Volume{{Name: "sock", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}}},
+		},
+	}
+	err = checkUnsafeObjects([]runtime.Object{pod}, provider.StartOptions{})
+	assert.EqualError(t, err, "Pod agent requests the host IPC namespace and the SYS_ADMIN capability and a host path volume of /var/run/docker.sock, pass --allow-unsafe to start it anyway")
+
+	services := []runtime.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "api"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db"}, Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+	}
+	err = checkUnsafeObjects(services, provider.StartOptions{BindAddress: "127.0.0.1"})
+	assert.EqualError(t, err, "Service web is a NodePort service listening on every node; Service api is a LoadBalancer service listening on every node, pass a non-loopback --bind-address or --allow-unsafe to expose it, or use a ClusterIP service and kubectl port-forward")
+	assert.Error(t, checkUnsafeObjects(services, provider.StartOptions{}))
+	assert.NoError(t, checkUnsafeObjects(services, provider.StartOptions{BindAddress: "0.0.0.0"}))
+	assert.NoError(t, checkUnsafeObjects(services, provider.StartOptions{AllowUnsafe: true}))
+	assert.NoError(t, checkUnsafeObjects(services[2:], provider.StartOptions{BindAddress: "127.0.0.1"}))
+}
+
+func TestKubernetesRefusesNodePortOnLoopback(t *testing.T) {
+	k, clientset, template := setupProvider(t)
+
+	err := k.Start(template, template.ID, provider.StartOptions{BindAddress: "127.0.0.1"})
+	assert.ErrorContains(t, err, "Service web is a NodePort service listening on every node")
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, namespaces.Items, "nothing is applied")
+	exist, _ := k.stateManager.DeploymentExist(ProviderName, template.ID) //nolint:errcheck
+	assert.False(t, exist)
+}
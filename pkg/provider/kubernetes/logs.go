@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// streamPodLogs writes the logs of every matching container in the namespace to opts.Output,
+// prefixing each line with the pod and container name.
+func streamPodLogs(ctx context.Context, clientset kubernetes.Interface, namespace string, opts provider.LogOptions) error {
+	logOpts, err := podLogOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		streams int
+	)
+
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			if len(opts.Services) > 0 && !slices.Contains(opts.Services, container.Name) {
+				continue
+			}
+
+			containerOpts := *logOpts
+			containerOpts.Container = container.Name
+			prefix := fmt.Sprintf("%s/%s | ", pod.Name, container.Name)
+			streams++
+
+			wg.Add(1)
+			go func(podName string) {
+				defer wg.Done()
+				err := copyPodLogs(ctx, clientset, namespace, podName, &containerOpts, prefix, output, &mu)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}(pod.Name)
+		}
+	}
+
+	wg.Wait()
+
+	if streams == 0 {
+		return fmt.Errorf("no containers found in namespace %s", namespace)
+	}
+
+	return errors.Join(errs...)
+}
+
+// copyPodLogs copies the log stream of a single container line by line to output.
+func copyPodLogs(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	namespace, podName string,
+	logOpts *corev1.PodLogOptions,
+	prefix string,
+	output io.Writer,
+	mu *sync.Mutex,
+) error {
+	stream, err := clientset.CoreV1().Pods(namespace).GetLogs(podName, logOpts).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stream logs of %s: %w", podName, err)
+	}
+	defer stream.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		mu.Lock()
+		_, err := fmt.Fprintf(output, "%s%s\n", prefix, scanner.Text())
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// podLogOptions converts provider log options into Kubernetes pod log options.
+func podLogOptions(opts provider.LogOptions) (*corev1.PodLogOptions, error) {
+	logOpts := &corev1.PodLogOptions{
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	}
+
+	if opts.Tail != "" && opts.Tail != "all" {
+		tail, err := strconv.ParseInt(opts.Tail, 10, 64)
+		if err != nil || tail < 0 {
+			return nil, fmt.Errorf("invalid tail value %q", opts.Tail)
+		}
+		logOpts.TailLines = &tail
+	}
+
+	if opts.Since != "" {
+		if duration, err := time.ParseDuration(opts.Since); err == nil {
+			seconds := int64(duration.Seconds())
+			logOpts.SinceSeconds = &seconds
+		} else if timestamp, err := time.Parse(time.RFC3339, opts.Since); err == nil {
+			since := metav1.NewTime(timestamp)
+			logOpts.SinceTime = &since
+		} else {
+			return nil, fmt.Errorf("invalid since value %q", opts.Since)
+		}
+	}
+
+	return logOpts, nil
+}
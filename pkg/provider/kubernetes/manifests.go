@@ -0,0 +1,190 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// maxKustomizationDepth limits how deep nested kustomization resources are followed.
+const maxKustomizationDepth = 10
+
+// kustomizationFileNames lists the file names recognised as kustomizations.
+var kustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml"}
+
+// supportedKustomizationFields lists the kustomization fields understood by the provider.
+// Only plain resource lists are supported; generators and patches are rejected.
+var supportedKustomizationFields = map[string]bool{
+	"apiVersion": true,
+	"kind":       true,
+	"resources":  true,
+	"namespace":  true,
+}
+
+// variablePattern matches the ${NAME} references substituted with template variables.
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// loadTemplateManifests decodes the manifests referenced by the template kubernetes provider path,
+// substituting ${NAME} with the value of the template variable NAME.
+func loadTemplateManifests(template tmpl.Template, templatesPath string, values map[string]string) ([]runtime.Object, error) {
+	manifestPath, _, err := tmpl.GetProviderPath(template.ID, template.RepositoryPath(templatesPath), ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadManifests(manifestPath, 0, values)
+}
+
+// loadManifests decodes a manifest file, or every resource of a kustomization file.
+func loadManifests(manifestPath string, depth int, values map[string]string) ([]runtime.Object, error) {
+	if depth > maxKustomizationDepth {
+		return nil, fmt.Errorf("maximum kustomization depth (%d) exceeded at %s", maxKustomizationDepth, manifestPath)
+	}
+
+	if isKustomizationFile(manifestPath) {
+		return loadKustomization(manifestPath, depth, values)
+	}
+
+	return decodeManifestFile(manifestPath, values)
+}
+
+// loadKustomization decodes every resource listed by a kustomization file.
+func loadKustomization(kustomizationPath string, depth int, values map[string]string) ([]runtime.Object, error) {
+	content, err := os.ReadFile(kustomizationPath) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+
+	var kustomization map[string]any
+	if err := yaml.Unmarshal(content, &kustomization); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", kustomizationPath, err)
+	}
+
+	for field := range kustomization {
+		if !supportedKustomizationFields[field] {
+			return nil, fmt.Errorf("kustomization %s: field %q is not supported", kustomizationPath, field)
+		}
+	}
+
+	resources, ok := kustomization["resources"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("kustomization %s: resources must be a list", kustomizationPath)
+	}
+
+	baseDir := filepath.Dir(kustomizationPath)
+	var objects []runtime.Object
+	for _, entry := range resources {
+		resource, ok := entry.(string)
+		if !ok || resource == "" {
+			return nil, fmt.Errorf("kustomization %s: invalid resource entry %v", kustomizationPath, entry)
+		}
+		if strings.Contains(resource, "://") {
+			return nil, fmt.Errorf("kustomization %s: remote resource %q is not supported", kustomizationPath, resource)
+		}
+
+		resourcePath := filepath.Join(baseDir, resource)
+		info, err := os.Stat(resourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("kustomization %s: %w", kustomizationPath, err)
+		}
+
+		if info.IsDir() {
+			nested, err := findKustomization(resourcePath)
+			if err != nil {
+				return nil, err
+			}
+			resourcePath = nested
+		}
+
+		resourceObjects, err := loadManifests(resourcePath, depth+1, values)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, resourceObjects...)
+	}
+
+	return objects, nil
+}
+
+// decodeManifestFile decodes every document of a multi-document YAML manifest.
+func decodeManifestFile(manifestPath string, values map[string]string) ([]runtime.Object, error) {
+	content, err := os.ReadFile(manifestPath) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	content = substituteVariables(content, values)
+
+	decoder := scheme.Codecs.UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+
+	var objects []runtime.Object
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", manifestPath, err)
+		}
+
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		object, _, err := decoder.Decode(document, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", manifestPath, err)
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// findKustomization returns the kustomization file inside dir.
+func findKustomization(dir string) (string, error) {
+	for _, name := range kustomizationFileNames {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no kustomization file found in %s", dir)
+}
+
+func isKustomizationFile(path string) bool {
+	base := filepath.Base(path)
+	for _, name := range kustomizationFileNames {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// substituteVariables replaces ${NAME} with the value of NAME. References to
+// names that are not template variables are left untouched.
+func substituteVariables(content []byte, values map[string]string) []byte {
+	if len(values) == 0 {
+		return content
+	}
+	return variablePattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		value, ok := values[string(variablePattern.FindSubmatch(match)[1])]
+		if !ok {
+			return match
+		}
+		return []byte(value)
+	})
+}
@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+)
+
+// scriptContainerName is the name of the container running a script.
+const scriptContainerName = "script"
+
+// RunScript runs a script in a throwaway pod in the namespace of the instance,
+// where services are reachable by their name.
+func (k *Kubernetes) RunScript(template *tmpl.Template, instance string, opts provider.ScriptOptions) (int, error) {
+	exist, err := k.stateManager.DeploymentExist(k.Name(), instance)
+	if err != nil {
+		return 0, err
+	}
+	if !exist {
+		return 0, fmt.Errorf("deployment not exist")
+	}
+
+	clientset, err := k.client()
+	if err != nil {
+		return 0, err
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = operationTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	env := make([]corev1.EnvVar, 0, len(opts.Env))
+	for _, variable := range opts.Env {
+		name, value, _ := strings.Cut(variable, "=")
+		env = append(env, corev1.EnvVar{Name: name, Value: value})
+	}
+
+	namespace := namespaceName(instance)
+	pods := clientset.CoreV1().Pods(namespace)
+	pod, err := pods.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "vt-script-" + utilrand.String(5),
+			Labels: map[string]string{instanceLabel: instance, templateLabel: template.ID},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:    scriptContainerName,
+				Image:   opts.Image,
+				Command: []string{"/bin/sh", "-c", opts.Script},
+				Env:     env,
+			}},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		// the run context may have expired, removal must still happen
+		_ = pods.Delete(context.Background(), pod.Name, metav1.DeleteOptions{}) //nolint:errcheck
+	}()
+
+	exitCode, err := waitScriptExit(ctx, clientset, namespace, pod.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Output != nil {
+		stream, err := pods.GetLogs(pod.Name, &corev1.PodLogOptions{Container: scriptContainerName}).Stream(ctx)
+		if err != nil {
+			return exitCode, err
+		}
+		defer stream.Close() //nolint:errcheck
+		if _, err := io.Copy(opts.Output, stream); err != nil {
+			return exitCode, err
+		}
+	}
+
+	return exitCode, nil
+}
+
+// waitScriptExit polls the script pod until its container terminated and
+// returns the exit code.
+func waitScriptExit(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (int, error) {
+	for {
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == scriptContainerName && status.State.Terminated != nil {
+				return int(status.State.Terminated.ExitCode), nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("script did not finish: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	namespacePrefix    = "vt-"
+	maxNamespaceLength = 63
+
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "vt"
+	templateLabel  = "vt.template"
+	instanceLabel  = "vt.instance"
+)
+
+var invalidNamespaceChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func createClientset() (kubernetes.Interface, *rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load kubernetes configuration: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return clientset, restConfig, nil
+}
+
+// namespaceName returns the DNS-1123 compliant namespace used for a template instance.
+func namespaceName(instance string) string {
+	name := namespacePrefix + invalidNamespaceChars.ReplaceAllString(strings.ToLower(instance), "-")
+	if len(name) > maxNamespaceLength {
+		name = name[:maxNamespaceLength]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+func createNamespace(ctx context.Context, clientset kubernetes.Interface, namespace, templateID, instance string) error {
+	_, err := clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+			Labels: map[string]string{
+				managedByLabel: managedByValue,
+				templateLabel:  templateID,
+				instanceLabel:  instance,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("namespace %s already exists", namespace)
+	}
+	return err
+}
+
+func deleteNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	propagation := metav1.DeletePropagationForeground
+	err := clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// applyObject creates a decoded manifest object inside the given namespace.
+func applyObject(ctx context.Context, clientset kubernetes.Interface, namespace string, object runtime.Object) error {
+	var err error
+	opts := metav1.CreateOptions{}
+
+	switch obj := object.(type) {
+	case *corev1.ConfigMap:
+		obj.Namespace = namespace
+		_, err = clientset.CoreV1().ConfigMaps(namespace).Create(ctx, obj, opts)
+	case *corev1.Secret:
+		obj.Namespace = namespace
+		_, err = clientset.CoreV1().Secrets(namespace).Create(ctx, obj, opts)
+	case *corev1.Service:
+		obj.Namespace = namespace
+		_, err = clientset.CoreV1().Services(namespace).Create(ctx, obj, opts)
+	case *corev1.ServiceAccount:
+		obj.Namespace = namespace
+		_, err = clientset.CoreV1().ServiceAccounts(namespace).Create(ctx, obj, opts)
+	case *corev1.PersistentVolumeClaim:
+		obj.Namespace = namespace
+		_, err = clientset.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, obj, opts)
+	case *corev1.Pod:
+		obj.Namespace = namespace
+		_, err = clientset.CoreV1().Pods(namespace).Create(ctx, obj, opts)
+	case *appsv1.Deployment:
+		obj.Namespace = namespace
+		_, err = clientset.AppsV1().Deployments(namespace).Create(ctx, obj, opts)
+	case *appsv1.StatefulSet:
+		obj.Namespace = namespace
+		_, err = clientset.AppsV1().StatefulSets(namespace).Create(ctx, obj, opts)
+	case *appsv1.DaemonSet:
+		obj.Namespace = namespace
+		_, err = clientset.AppsV1().DaemonSets(namespace).Create(ctx, obj, opts)
+	case *batchv1.Job:
+		obj.Namespace = namespace
+		_, err = clientset.BatchV1().Jobs(namespace).Create(ctx, obj, opts)
+	case *networkingv1.Ingress:
+		obj.Namespace = namespace
+		_, err = clientset.NetworkingV1().Ingresses(namespace).Create(ctx, obj, opts)
+	case *networkingv1.NetworkPolicy:
+		obj.Namespace = namespace
+		_, err = clientset.NetworkingV1().NetworkPolicies(namespace).Create(ctx, obj, opts)
+	default:
+		return fmt.Errorf("unsupported manifest kind %q", object.GetObjectKind().GroupVersionKind().Kind)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", object.GetObjectKind().GroupVersionKind().Kind, err)
+	}
+
+	return nil
+}
+
+// serviceEndpoints returns the endpoints of the NodePort and LoadBalancer services in the namespace.
+// Node ports are reported on the first node address, preferring external addresses.
+func serviceEndpoints(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]state.Endpoint, error) {
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var nodeAddress string
+	var endpoints []state.Endpoint
+	for _, service := range services.Items {
+		if service.Spec.Type != corev1.ServiceTypeNodePort && service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+
+		for _, port := range service.Spec.Ports {
+			protocol := strings.ToLower(string(port.Protocol))
+			if protocol == "" {
+				protocol = "tcp"
+			}
+
+			for _, ingress := range service.Status.LoadBalancer.Ingress {
+				host := ingress.IP
+				if host == "" {
+					host = ingress.Hostname
+				}
+				endpoints = append(endpoints, state.Endpoint{
+					Service:       service.Name,
+					HostIP:        host,
+					HostPort:      int(port.Port),
+					ContainerPort: port.TargetPort.IntValue(),
+					Protocol:      protocol,
+				})
+			}
+
+			if port.NodePort == 0 {
+				continue
+			}
+
+			if nodeAddress == "" {
+				nodeAddress, err = firstNodeAddress(ctx, clientset)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			endpoints = append(endpoints, state.Endpoint{
+				Service:       service.Name,
+				HostIP:        nodeAddress,
+				HostPort:      int(port.NodePort),
+				ContainerPort: port.TargetPort.IntValue(),
+				Protocol:      protocol,
+			})
+		}
+	}
+
+	return endpoints, nil
+}
+
+// firstNodeAddress returns the external address of the first node, falling back to its internal address.
+func firstNodeAddress(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return "", err
+	}
+	if len(nodes.Items) == 0 {
+		return "", fmt.Errorf("no nodes found in the cluster")
+	}
+
+	var internal string
+	for _, address := range nodes.Items[0].Status.Addresses {
+		switch address.Type {
+		case corev1.NodeExternalIP:
+			return address.Address, nil
+		case corev1.NodeInternalIP:
+			internal = address.Address
+		}
+	}
+
+	if internal == "" {
+		return "", fmt.Errorf("node %s has no IP address", nodes.Items[0].Name)
+	}
+	return internal, nil
+}
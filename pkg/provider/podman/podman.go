@@ -0,0 +1,219 @@
+// Package podman provides Podman provider implementation for managing vulnerable target environments.
+// It drives the compose engine through the Docker-compatible API exposed by the Podman socket.
+package podman
+
+import (
+	"io"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/provider/dockercompose"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+var (
+	_ provider.Provider      = &Podman{}
+	_ provider.Discoverer    = &Podman{}
+	_ provider.ImageArchiver = &Podman{}
+	_ provider.ScriptRunner  = &Podman{}
+	_ provider.Attacher      = &Podman{}
+	_ provider.NetworkSharer = &Podman{}
+	_ provider.Pruner        = &Podman{}
+	_ provider.Remover       = &Podman{}
+)
+
+// ProviderName is the name under which the Podman provider is registered.
+const ProviderName = "podman"
+
+// Podman implements the Provider interface using the Podman socket.
+type Podman struct {
+	stateManager  *state.Manager
+	templatesPath string
+	socketPath    string
+}
+
+// NewPodman creates a new Podman provider with the given state manager, reading
+// templates from templatesPath. The socket is discovered lazily so the provider
+// can be registered on hosts without Podman.
+func NewPodman(sm *state.Manager, templatesPath string) *Podman {
+	return &Podman{stateManager: sm, templatesPath: templatesPath}
+}
+
+// WithSocketPath sets an explicit Podman socket path and returns the Podman for chaining.
+func (p *Podman) WithSocketPath(socketPath string) *Podman {
+	p.socketPath = socketPath
+	return p
+}
+
+// Name returns the provider name.
+func (p *Podman) Name() string {
+	return ProviderName
+}
+
+// Start launches the vulnerable target environment using Podman.
+func (p *Podman) Start(template *tmpl.Template, instance string, opts provider.StartOptions) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.Start(template, instance, opts)
+}
+
+// Stop shuts down the vulnerable target environment using Podman.
+func (p *Podman) Stop(template *tmpl.Template, instance string) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.Stop(template, instance)
+}
+
+// Remove shuts down an instance whose template is not loaded anymore using Podman.
+func (p *Podman) Remove(instance string) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.Remove(instance)
+}
+
+// Status returns status the vulnerable target environment using Podman.
+func (p *Podman) Status(template *tmpl.Template, instance string) (provider.Status, error) {
+	engine, err := p.engine()
+	if err != nil {
+		return provider.UnknownStatus(), err
+	}
+	return engine.Status(template, instance)
+}
+
+// Logs streams the logs of the vulnerable target environment using Podman.
+func (p *Podman) Logs(template *tmpl.Template, instance string, opts provider.LogOptions) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.Logs(template, instance, opts)
+}
+
+// Exec runs a command inside a service of the vulnerable target environment using Podman.
+func (p *Podman) Exec(template *tmpl.Template, instance string, opts provider.ExecOptions) (int, error) {
+	engine, err := p.engine()
+	if err != nil {
+		return 0, err
+	}
+	return engine.Exec(template, instance, opts)
+}
+
+// Discover lists the vt-owned compose projects running on Podman.
+func (p *Podman) Discover() ([]provider.Instance, error) {
+	engine, err := p.engine()
+	if err != nil {
+		return nil, err
+	}
+	return engine.Discover()
+}
+
+// Images returns the container images the template runs on Podman.
+func (p *Podman) Images(template *tmpl.Template) ([]string, error) {
+	// reading the compose project needs no socket
+	return dockercompose.NewDockerCompose(p.stateManager, p.templatesPath).
+		WithName(ProviderName).
+		Images(template)
+}
+
+// SaveImages writes the images to w in the docker save format using Podman.
+func (p *Podman) SaveImages(images []string, w io.Writer) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.SaveImages(images, w)
+}
+
+// LoadImages loads an archive in the docker save format into Podman.
+func (p *Podman) LoadImages(r io.Reader) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.LoadImages(r)
+}
+
+// RunScript runs a script in a throwaway container next to the instance using Podman.
+func (p *Podman) RunScript(template *tmpl.Template, instance string, opts provider.ScriptOptions) (int, error) {
+	engine, err := p.engine()
+	if err != nil {
+		return 0, err
+	}
+	return engine.RunScript(template, instance, opts)
+}
+
+// StartAttacher starts the attacker workstation of the instance using Podman.
+func (p *Podman) StartAttacher(template *tmpl.Template, instance string, opts provider.AttackerOptions) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.StartAttacher(template, instance, opts)
+}
+
+// StopAttacher removes the attacker workstation of the instance using Podman.
+func (p *Podman) StopAttacher(instance string) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.StopAttacher(instance)
+}
+
+// ExecAttacher runs a command in the attacker workstation of the instance using Podman.
+func (p *Podman) ExecAttacher(instance string, opts provider.ExecOptions) (int, error) {
+	engine, err := p.engine()
+	if err != nil {
+		return 0, err
+	}
+	return engine.ExecAttacher(instance, opts)
+}
+
+// RemoveNetworks removes shared networks using Podman.
+func (p *Podman) RemoveNetworks(names []string) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.RemoveNetworks(names)
+}
+
+// Orphans lists the vt resources left on Podman that refs do not reference.
+func (p *Podman) Orphans(refs provider.References) ([]provider.Resource, error) {
+	engine, err := p.engine()
+	if err != nil {
+		return nil, err
+	}
+	return engine.Orphans(refs)
+}
+
+// Prune removes resources listed by Orphans using Podman.
+func (p *Podman) Prune(resources []provider.Resource) error {
+	engine, err := p.engine()
+	if err != nil {
+		return err
+	}
+	return engine.Prune(resources)
+}
+
+// engine returns a compose engine bound to a reachable Podman socket.
+func (p *Podman) engine() (*dockercompose.DockerCompose, error) {
+	host, err := resolveHost(p.socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ping(host); err != nil {
+		return nil, err
+	}
+
+	return dockercompose.NewDockerCompose(p.stateManager, p.templatesPath).
+		WithName(ProviderName).
+		WithHost(host), nil
+}
@@ -0,0 +1,97 @@
+package podman
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startStandInSocket serves the given handler on a unix socket that stands in for the Podman service.
+func startStandInSocket(t *testing.T, dir string, handler http.Handler) string {
+	t.Helper()
+
+	socketPath := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: handler} // #nosec G112
+	go server.Serve(listener)                //nolint:errcheck
+
+	t.Cleanup(func() {
+		err := server.Close()
+		require.NoError(t, err)
+	})
+
+	return socketPath
+}
+
+// shortTempDir returns a temp directory short enough for unix socket paths.
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "vt")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := os.RemoveAll(dir)
+		require.NoError(t, err)
+	})
+	return dir
+}
+
+func TestResolveHost(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	host, err := resolveHost("/tmp/custom.sock")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix:///tmp/custom.sock", host)
+
+	t.Setenv("CONTAINER_HOST", "unix:///tmp/env.sock")
+	host, err = resolveHost("")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix:///tmp/env.sock", host)
+}
+
+func TestResolveHostFromRuntimeDir(t *testing.T) {
+	runtimeDir := shortTempDir(t)
+	socketDir := filepath.Join(runtimeDir, "podman")
+	require.NoError(t, os.MkdirAll(socketDir, 0750))
+	socketPath := startStandInSocket(t, socketDir, http.NotFoundHandler())
+
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	host, err := resolveHost("")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix://"+socketPath, host)
+}
+
+func TestPing(t *testing.T) {
+	dir := shortTempDir(t)
+
+	okSocket := startStandInSocket(t, dir, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	assert.NoError(t, ping("unix://"+okSocket))
+
+	failingDir := filepath.Join(dir, "failing")
+	require.NoError(t, os.MkdirAll(failingDir, 0750))
+	failingSocket := startStandInSocket(t, failingDir, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	assert.ErrorContains(t, ping("unix://"+failingSocket), "status 500")
+
+	assert.ErrorContains(t, ping("unix://"+filepath.Join(dir, "missing.sock")), "not reachable")
+
+	// non-unix endpoints are validated by the engine client
+	assert.NoError(t, ping("tcp://127.0.0.1:8080"))
+}
@@ -0,0 +1,83 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	unixScheme  = "unix://"
+	pingTimeout = 5 * time.Second
+)
+
+// resolveHost returns the engine endpoint of the Podman socket.
+// An explicit socket path wins, then the CONTAINER_HOST environment variable,
+// then the rootless and rootful default socket locations.
+func resolveHost(socketPath string) (string, error) {
+	if socketPath != "" {
+		return unixScheme + socketPath, nil
+	}
+
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host, nil
+	}
+
+	for _, candidate := range socketCandidates() {
+		info, err := os.Stat(candidate)
+		if err == nil && info.Mode()&os.ModeSocket != 0 {
+			return unixScheme + candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("podman socket not found; enable it with 'systemctl --user enable --now podman.socket' or set CONTAINER_HOST")
+}
+
+// socketCandidates lists the default Podman socket locations in lookup order.
+func socketCandidates() []string {
+	var candidates []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	}
+	candidates = append(candidates,
+		filepath.Join("/run/user", fmt.Sprint(os.Getuid()), "podman", "podman.sock"),
+		"/run/podman/podman.sock",
+	)
+	return candidates
+}
+
+// ping checks that the Podman service answers on a unix socket endpoint.
+// Non-unix endpoints are left to the engine client to validate.
+func ping(host string) error {
+	socketPath, ok := strings.CutPrefix(host, unixScheme)
+	if !ok {
+		return nil
+	}
+
+	client := &http.Client{
+		Timeout: pingTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://podman/_ping")
+	if err != nil {
+		return fmt.Errorf("podman socket %s is not reachable: %w", socketPath, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("podman socket %s ping failed with status %d", socketPath, resp.StatusCode)
+	}
+
+	return nil
+}
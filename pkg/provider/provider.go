@@ -2,13 +2,280 @@
 package provider
 
 import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
 	tmpl "github.com/happyhackingspace/vt/pkg/template"
 )
 
 // Provider defines the interface for managing vulnerable target environments.
+// A template can be deployed several times, each deployment being addressed
+// by its instance name.
 type Provider interface {
 	Name() string
-	Start(template *tmpl.Template) error
-	Stop(template *tmpl.Template) error
-	Status(template *tmpl.Template) (string, error)
+	Start(template *tmpl.Template, instance string, opts StartOptions) error
+	Stop(template *tmpl.Template, instance string) error
+	Status(template *tmpl.Template, instance string) (Status, error)
+	Logs(template *tmpl.Template, instance string, opts LogOptions) error
+	Exec(template *tmpl.Template, instance string, opts ExecOptions) (int, error)
+}
+
+// Discoverer is implemented by providers that can list the instances vt
+// deployed on them, including the ones missing from the deployment state.
+type Discoverer interface {
+	Discover() ([]Instance, error)
+}
+
+// ImageArchiver is implemented by providers able to export the container images
+// of templates and import them on a host without registry access.
+type ImageArchiver interface {
+	// Images returns the container images the template runs.
+	Images(template *tmpl.Template) ([]string, error)
+	// SaveImages writes the images to w as a single archive, pulling missing ones first.
+	SaveImages(images []string, w io.Writer) error
+	// LoadImages loads the images of an archive written by SaveImages.
+	LoadImages(r io.Reader) error
+}
+
+// ScriptRunner is implemented by providers able to run a script in a sidecar
+// container that reaches the services of a deployment by their name.
+type ScriptRunner interface {
+	// RunScript runs the script until it exits and returns its exit code.
+	RunScript(template *tmpl.Template, instance string, opts ScriptOptions) (int, error)
+}
+
+// NetworkSharer is implemented by providers able to connect deployments
+// through the shared networks of StartOptions.
+type NetworkSharer interface {
+	// RemoveNetworks removes shared networks, which must not be used anymore.
+	// Networks that do not exist are ignored.
+	RemoveNetworks(names []string) error
+}
+
+// Attacher is implemented by providers able to run an attacker workstation, a
+// long running toolbox container on the networks of a deployment from which
+// its services are attacked by their name. The workstation is recorded in the
+// deployment state.
+type Attacher interface {
+	// StartAttacher starts the attacker workstation of a running instance.
+	StartAttacher(template *tmpl.Template, instance string, opts AttackerOptions) error
+	// StopAttacher removes the attacker workstation of an instance.
+	StopAttacher(instance string) error
+	// ExecAttacher runs a command in the attacker workstation of an instance
+	// and returns its exit code. The service of opts is ignored.
+	ExecAttacher(instance string, opts ExecOptions) (int, error)
+}
+
+// Pruner is implemented by providers able to find the resources vt created
+// that no deployment record references anymore, and remove them.
+type Pruner interface {
+	// Orphans lists the vt resources not referenced by refs.
+	Orphans(refs References) ([]Resource, error)
+	// Prune removes resources listed by Orphans. Resources already gone are ignored.
+	Prune(resources []Resource) error
+}
+
+// Remover is implemented by providers able to tear an instance down from its
+// name alone, for deployments whose template is not loaded anymore.
+type Remover interface {
+	// Remove stops the instance and removes its deployment record.
+	Remove(instance string) error
+}
+
+// References are the instances and shared networks recorded for a provider.
+type References struct {
+	Instances map[string]bool
+	Networks  map[string]bool
+}
+
+// Kinds of the resources created by vt.
+const (
+	ResourceContainer = "container"
+	ResourceNetwork   = "network"
+	ResourceVolume    = "volume"
+	ResourceImage     = "image"
+	ResourceNamespace = "namespace"
+)
+
+// Resource is an object vt created on a provider.
+type Resource struct {
+	Kind string
+	// ID identifies the resource on the provider.
+	ID   string
+	Name string
+	// Instance is the instance the resource was created for. It is empty for
+	// shared networks.
+	Instance string
+}
+
+// DefaultAttackerImage is the attacker toolbox image with curl, sqlmap, nmap
+// and netcat. It is not published to a registry: providers build it from the
+// Dockerfile shipped with vt the first time a workstation uses it.
+const DefaultAttackerImage = "localhost/vt-attacker:latest"
+
+// AttackerOptions configures an attacker workstation.
+type AttackerOptions struct {
+	// Image is the toolbox image of the workstation.
+	Image string
+}
+
+// ScriptOptions configures a script run in a sidecar container.
+type ScriptOptions struct {
+	// Image is the image of the sidecar container, which must provide sh.
+	Image string
+	// Script is the shell script to run.
+	Script string
+	// Env sets environment variables in KEY=VALUE form.
+	Env []string
+	// Timeout stops the script when it runs for longer. Zero means no limit.
+	Timeout time.Duration
+	// Output receives the combined standard output and error of the script.
+	Output io.Writer
+}
+
+// Instance describes a vt-owned deployment found on a provider.
+type Instance struct {
+	// Name is the instance name.
+	Name string
+	// TemplateID is the template the instance was started from.
+	TemplateID string
+	// Running reports whether any of the instance containers is running.
+	Running bool
+	// CreatedAt is the creation time of the oldest resource of the instance.
+	CreatedAt time.Time
+}
+
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidateInstanceName checks that name can be used as an instance name, which
+// ends up in compose project names and Kubernetes namespaces. '_' is refused as
+// namespaces can not hold it, and mapping it to '-' would make names collide.
+func ValidateInstanceName(name string) error {
+	if len(name) > maxInstanceNameLength {
+		return fmt.Errorf("instance name %q is longer than %d characters", name, maxInstanceNameLength)
+	}
+	if !instanceNamePattern.MatchString(name) {
+		return fmt.Errorf("instance name %q must contain only lowercase letters, digits and '-', and start with a letter or digit", name)
+	}
+	return nil
+}
+
+// DefaultInstanceName derives a valid instance name from a template or scenario
+// ID, which may hold uppercase letters, '.' and '_': letters are lowercased,
+// other invalid characters become '-' and the name is cut to the maximum length.
+func DefaultInstanceName(id string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, id)
+	name = strings.TrimLeft(name, "-")
+	return strings.TrimRight(name[:min(len(name), maxInstanceNameLength)], "-")
+}
+
+// dangerousCapabilities are the Linux capabilities that let a container take
+// over or spy on its host.
+var dangerousCapabilities = map[string]bool{
+	"ALL":             true,
+	"SYS_ADMIN":       true,
+	"SYS_MODULE":      true,
+	"SYS_RAWIO":       true,
+	"SYS_PTRACE":      true,
+	"SYS_BOOT":        true,
+	"SYS_TIME":        true,
+	"NET_ADMIN":       true,
+	"DAC_READ_SEARCH": true,
+	"MAC_ADMIN":       true,
+	"MAC_OVERRIDE":    true,
+	"BPF":             true,
+	"PERFMON":         true,
+}
+
+// DangerousCapability reports whether adding the capability, with or without
+// its CAP_ prefix, lets a container take over or spy on its host.
+func DangerousCapability(capability string) bool {
+	return dangerousCapabilities[strings.TrimPrefix(strings.ToUpper(capability), "CAP_")]
+}
+
+// maxInstanceNameLength keeps derived names such as "vt-<instance>" namespaces within DNS label limits.
+const maxInstanceNameLength = 60
+
+// Host port conflict strategies used when a published port is already in use.
+const (
+	// PortStrategyOffset publishes the port on the next free port above the requested one.
+	PortStrategyOffset = "offset"
+	// PortStrategyRandom publishes the port on a random free port chosen by the kernel.
+	PortStrategyRandom = "random"
+	// PortStrategyFail refuses to start the deployment.
+	PortStrategyFail = "fail"
+)
+
+// PortStrategies lists the supported host port conflict strategies.
+var PortStrategies = []string{PortStrategyOffset, PortStrategyRandom, PortStrategyFail}
+
+// StartOptions configures how a deployment is started.
+type StartOptions struct {
+	// PortStrategy decides what happens when a published host port is already
+	// in use. Empty means PortStrategyOffset.
+	PortStrategy string
+	// Values sets template variables, the others keep their default.
+	Values map[string]string
+	// Flags are the CTF flags of the deployment keyed by flag name. Env flags
+	// are injected by the provider, which records all of them.
+	Flags map[string]string
+	// BindAddress is the host address every published port is bound to.
+	// Empty keeps the addresses the template declares.
+	BindAddress string
+	// AllowEgress keeps outbound network access of the services, which are
+	// cut off the internet otherwise.
+	AllowEgress bool
+	// AllowUnsafe starts templates reaching into the host: host networking,
+	// the host PID, IPC or user namespace, privileged containers, dangerous
+	// capabilities, devices or host paths.
+	AllowUnsafe bool
+	// Networks are shared networks every service joins, created when missing.
+	// Scenarios connect their deployments through them. Only providers
+	// implementing NetworkSharer support them.
+	Networks []string
+}
+
+// LogOptions configures how the logs of a deployment are streamed.
+type LogOptions struct {
+	// Services restricts the output to the given services. Empty means all services.
+	Services []string
+	// Follow keeps streaming new log lines until the process is interrupted.
+	Follow bool
+	// Tail is the number of lines to show from the end of the logs, or "all".
+	Tail string
+	// Since shows logs since a timestamp (RFC3339) or relative duration (e.g. 10m).
+	Since string
+	// Timestamps prefixes every line with its timestamp.
+	Timestamps bool
+	// Output receives the log lines.
+	Output io.Writer
+}
+
+// ExecOptions configures a command executed inside a running deployment.
+type ExecOptions struct {
+	// Service is the service to run the command in. It may be empty when
+	// the deployment has a single service.
+	Service string
+	// Command is the command and its arguments.
+	Command []string
+	// Tty allocates a pseudo-TTY for the command.
+	Tty bool
+	// Interactive keeps STDIN attached to the command.
+	Interactive bool
+	// User runs the command as the given user.
+	User string
+	// Env sets additional environment variables in KEY=VALUE form.
+	Env []string
 }
@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateInstanceName(t *testing.T) {
+	for _, name := range []string{"vt-dvwa", "alice", "team-1", "0day"} {
+		assert.NoError(t, ValidateInstanceName(name), name)
+	}
+
+	for _, name := range []string{"", "Alice", "-alice", "alice.bob", "a b", "team_1", strings.Repeat("a", 61)} {
+		assert.Error(t, ValidateInstanceName(name), name)
+	}
+}
+
+func TestDefaultInstanceName(t *testing.T) {
+	for id, name := range map[string]string{
+		"vt-dvwa":                      "vt-dvwa",
+		"CVE-2021-44228":               "cve-2021-44228",
+		"vt.log4shell_lab":             "vt-log4shell-lab",
+		"_hidden":                      "hidden",
+		strings.Repeat("a", 70):        strings.Repeat("a", 60),
+		strings.Repeat("a", 59) + ".b": strings.Repeat("a", 59),
+	} {
+		assert.Equal(t, name, DefaultInstanceName(id), id)
+		assert.NoError(t, ValidateInstanceName(DefaultInstanceName(id)), id)
+	}
+}
+
+func TestDangerousCapability(t *testing.T) {
+	for _, capability := range []string{"SYS_ADMIN", "cap_sys_admin", "ALL", "NET_ADMIN"} {
+		assert.True(t, DangerousCapability(capability), capability)
+	}
+	for _, capability := range []string{"NET_BIND_SERVICE", "CHOWN", "CAP_NET_RAW"} {
+		assert.False(t, DangerousCapability(capability), capability)
+	}
+}
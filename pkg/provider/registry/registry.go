@@ -5,13 +5,18 @@ import (
 	"github.com/happyhackingspace/vt/internal/state"
 	"github.com/happyhackingspace/vt/pkg/provider"
 	"github.com/happyhackingspace/vt/pkg/provider/dockercompose"
+	"github.com/happyhackingspace/vt/pkg/provider/kubernetes"
+	"github.com/happyhackingspace/vt/pkg/provider/podman"
 )
 
 // NewProviders creates and returns a map of all available providers.
-// Each provider is initialized with the given state manager.
-func NewProviders(sm *state.Manager) map[string]provider.Provider {
+// Each provider is initialized with the given state manager and reads
+// templates from templatesPath.
+func NewProviders(sm *state.Manager, templatesPath string) map[string]provider.Provider {
 	return map[string]provider.Provider{
-		"docker-compose": dockercompose.NewDockerCompose(sm),
+		dockercompose.ProviderName: dockercompose.NewDockerCompose(sm, templatesPath),
+		podman.ProviderName:        podman.NewPodman(sm, templatesPath),
+		kubernetes.ProviderName:    kubernetes.NewKubernetes(sm, templatesPath),
 	}
 }
 
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Deployment states reported by providers.
+const (
+	// StateRunning means every service is running and healthy.
+	StateRunning = "running"
+	// StateStarting means every service is running or being created, but some
+	// are still pending or their health checks have not passed yet.
+	StateStarting = "starting"
+	// StateUnhealthy means every service is running but some health checks are failing.
+	StateUnhealthy = "unhealthy"
+	// StatePartial means only some of the services are running, the others
+	// being stopped, crashed or missing.
+	StatePartial = "partial"
+	// StateCrashed means no service is running and some exited with an error or keep restarting.
+	StateCrashed = "crashed"
+	// StateStopped means no service is running.
+	StateStopped = "stopped"
+	// StateUnknown means the state could not be determined.
+	StateUnknown = "unknown"
+)
+
+// Service health check results.
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+	HealthStarting  = "starting"
+)
+
+// Status describes the state of a deployment and of each of its services.
+type Status struct {
+	State    string          `json:"state" yaml:"state"`
+	Services []ServiceStatus `json:"services" yaml:"services"`
+}
+
+// ServiceStatus describes a single container of a deployment.
+type ServiceStatus struct {
+	// Name is the service name declared by the template.
+	Name string `json:"name" yaml:"name"`
+	// Container is the runtime name of the container.
+	Container string `json:"container" yaml:"container"`
+	// State is the container state (running, exited, restarting, created, ...).
+	// It is pending while the container is being scheduled or pulled, and
+	// missing when the service has no container at all.
+	State string `json:"state" yaml:"state"`
+	// Health is the health check result, empty when the service has no health check.
+	Health string `json:"health" yaml:"health"`
+	// ExitCode is the exit code of the last run, meaningful for exited containers.
+	ExitCode int `json:"exit_code" yaml:"exit_code"`
+	// RestartCount is the number of times the container was restarted.
+	RestartCount int `json:"restart_count" yaml:"restart_count"`
+	// Ports lists the published ports of the container.
+	Ports []PortMapping `json:"ports" yaml:"ports"`
+	// StartedAt is the time the container was last started.
+	StartedAt time.Time `json:"started_at" yaml:"started_at"`
+}
+
+// PortMapping describes a container port published on the host.
+type PortMapping struct {
+	HostIP        string `json:"host_ip" yaml:"host_ip"`
+	HostPort      int    `json:"host_port" yaml:"host_port"`
+	ContainerPort int    `json:"container_port" yaml:"container_port"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+}
+
+// String returns the port mapping in host:port->port/protocol form.
+func (p PortMapping) String() string {
+	if p.HostPort == 0 {
+		return fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol)
+	}
+	return fmt.Sprintf("%s:%d->%d/%s", p.HostIP, p.HostPort, p.ContainerPort, p.Protocol)
+}
+
+// Uptime returns how long the service has been running, or zero when it is not running.
+func (s ServiceStatus) Uptime() time.Duration {
+	if s.State != "running" || s.StartedAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.StartedAt).Truncate(time.Second)
+}
+
+// Running returns the number of running services.
+func (s Status) Running() int {
+	running := 0
+	for _, service := range s.Services {
+		if service.State == "running" {
+			running++
+		}
+	}
+	return running
+}
+
+// Summary returns a short description of how many services are running.
+func (s Status) Summary() string {
+	return fmt.Sprintf("%d/%d running", s.Running(), len(s.Services))
+}
+
+// UnknownStatus returns the status reported when it can not be determined.
+func UnknownStatus() Status {
+	return Status{State: StateUnknown}
+}
+
+// AggregateState derives the overall deployment state from the state of its services.
+// Services that exited successfully are treated as completed one-shot jobs,
+// and pending services as starting ones.
+func AggregateState(services []ServiceStatus) string {
+	if len(services) == 0 {
+		return StateStopped
+	}
+
+	var running, pending, starting, unhealthy, crashed, completed int
+	for _, service := range services {
+		switch strings.ToLower(service.State) {
+		case "pending":
+			pending++
+		case "running":
+			running++
+			switch service.Health {
+			case HealthStarting:
+				starting++
+			case HealthUnhealthy:
+				unhealthy++
+			}
+		case "restarting", "dead":
+			crashed++
+		case "exited":
+			if service.ExitCode == 0 {
+				completed++
+			} else {
+				crashed++
+			}
+		}
+	}
+
+	active := len(services) - completed
+	switch {
+	case running == 0 && crashed > 0:
+		return StateCrashed
+	case running == 0 && pending == 0:
+		return StateStopped
+	case running+pending < active:
+		return StatePartial
+	case unhealthy > 0:
+		return StateUnhealthy
+	case starting > 0 || pending > 0:
+		return StateStarting
+	default:
+		return StateRunning
+	}
+}
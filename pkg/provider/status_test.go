@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateState(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []ServiceStatus
+		expected string
+	}{
+		{
+			name:     "no services",
+			expected: StateStopped,
+		},
+		{
+			name: "all running",
+			services: []ServiceStatus{
+				{State: "running"},
+				{State: "running", Health: HealthHealthy},
+			},
+			expected: StateRunning,
+		},
+		{
+			name: "health check starting",
+			services: []ServiceStatus{
+				{State: "running", Health: HealthStarting},
+				{State: "running"},
+			},
+			expected: StateStarting,
+		},
+		{
+			name: "health check failing",
+			services: []ServiceStatus{
+				{State: "running", Health: HealthUnhealthy},
+				{State: "running", Health: HealthStarting},
+			},
+			expected: StateUnhealthy,
+		},
+		{
+			name: "one service crashed",
+			services: []ServiceStatus{
+				{State: "running"},
+				{State: "exited", ExitCode: 1},
+			},
+			expected: StatePartial,
+		},
+		{
+			name: "completed init job",
+			services: []ServiceStatus{
+				{State: "running"},
+				{State: "exited", ExitCode: 0},
+			},
+			expected: StateRunning,
+		},
+		{
+			name: "crash loop",
+			services: []ServiceStatus{
+				{State: "restarting", RestartCount: 5},
+				{State: "exited", ExitCode: 137},
+			},
+			expected: StateCrashed,
+		},
+		{
+			name: "pending",
+			services: []ServiceStatus{
+				{State: "pending"},
+				{State: "pending"},
+			},
+			expected: StateStarting,
+		},
+		{
+			name: "some pending",
+			services: []ServiceStatus{
+				{State: "running", Health: HealthHealthy},
+				{State: "pending"},
+			},
+			expected: StateStarting,
+		},
+		{
+			name: "service missing",
+			services: []ServiceStatus{
+				{State: "running"},
+				{State: "missing"},
+			},
+			expected: StatePartial,
+		},
+		{
+			name: "stopped",
+			services: []ServiceStatus{
+				{State: "exited", ExitCode: 0},
+				{State: "created"},
+			},
+			expected: StateStopped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, AggregateState(tt.services))
+		})
+	}
+}
+
+func TestServiceStatusUptime(t *testing.T) {
+	running := ServiceStatus{State: "running", StartedAt: time.Now().Add(-90 * time.Second)}
+	assert.GreaterOrEqual(t, running.Uptime(), 90*time.Second)
+
+	exited := ServiceStatus{State: "exited", StartedAt: time.Now().Add(-time.Hour)}
+	assert.Zero(t, exited.Uptime())
+}
+
+func TestPortMappingString(t *testing.T) {
+	assert.Equal(t, "0.0.0.0:8080->80/tcp", PortMapping{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}.String())
+	assert.Equal(t, "80/tcp", PortMapping{ContainerPort: 80, Protocol: "tcp"}.String())
+}
@@ -0,0 +1,295 @@
+// Package readiness waits until a deployment is actually usable, by running
+// the readiness probes its template declares.
+package readiness
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultInterval is the delay between two rounds of probes.
+	DefaultInterval = 2 * time.Second
+	// probeTimeout bounds a single network probe.
+	probeTimeout = 5 * time.Second
+	// progressInterval is how often pending probes are reported.
+	progressInterval = 10 * time.Second
+	// maxBodySize bounds the part of an HTTP response matched against a body pattern.
+	maxBodySize = 1 << 20
+)
+
+// Target is a deployed instance whose readiness is probed.
+type Target struct {
+	Provider provider.Provider
+	Template *tmpl.Template
+	Instance string
+	// Endpoints are the published addresses of the instance, used to reach
+	// the container ports of http and tcp probes.
+	Endpoints []state.Endpoint
+}
+
+// Probes returns the probes of the target template. Templates without
+// readiness probes are ready once every service runs and passes its health check.
+func (t Target) Probes() []tmpl.Probe {
+	if len(t.Template.Readiness) == 0 {
+		return []tmpl.Probe{{Type: tmpl.ProbeTypeHealthcheck}}
+	}
+	return t.Template.Readiness
+}
+
+// Wait runs the probes of the target until all of them passed once, the
+// deployment crashed or timeout elapsed.
+func Wait(ctx context.Context, target Target, timeout, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	lastReport := started
+	pending := target.Probes()
+	errs := make([]error, len(pending))
+
+	for {
+		status, err := target.Provider.Status(target.Template, target.Instance)
+		if err != nil {
+			return err
+		}
+		if status.State == provider.StateCrashed {
+			return fmt.Errorf("%s crashed while waiting for it to be ready", target.Instance)
+		}
+
+		var remaining []tmpl.Probe
+		var remainingErrs []error
+		for i, probe := range pending {
+			if errs[i] = Check(ctx, target, status, probe); errs[i] != nil {
+				remaining = append(remaining, probe)
+				remainingErrs = append(remainingErrs, errs[i])
+				continue
+			}
+			log.Info().Msgf("%s passed after %s", probe, time.Since(started).Truncate(time.Second))
+		}
+		pending, errs = remaining, remainingErrs
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if time.Since(lastReport) >= progressInterval {
+			lastReport = time.Now()
+			log.Info().Msgf("waiting for %s (%s elapsed): %s", target.Instance, time.Since(started).Truncate(time.Second), describe(pending, errs))
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%s is not ready after %s: %s", target.Instance, timeout, describe(pending, errs))
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Check runs a single probe against the target, status being its current status.
+// It returns nil when the probe passes.
+func Check(ctx context.Context, target Target, status provider.Status, probe tmpl.Probe) error {
+	switch probe.Type {
+	case tmpl.ProbeTypeHealthcheck:
+		return checkHealth(status, probe)
+	case tmpl.ProbeTypeTCP:
+		address, err := target.Address(status, probe.Service, probe.Port)
+		if err != nil {
+			return err
+		}
+		return checkTCP(ctx, address)
+	case tmpl.ProbeTypeHTTP:
+		url, err := target.URL(status, probe.Service, probe.Port, probe.Path)
+		if err != nil {
+			return err
+		}
+		return checkHTTP(ctx, url, probe)
+	case tmpl.ProbeTypeLog:
+		return checkLogs(target, probe)
+	default:
+		return fmt.Errorf("unknown probe type %s", probe.Type)
+	}
+}
+
+// checkHealth requires the probed services to run and pass their health check.
+func checkHealth(status provider.Status, probe tmpl.Probe) error {
+	if probe.Service == "" {
+		if status.State != provider.StateRunning {
+			return fmt.Errorf("deployment is %s (%s)", status.State, status.Summary())
+		}
+		return nil
+	}
+
+	index := slices.IndexFunc(status.Services, func(s provider.ServiceStatus) bool { return s.Name == probe.Service })
+	if index < 0 {
+		return fmt.Errorf("service %s not found", probe.Service)
+	}
+	service := status.Services[index]
+	if service.State != "running" {
+		return fmt.Errorf("service %s is %s", service.Name, service.State)
+	}
+	if service.Health != "" && service.Health != provider.HealthHealthy {
+		return fmt.Errorf("service %s is %s", service.Name, service.Health)
+	}
+	return nil
+}
+
+// Address returns the host address publishing a container port of the target,
+// from its recorded endpoints or the published ports of its services. An
+// empty service matches any service.
+func (t Target) Address(status provider.Status, service string, port int) (string, error) {
+	for _, endpoint := range t.Endpoints {
+		if endpoint.ContainerPort == port && endpoint.HostPort != 0 && (service == "" || endpoint.Service == service) {
+			return net.JoinHostPort(hostAddress(endpoint.HostIP), strconv.Itoa(endpoint.HostPort)), nil
+		}
+	}
+
+	for _, s := range status.Services {
+		if service != "" && s.Name != service {
+			continue
+		}
+		for _, mapping := range s.Ports {
+			if mapping.ContainerPort == port && mapping.HostPort != 0 {
+				return net.JoinHostPort(hostAddress(mapping.HostIP), strconv.Itoa(mapping.HostPort)), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("port %d is not published", port)
+}
+
+// URL returns the URL of path on a container port of the target. Ports
+// usually serving TLS are requested over HTTPS.
+func (t Target) URL(status provider.Status, service string, port int, path string) (string, error) {
+	address, err := t.Address(status, service, port)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := "http"
+	if port == 443 || port == 8443 {
+		scheme = "https"
+	}
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, address, path), nil
+}
+
+// hostAddress returns the address to connect to for a published host IP.
+func hostAddress(hostIP string) string {
+	if hostIP == "" || hostIP == "0.0.0.0" || hostIP == "::" {
+		return "localhost"
+	}
+	return hostIP
+}
+
+// checkTCP requires address to accept connections.
+func checkTCP(ctx context.Context, address string) error {
+	dialer := net.Dialer{Timeout: probeTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkHTTP requires the response of url to have the expected status and
+// body. The certificate of the target is not verified.
+func checkHTTP(ctx context.Context, url string, probe tmpl.Probe) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+			DisableKeepAlives: true,
+		},
+		// redirects are reported as is, a login redirect usually means the application is up
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close() //nolint:errcheck
+
+	if probe.Status != 0 && response.StatusCode != probe.Status {
+		return fmt.Errorf("%s returned status %d, expected %d", url, response.StatusCode, probe.Status)
+	}
+	if probe.Status == 0 && (response.StatusCode < 200 || response.StatusCode >= 400) {
+		return fmt.Errorf("%s returned status %d", url, response.StatusCode)
+	}
+
+	if probe.Body == "" {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	pattern, err := regexp.Compile(probe.Body)
+	if err != nil {
+		return err
+	}
+	if !pattern.Match(body) {
+		return fmt.Errorf("body of %s does not match %q", url, probe.Body)
+	}
+	return nil
+}
+
+// checkLogs requires a log line of the probed service, or of any service when
+// the probe names none, to match the pattern.
+func checkLogs(target Target, probe tmpl.Probe) error {
+	var output bytes.Buffer
+	opts := provider.LogOptions{Tail: "all", Output: &output}
+	if probe.Service != "" {
+		opts.Services = []string{probe.Service}
+	}
+	if err := target.Provider.Logs(target.Template, target.Instance, opts); err != nil {
+		return err
+	}
+
+	pattern, err := regexp.Compile(probe.Pattern)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(output.String(), "\n") {
+		if pattern.MatchString(line) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no log line matches %q yet", probe.Pattern)
+}
+
+// describe lists the pending probes with the reason they failed.
+func describe(probes []tmpl.Probe, errs []error) string {
+	parts := make([]string, len(probes))
+	for i, probe := range probes {
+		parts[i] = fmt.Sprintf("%s: %v", probe, errs[i])
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,172 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider reports a fixed status and log output, or the output of the
+// requested services when serviceLogs is set.
+type fakeProvider struct {
+	status      provider.Status
+	logs        string
+	serviceLogs map[string]string
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) Start(*tmpl.Template, string, provider.StartOptions) error { return nil }
+
+func (f *fakeProvider) Stop(*tmpl.Template, string) error { return nil }
+
+func (f *fakeProvider) Status(*tmpl.Template, string) (provider.Status, error) {
+	return f.status, nil
+}
+
+func (f *fakeProvider) Logs(_ *tmpl.Template, _ string, opts provider.LogOptions) error {
+	if f.serviceLogs == nil {
+		_, err := io.WriteString(opts.Output, f.logs)
+		return err
+	}
+
+	for service, logs := range f.serviceLogs {
+		if len(opts.Services) > 0 && !slices.Contains(opts.Services, service) {
+			continue
+		}
+		if _, err := io.WriteString(opts.Output, logs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeProvider) Exec(*tmpl.Template, string, provider.ExecOptions) (int, error) {
+	return 0, nil
+}
+
+// serverEndpoint returns the endpoint of a test server for the given container port.
+func serverEndpoint(t *testing.T, address string, containerPort int) state.Endpoint {
+	t.Helper()
+	host, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	hostPort, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return state.Endpoint{Service: "web", HostIP: host, HostPort: hostPort, ContainerPort: containerPort, Protocol: "tcp"}
+}
+
+func TestCheckHealth(t *testing.T) {
+	status := provider.Status{
+		State: provider.StateStarting,
+		Services: []provider.ServiceStatus{
+			{Name: "web", State: "running"},
+			{Name: "db", State: "running", Health: provider.HealthStarting},
+		},
+	}
+
+	assert.EqualError(t, checkHealth(status, tmpl.Probe{Type: tmpl.ProbeTypeHealthcheck}), "deployment is starting (2/2 running)")
+	assert.NoError(t, checkHealth(status, tmpl.Probe{Type: tmpl.ProbeTypeHealthcheck, Service: "web"}))
+	assert.EqualError(t, checkHealth(status, tmpl.Probe{Type: tmpl.ProbeTypeHealthcheck, Service: "db"}), "service db is starting")
+	assert.EqualError(t, checkHealth(status, tmpl.Probe{Type: tmpl.ProbeTypeHealthcheck, Service: "cache"}), "service cache not found")
+}
+
+func TestCheckHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/login" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<title>WebGoat</title>")
+	}))
+	defer server.Close()
+
+	target := Target{
+		Provider:  &fakeProvider{},
+		Template:  &tmpl.Template{ID: "vt-webgoat"},
+		Instance:  "vt-webgoat",
+		Endpoints: []state.Endpoint{serverEndpoint(t, server.Listener.Addr().String(), 8080)},
+	}
+	ctx := context.Background()
+
+	assert.NoError(t, Check(ctx, target, provider.Status{}, tmpl.Probe{Type: tmpl.ProbeTypeHTTP, Port: 8080, Path: "/login", Body: "WebGoat"}))
+	assert.ErrorContains(t, Check(ctx, target, provider.Status{}, tmpl.Probe{Type: tmpl.ProbeTypeHTTP, Port: 8080}), "returned status 404")
+	assert.ErrorContains(t, Check(ctx, target, provider.Status{}, tmpl.Probe{Type: tmpl.ProbeTypeHTTP, Port: 8080, Path: "/login", Body: "DVWA"}), `does not match "DVWA"`)
+	assert.EqualError(t, Check(ctx, target, provider.Status{}, tmpl.Probe{Type: tmpl.ProbeTypeHTTP, Port: 9090}), "port 9090 is not published")
+}
+
+func TestCheckTCPUsesPublishedPorts(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	endpoint := serverEndpoint(t, address, 3306)
+
+	status := provider.Status{Services: []provider.ServiceStatus{{
+		Name:  "db",
+		Ports: []provider.PortMapping{{HostIP: endpoint.HostIP, HostPort: endpoint.HostPort, ContainerPort: 3306, Protocol: "tcp"}},
+	}}}
+	target := Target{Provider: &fakeProvider{}, Template: &tmpl.Template{ID: "vt-db"}, Instance: "vt-db"}
+	probe := tmpl.Probe{Type: tmpl.ProbeTypeTCP, Service: "db", Port: 3306}
+
+	assert.NoError(t, Check(context.Background(), target, status, probe))
+
+	require.NoError(t, listener.Close())
+	assert.Error(t, Check(context.Background(), target, status, probe))
+}
+
+func TestCheckLogs(t *testing.T) {
+	target := Target{
+		Provider: &fakeProvider{serviceLogs: map[string]string{
+			"web": "booting\nStarted StartWebGoat in 12.3 seconds\n",
+			"db":  "mysqld: ready for connections\n",
+		}},
+		Template: &tmpl.Template{ID: "vt-webgoat"},
+		Instance: "vt-webgoat",
+	}
+
+	// without a service, a line of any service is enough
+	assert.NoError(t, checkLogs(target, tmpl.Probe{Type: tmpl.ProbeTypeLog, Pattern: "ready for connections"}))
+	assert.NoError(t, checkLogs(target, tmpl.Probe{Type: tmpl.ProbeTypeLog, Pattern: "Started StartWebGoat"}))
+
+	assert.NoError(t, checkLogs(target, tmpl.Probe{Type: tmpl.ProbeTypeLog, Service: "db", Pattern: "ready for connections"}))
+	assert.EqualError(t, checkLogs(target, tmpl.Probe{Type: tmpl.ProbeTypeLog, Service: "web", Pattern: "ready for connections"}),
+		`no log line matches "ready for connections" yet`)
+}
+
+func TestWait(t *testing.T) {
+	fake := &fakeProvider{
+		status: provider.Status{State: provider.StateRunning, Services: []provider.ServiceStatus{{Name: "web", State: "running"}}},
+		logs:   "web-1  | booting\nweb-1  | Started WebGoat in 42 seconds\n",
+	}
+	template := &tmpl.Template{
+		ID:        "vt-webgoat",
+		Readiness: []tmpl.Probe{{Type: tmpl.ProbeTypeLog, Pattern: `Started WebGoat`}},
+	}
+	target := Target{Provider: fake, Template: template, Instance: "vt-webgoat"}
+
+	assert.NoError(t, Wait(context.Background(), target, time.Second, time.Millisecond))
+
+	template.Readiness[0].Pattern = "never"
+	err := Wait(context.Background(), target, 50*time.Millisecond, 10*time.Millisecond)
+	assert.EqualError(t, err, `vt-webgoat is not ready after 50ms: log probe of any service for "never": no log line matches "never" yet`)
+
+	fake.status.State = provider.StateCrashed
+	assert.EqualError(t, Wait(context.Background(), target, time.Second, time.Millisecond), "vt-webgoat crashed while waiting for it to be ready")
+}
+
+func TestProbesDefaultToHealthcheck(t *testing.T) {
+	target := Target{Template: &tmpl.Template{ID: "vt-dvwa"}}
+	assert.Equal(t, []tmpl.Probe{{Type: tmpl.ProbeTypeHealthcheck}}, target.Probes())
+}
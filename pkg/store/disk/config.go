@@ -1,19 +1,28 @@
 package disk
 
-// Config holds configuration parameters for disk storage including file and bucket names
+// Config holds configuration parameters for disk storage including directory, file and bucket names
 type Config struct {
+	Dir        string
 	FileName   string
 	BucketName string
 }
 
-// NewConfig creates a new Config instance with empty file and bucket names
+// NewConfig creates a new Config instance with empty directory, file and bucket names
 func NewConfig() *Config {
 	return &Config{
+		Dir:        "",
 		FileName:   "",
 		BucketName: "",
 	}
 }
 
+// WithDir sets the directory holding the database file and returns the Config for chaining.
+// An empty directory means ~/.vt
+func (c *Config) WithDir(dir string) *Config {
+	c.Dir = dir
+	return c
+}
+
 // WithFileName sets the file name for the configuration and returns the Config for chaining
 func (c *Config) WithFileName(fileName string) *Config {
 	c.FileName = fileName
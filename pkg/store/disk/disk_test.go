@@ -5,8 +5,6 @@ import (
 	"path/filepath"
 	"testing"
 
-	bolt "go.etcd.io/bbolt"
-
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,7 +27,7 @@ func setupTestStore(t *testing.T) (*Store[TestUser], string) {
 		BucketName: "test_bucket",
 	}
 
-	store, err := newStorageStoreWithPath[TestUser](config, tmpDir)
+	store, err := NewStorageStore[TestUser](config.WithDir(tmpDir))
 	require.NoError(t, err)
 	require.NotNil(t, store)
 
@@ -41,25 +39,6 @@ func setupTestStore(t *testing.T) (*Store[TestUser], string) {
 	return store, tmpDir
 }
 
-// newStorageStoreWithPath creates a new disk-based storage instance with a custom base path (for testing)
-func newStorageStoreWithPath[T any](config *Config, basePath string) (*Store[T], error) {
-	vtHomeDir := filepath.Join(basePath, ".vt")
-	if err := os.MkdirAll(vtHomeDir, 0750); err != nil {
-		return nil, err
-	}
-
-	dbPath := filepath.Join(vtHomeDir, config.FileName)
-	db, err := bolt.Open(dbPath, 0600, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return &Store[T]{
-		db:     db,
-		Config: config,
-	}, nil
-}
-
 func TestNewStorageStore(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -68,7 +47,7 @@ func TestNewStorageStore(t *testing.T) {
 		BucketName: "users",
 	}
 
-	store, err := newStorageStoreWithPath[TestUser](config, tmpDir)
+	store, err := NewStorageStore[TestUser](config.WithDir(tmpDir))
 	require.NoError(t, err)
 	require.NotNil(t, store)
 	defer func() {
@@ -77,7 +56,7 @@ func TestNewStorageStore(t *testing.T) {
 	}()
 
 	// Verify the database file was created
-	dbPath := filepath.Join(tmpDir, ".vt", "test.db")
+	dbPath := filepath.Join(tmpDir, "test.db")
 	_, err = os.Stat(dbPath)
 	assert.NoError(t, err, "database file should exist")
 
@@ -241,7 +220,7 @@ func TestStore_Close(t *testing.T) {
 		BucketName: "test",
 	}
 
-	store, err := newStorageStoreWithPath[TestUser](config, tmpDir)
+	store, err := NewStorageStore[TestUser](config.WithDir(tmpDir))
 	require.NoError(t, err)
 
 	// Close should not return an error
@@ -258,7 +237,7 @@ func TestStore_WithDifferentTypes(t *testing.T) {
 			BucketName: "strings",
 		}
 
-		store, err := newStorageStoreWithPath[string](config, tmpDir)
+		store, err := NewStorageStore[string](config.WithDir(tmpDir))
 		require.NoError(t, err)
 		defer func() {
 			err = store.Close()
@@ -279,7 +258,7 @@ func TestStore_WithDifferentTypes(t *testing.T) {
 			BucketName: "integers",
 		}
 
-		store, err := newStorageStoreWithPath[int](config, tmpDir)
+		store, err := NewStorageStore[int](config.WithDir(tmpDir))
 		require.NoError(t, err)
 		defer func() {
 			err = store.Close()
@@ -300,7 +279,7 @@ func TestStore_WithDifferentTypes(t *testing.T) {
 			BucketName: "maps",
 		}
 
-		store, err := newStorageStoreWithPath[map[string]string](config, tmpDir)
+		store, err := NewStorageStore[map[string]string](config.WithDir(tmpDir))
 		require.NoError(t, err)
 		defer func() {
 			err = store.Close()
@@ -342,3 +321,15 @@ func TestStore_ConcurrentOperations(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, results, len(users))
 }
+
+func TestNewStorageStoreDefaultDir(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	store, err := NewStorageStore[TestUser](NewConfig().WithFileName("test.db").WithBucketName("users"))
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	_, err = os.Stat(filepath.Join(homeDir, ".vt", "test.db"))
+	assert.NoError(t, err)
+}
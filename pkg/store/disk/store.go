@@ -100,19 +100,23 @@ func (s *Store[T]) Close() error {
 	return s.db.Close()
 }
 
-// NewStorageStore creates a new disk-based storage instance with the given configuration
+// NewStorageStore creates a new disk-based storage instance with the given configuration.
+// The database file is created in the configured directory, or in ~/.vt when it is empty
 func NewStorageStore[T any](config *Config) (*Store[T], error) {
-	userHomeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
+	dir := config.Dir
+	if dir == "" {
+		userHomeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(userHomeDir, ".vt")
 	}
 
-	vtHomeDir := filepath.Join(userHomeDir, ".vt")
-	if err := os.MkdirAll(vtHomeDir, 0750); err != nil {
+	if err := os.MkdirAll(dir, 0750); err != nil {
 		return nil, err
 	}
 
-	dbPath := filepath.Join(vtHomeDir, config.FileName)
+	dbPath := filepath.Join(dir, config.FileName)
 	db, err := bolt.Open(dbPath, 0600, nil)
 	if err != nil {
 		return nil, err
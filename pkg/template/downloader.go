@@ -4,60 +4,331 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-func cloneTemplatesRepo(repoPath string, force bool) error {
-	repo, err := git.PlainOpen(repoPath)
-	if err == nil {
-		worktree, err := repo.Worktree()
+// commitHashPattern matches abbreviated and full commit hashes.
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// checkoutTemplatesRepo makes repoPath a clone of repoURL checked out at commit,
+// or at the latest commit of ref when commit is empty. An empty ref is the
+// default branch of the remote. Local changes are only discarded when force is
+// set. It returns the commit checked out before, zero for a new clone, and the
+// commit checked out now.
+func checkoutTemplatesRepo(repoPath, repoURL, ref, commit string, force bool) (previous, current plumbing.Hash, err error) {
+	repo, fresh, err := openTemplatesRepo(repoPath, repoURL)
+	if err != nil {
+		return plumbing.ZeroHash, plumbing.ZeroHash, err
+	}
+
+	if head, err := repo.Head(); err == nil {
+		previous = head.Hash()
+	}
+
+	// a locked commit already checked out needs no network access
+	if commit != "" && !previous.IsZero() && strings.HasPrefix(previous.String(), commit) {
+		return previous, previous, nil
+	}
+
+	revision := ref
+	if commit != "" {
+		revision = commit
+	}
+
+	current, err = fetchRevision(repo, revision)
+	if err == nil && current != previous {
+		err = checkoutRevision(repo, repoPath, current, force || fresh)
+	}
+
+	if err != nil {
+		if fresh {
+			if removeErr := os.RemoveAll(repoPath); removeErr != nil {
+				return previous, previous, errors.Join(err, fmt.Errorf("cleanup failed: %w", removeErr))
+			}
+		}
+		return previous, previous, err
+	}
+
+	return previous, current, nil
+}
+
+// openTemplatesRepo opens the clone in repoPath, or initializes an empty one
+// when repoPath is not a git repository. The origin remote is pointed at repoURL.
+func openTemplatesRepo(repoPath, repoURL string) (repo *git.Repository, fresh bool, err error) {
+	repo, err = git.PlainOpen(repoPath)
+	if err != nil {
+		if err := os.RemoveAll(repoPath); err != nil {
+			return nil, false, err
+		}
+		if err := os.MkdirAll(repoPath, 0750); err != nil {
+			return nil, false, err
+		}
+		if repo, err = git.PlainInit(repoPath, false); err != nil {
+			return nil, false, err
+		}
+		fresh = true
+	}
+
+	if remote, err := repo.Remote("origin"); err == nil {
+		if urls := remote.Config().URLs; len(urls) > 0 && urls[0] == repoURL {
+			return repo, fresh, nil
+		}
+		if err := repo.DeleteRemote("origin"); err != nil {
+			return nil, false, err
+		}
+	}
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{repoURL}})
+	if err != nil {
+		return nil, false, err
+	}
+	return repo, fresh, nil
+}
+
+// fetchRevision fetches ref, a branch, a tag or a commit hash, from the origin
+// remote and returns the commit it points to. Branches and tags are fetched
+// shallow, a commit requires fetching the history of all branches.
+func fetchRevision(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to list remote references: %w", err)
+	}
+
+	name, found := findRemoteReference(refs, ref)
+	if !found {
+		if !commitHashPattern.MatchString(ref) {
+			return plumbing.ZeroHash, fmt.Errorf("branch or tag %q not found", ref)
+		}
+		return fetchCommit(repo, ref)
+	}
+
+	local := name
+	if name.IsBranch() {
+		local = plumbing.NewRemoteReferenceName("origin", name.Short())
+	}
+
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", name, local))},
+		Depth:      1,
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, err
+	}
+
+	reference, err := repo.Reference(local, true)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	// annotated tags point to a tag object instead of the commit
+	if tag, err := repo.TagObject(reference.Hash()); err == nil {
+		commit, err := tag.Commit()
 		if err != nil {
-			return err
+			return plumbing.ZeroHash, err
+		}
+		return commit.Hash, nil
+	}
+	return reference.Hash(), nil
+}
+
+// findRemoteReference returns the name of the remote branch or tag called ref,
+// or of the default branch when ref is empty.
+func findRemoteReference(refs []*plumbing.Reference, ref string) (plumbing.ReferenceName, bool) {
+	if ref == "" {
+		var head *plumbing.Reference
+		for _, r := range refs {
+			if r.Name() == plumbing.HEAD {
+				head = r
+			}
+		}
+		if head == nil {
+			return "", false
 		}
+		if head.Type() == plumbing.SymbolicReference {
+			return head.Target(), true
+		}
+		for _, r := range refs {
+			if r.Name().IsBranch() && r.Hash() == head.Hash() {
+				return r.Name(), true
+			}
+		}
+		return "", false
+	}
+
+	for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)} {
+		for _, r := range refs {
+			if r.Name() == name {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// fetchCommit returns the commit matching hash, fetching the history of all
+// branches when it is not available locally.
+func fetchCommit(repo *git.Repository, hash string) (plumbing.Hash, error) {
+	if commit, err := repo.ResolveRevision(plumbing.Revision(hash)); err == nil {
+		return *commit, nil
+	}
+
+	err := repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		Tags:       git.AllTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, err
+	}
+
+	commit, err := repo.ResolveRevision(plumbing.Revision(hash))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("commit %s not found: %w", hash, err)
+	}
+	return *commit, nil
+}
 
+// checkoutRevision checks out hash in the worktree of repo. Local changes are
+// only discarded when force is set.
+func checkoutRevision(repo *git.Repository, repoPath string, hash plumbing.Hash, force bool) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if !force {
 		status, err := worktree.Status()
 		if err != nil {
 			return err
 		}
-
-		if !force && !status.IsClean() {
+		if !status.IsClean() {
 			return fmt.Errorf("detected uncommitted changes in %s", repoPath)
 		}
+	}
 
-		err = worktree.Pull(&git.PullOptions{
-			RemoteName: "origin",
-			Force:      true,
-		})
+	return worktree.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+}
 
-		if err != nil && err != git.NoErrAlreadyUpToDate {
-			return err
-		}
+// headCommit returns the commit checked out in the clone at repoPath.
+func headCommit(repoPath string) (plumbing.Hash, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return head.Hash(), nil
+}
 
-		return nil
+// diffTemplates compares the templates of two commits, identifying templates
+// by the directories holding an index.yaml. from is zero for a new clone, in
+// which case every template is reported as added.
+func diffTemplates(repo *git.Repository, from, to plumbing.Hash) (added, removed, modified []string, err error) {
+	toTree, err := commitTree(repo, to)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	newDirs := templateDirs(toTree)
+
+	var fromTree *object.Tree
+	oldDirs := map[string]string{}
+	if !from.IsZero() {
+		if fromTree, err = commitTree(repo, from); err != nil {
+			return nil, nil, nil, err
+		}
+		oldDirs = templateDirs(fromTree)
 	}
 
-	err = os.RemoveAll(repoPath)
+	changes, err := object.DiffTree(fromTree, toTree)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 
-	if err := os.MkdirAll(repoPath, 0750); err != nil {
-		return err
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+				if oldDirs[dir] != "" || newDirs[dir] != "" {
+					changed[dir] = true
+					break
+				}
+			}
+		}
 	}
 
-	_, err = git.PlainClone(repoPath, false, &git.CloneOptions{
-		URL:   TemplateRemoteRepository,
-		Depth: 1,
-	})
+	oldIDs := invertDirs(oldDirs)
+	newIDs := invertDirs(newDirs)
+	for id, dir := range newIDs {
+		oldDir, exists := oldIDs[id]
+		switch {
+		case !exists:
+			added = append(added, id)
+		case oldDir != dir || changed[dir]:
+			modified = append(modified, id)
+		}
+	}
+	for id := range oldIDs {
+		if _, exists := newIDs[id]; !exists {
+			removed = append(removed, id)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified, nil
+}
 
+// commitTree returns the tree of the given commit.
+func commitTree(repo *git.Repository, hash plumbing.Hash) (*object.Tree, error) {
+	commit, err := repo.CommitObject(hash)
 	if err != nil {
-		removeErr := os.RemoveAll(repoPath)
-		if removeErr != nil {
-			return errors.Join(err, fmt.Errorf("cleanup failed: %w", removeErr))
-		}
-		return err
+		return nil, err
 	}
+	return commit.Tree()
+}
 
-	return nil
+// templateDirs returns the template directories of tree mapped to their
+// template ID, which matches the directory name. Scenarios are skipped.
+func templateDirs(tree *object.Tree) map[string]string {
+	dirs := make(map[string]string)
+	_ = tree.Files().ForEach(func(file *object.File) error { //nolint:errcheck
+		if path.Base(file.Name) != "index.yaml" || strings.HasPrefix(file.Name, ".") || strings.Contains(file.Name, "/.") ||
+			strings.HasPrefix(file.Name, ScenariosDir+"/") {
+			return nil
+		}
+		if dir := path.Dir(file.Name); dir != "." {
+			dirs[dir] = path.Base(dir)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// invertDirs maps template IDs to their directory.
+func invertDirs(dirs map[string]string) map[string]string {
+	ids := make(map[string]string, len(dirs))
+	for dir, id := range dirs {
+		ids[id] = dir
+	}
+	return ids
 }
@@ -0,0 +1,146 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUpstream is a local git repository serving as template source.
+type testUpstream struct {
+	t    *testing.T
+	path string
+	repo *git.Repository
+}
+
+func newTestUpstream(t *testing.T) *testUpstream {
+	t.Helper()
+	path := t.TempDir()
+	repo, err := git.PlainInit(path, false)
+	require.NoError(t, err)
+	return &testUpstream{t: t, path: path, repo: repo}
+}
+
+// commit writes the given files, removes the paths mapped to an empty content
+// and commits the result.
+func (u *testUpstream) commit(message string, files map[string]string) plumbing.Hash {
+	u.t.Helper()
+	worktree, err := u.repo.Worktree()
+	require.NoError(u.t, err)
+
+	for name, content := range files {
+		path := filepath.Join(u.path, name)
+		if content == "" {
+			require.NoError(u.t, os.RemoveAll(path))
+			continue
+		}
+		require.NoError(u.t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(u.t, os.WriteFile(path, []byte(content), 0600))
+	}
+	require.NoError(u.t, worktree.AddWithOptions(&git.AddOptions{All: true}))
+
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "vt", Email: "vt@example.com", When: time.Now()},
+	})
+	require.NoError(u.t, err)
+	return hash
+}
+
+func testIndex(id string) string {
+	return "id: " + id + "\ninfo:\n  name: " + id + "\n  author: vt\n  description: test\n  type: Lab\n  targets: [test]\n  tags: [test]\nproviders:\n  docker-compose:\n    path: docker-compose.yaml\n"
+}
+
+func TestUpdateSourcesReportsChanges(t *testing.T) {
+	upstream := newTestUpstream(t)
+	first := upstream.commit("initial", map[string]string{
+		"web/vt-dvwa/index.yaml":          testIndex("vt-dvwa"),
+		"web/vt-dvwa/docker-compose.yaml": "services: {}\n",
+		"web/vt-xss/index.yaml":           testIndex("vt-xss"),
+	})
+
+	source := Source{Name: "official", URL: upstream.path, Path: filepath.Join(t.TempDir(), "templates")}
+	templates, _, err := LoadSources([]Source{source})
+	require.NoError(t, err)
+	assert.Len(t, templates, 2)
+
+	head, err := headCommit(source.Path)
+	require.NoError(t, err)
+	assert.Equal(t, first, head)
+
+	second := upstream.commit("change labs", map[string]string{
+		"web/vt-dvwa/docker-compose.yaml": "services:\n  web: {}\n",
+		"web/vt-xss":                      "",
+		"api/vt-graphql/index.yaml":       testIndex("vt-graphql"),
+	})
+
+	updates, err := UpdateSources([]Source{source})
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, Update{
+		Source:   "official",
+		From:     first.String(),
+		To:       second.String(),
+		Added:    []string{"vt-graphql"},
+		Removed:  []string{"vt-xss"},
+		Modified: []string{"vt-dvwa"},
+	}, updates[0])
+
+	updates, err = UpdateSources([]Source{source})
+	require.NoError(t, err)
+	assert.False(t, updates[0].Changed(), "a second update must not report changes")
+}
+
+func TestLoadSourcesChecksOutRefAndLockedCommit(t *testing.T) {
+	upstream := newTestUpstream(t)
+	first := upstream.commit("initial", map[string]string{"web/vt-dvwa/index.yaml": testIndex("vt-dvwa")})
+	_, err := upstream.repo.CreateTag("v1", first, nil)
+	require.NoError(t, err)
+	upstream.commit("add lab", map[string]string{"web/vt-xss/index.yaml": testIndex("vt-xss")})
+
+	// a tag keeps the source on the tagged commit, even when updated
+	tagged := Source{Name: "tagged", URL: upstream.path, Ref: "v1", Path: filepath.Join(t.TempDir(), "tagged")}
+	templates, _, err := LoadSources([]Source{tagged})
+	require.NoError(t, err)
+	assert.NotContains(t, templates, "vt-xss")
+	updates, err := UpdateSources([]Source{tagged})
+	require.NoError(t, err)
+	assert.Equal(t, first.String(), updates[0].To)
+
+	// the lock pins the commit a source was first cloned at
+	lockPath := filepath.Join(t.TempDir(), "templates.lock")
+	lock, err := ReadLock(lockPath)
+	require.NoError(t, err)
+	assert.True(t, lock.Record([]Source{tagged}))
+	assert.False(t, lock.Record([]Source{tagged}), "recording the same commit must not change the lock")
+	require.NoError(t, lock.Save(lockPath))
+
+	lock, err = ReadLock(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, first.String(), lock.Repositories["tagged"].Commit)
+
+	// another machine sharing the lock gets the locked commit instead of the latest one
+	other := Source{Name: "tagged", URL: upstream.path, Ref: "v1", Path: filepath.Join(t.TempDir(), "other")}
+	templates, _, err = LoadSources(lock.Pin([]Source{other}))
+	require.NoError(t, err)
+	assert.NotContains(t, templates, "vt-xss")
+
+	// a changed ref invalidates the locked commit
+	other.Ref = "master"
+	assert.Empty(t, lock.Pin([]Source{other})[0].Commit)
+
+	// an abbreviated commit works as ref as well
+	pinned := Source{Name: "pinned", URL: upstream.path, Ref: first.String()[:8], Path: filepath.Join(t.TempDir(), "pinned")}
+	templates, _, err = LoadSources([]Source{pinned})
+	require.NoError(t, err)
+	assert.NotContains(t, templates, "vt-xss")
+
+	assert.True(t, lock.Record(nil), "repositories no longer configured must be forgotten")
+	assert.Empty(t, lock.Repositories)
+}
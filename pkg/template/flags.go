@@ -0,0 +1,64 @@
+package template
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+var flagNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Flag is a location where a CTF flag is planted. A random flag is generated
+// for every deployment and injected in exactly one way: as the variable Env,
+// written to File in Service, or passed to Command run in Service as VT_FLAG.
+type Flag struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	// Env is the name under which the flag is available to compose
+	// interpolation and Kubernetes manifests, like a variable.
+	Env string `yaml:"env" json:"env"`
+	// Service is the service File is written to or Command runs in. It may be
+	// empty when the deployment has a single service.
+	Service string `yaml:"service" json:"service"`
+	// File is the absolute path the flag is written to.
+	File string `yaml:"file" json:"file"`
+	// Command runs once the deployment started, with the flag in VT_FLAG.
+	Command []string `yaml:"command" json:"command"`
+}
+
+// Validate validates the flag declaration.
+func (f Flag) Validate(templateID string) error {
+	if !flagNameRegex.MatchString(f.Name) {
+		return fmt.Errorf("template '%s': invalid flag name '%s'", templateID, f.Name)
+	}
+
+	locations := 0
+	for _, set := range []bool{f.Env != "", f.File != "", len(f.Command) > 0} {
+		if set {
+			locations++
+		}
+	}
+	if locations != 1 {
+		return fmt.Errorf("template '%s', flag '%s': exactly one of env, file or command must be set", templateID, f.Name)
+	}
+
+	if f.Env != "" && !variableNameRegex.MatchString(f.Env) {
+		return fmt.Errorf("template '%s', flag '%s': invalid env name '%s'", templateID, f.Name, f.Env)
+	}
+	if f.File != "" && !path.IsAbs(f.File) {
+		return fmt.Errorf("template '%s', flag '%s': file must be an absolute path", templateID, f.Name)
+	}
+	return nil
+}
+
+// FlagEnvironment returns the values of the env flags keyed by their env
+// name, flags mapping flag names to the flags of a deployment.
+func (t Template) FlagEnvironment(flags map[string]string) map[string]string {
+	environment := make(map[string]string)
+	for _, flag := range t.Flags {
+		if value, ok := flags[flag.Name]; ok && flag.Env != "" {
+			environment[flag.Env] = value
+		}
+	}
+	return environment
+}
@@ -0,0 +1,53 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagValidate(t *testing.T) {
+	valid := []Flag{
+		{Name: "user", Env: "USER_FLAG"},
+		{Name: "root", Service: "web", File: "/root/flag.txt"},
+		{Name: "db", Service: "db", Command: []string{"sh", "-c", "echo $VT_FLAG"}},
+	}
+	for _, flag := range valid {
+		assert.NoError(t, flag.Validate("vt-test"), flag.Name)
+	}
+
+	invalid := map[string]Flag{
+		"invalid flag name":             {Name: "Root", Env: "FLAG"},
+		"exactly one of env, file":      {Name: "root"},
+		"exactly one of env, file or":   {Name: "root", Env: "FLAG", File: "/flag"},
+		"invalid env name '1FLAG'":      {Name: "root", Env: "1FLAG"},
+		"file must be an absolute path": {Name: "root", File: "flag.txt"},
+	}
+	for message, flag := range invalid {
+		assert.ErrorContains(t, flag.Validate("vt-test"), message)
+	}
+}
+
+func TestValidateRejectsConflictingFlags(t *testing.T) {
+	template := Template{
+		ID:        "vt-test",
+		Info:      Info{Name: "Test", Author: "vt", Type: "Lab", Targets: []string{"php"}, Tags: []string{"web"}},
+		Providers: map[string]ProviderConfig{"docker-compose": {Path: "docker-compose.yaml"}},
+		Variables: []Variable{{Name: "LEVEL", Default: "low"}},
+	}
+
+	template.Flags = []Flag{{Name: "user", Env: "FLAG"}, {Name: "user", File: "/flag"}}
+	assert.ErrorContains(t, template.Validate(), "duplicate flag 'user'")
+
+	template.Flags = []Flag{{Name: "user", Env: "LEVEL"}}
+	assert.ErrorContains(t, template.Validate(), "env 'LEVEL' is already used")
+
+	template.Flags = []Flag{{Name: "user", Env: "FLAG"}, {Name: "root", Env: "FLAG"}}
+	assert.ErrorContains(t, template.Validate(), "env 'FLAG' is already used")
+}
+
+func TestFlagEnvironment(t *testing.T) {
+	template := Template{Flags: []Flag{{Name: "user", Env: "USER_FLAG"}, {Name: "root", File: "/root/flag.txt"}}}
+	environment := template.FlagEnvironment(map[string]string{"user": "vt{user}", "root": "vt{root}"})
+	assert.Equal(t, map[string]string{"USER_FLAG": "vt{user}"}, environment)
+}
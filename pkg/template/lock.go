@@ -0,0 +1,116 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lock records the commit each git source is checked out at, so that every
+// machine sharing the lock file runs the same revision of the templates.
+type Lock struct {
+	Repositories map[string]LockedRepository `yaml:"repositories"`
+}
+
+// LockedRepository is the locked commit of a git source.
+type LockedRepository struct {
+	URL    string `yaml:"url"`
+	Ref    string `yaml:"ref,omitempty"`
+	Commit string `yaml:"commit"`
+}
+
+// ReadLock reads the lock file at path. A missing file yields an empty lock.
+func ReadLock(path string) (*Lock, error) {
+	lock := &Lock{Repositories: make(map[string]LockedRepository)}
+
+	data, err := os.ReadFile(path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	if lock.Repositories == nil {
+		lock.Repositories = make(map[string]LockedRepository)
+	}
+	return lock, nil
+}
+
+// Save writes the lock to path.
+func (l *Lock) Save(path string) error {
+	var data bytes.Buffer
+	data.WriteString("# Generated by vt, records the commit of each template repository.\n")
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(l); err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create lock file directory: %w", err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Pin returns the sources with the commit recorded in the lock. Sources whose
+// URL or ref changed since they were locked are not pinned.
+func (l *Lock) Pin(sources []Source) []Source {
+	pinned := make([]Source, len(sources))
+	for i, source := range sources {
+		locked, ok := l.Repositories[source.Name]
+		if ok && !source.IsLocal() && locked.URL == source.URL && locked.Ref == source.Ref {
+			source.Commit = locked.Commit
+		}
+		pinned[i] = source
+	}
+	return pinned
+}
+
+// Record locks every cloned git source at the commit it is checked out at and
+// forgets repositories that are not among sources. It reports whether the lock
+// changed.
+func (l *Lock) Record(sources []Source) bool {
+	changed := false
+	known := make(map[string]bool, len(sources))
+
+	for _, source := range sources {
+		if source.IsLocal() {
+			continue
+		}
+		known[source.Name] = true
+
+		head, err := headCommit(source.Path)
+		if err != nil {
+			continue
+		}
+
+		locked := LockedRepository{URL: source.URL, Ref: source.Ref, Commit: head.String()}
+		if l.Repositories[source.Name] != locked {
+			l.Repositories[source.Name] = locked
+			changed = true
+		}
+	}
+
+	for name := range l.Repositories {
+		if !known[name] {
+			delete(l.Repositories, name)
+			changed = true
+		}
+	}
+
+	return changed
+}
@@ -19,6 +19,7 @@ func LoadTemplate(filepath string) (Template, error) {
 	if err != nil {
 		return template, err
 	}
+	template.Dir = filepath
 	err = template.Validate()
 	return template, err
 }
@@ -51,6 +51,7 @@ providers:
 	assert.Equal(t, 1, len(tpl.Info.References))
 	assert.Equal(t, 3, len(tpl.Info.Tags))
 	assert.Contains(t, tpl.Providers, "docker-compose")
+	assert.Equal(t, tempDir, tpl.Dir)
 
 	// case of none exist path
 	_, err = LoadTemplate("/non/existent/path")
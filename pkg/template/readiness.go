@@ -0,0 +1,92 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Readiness probe types.
+const (
+	// ProbeTypeHTTP expects an HTTP response with a status and optionally a body matching.
+	ProbeTypeHTTP = "http"
+	// ProbeTypeTCP expects a port to accept connections.
+	ProbeTypeTCP = "tcp"
+	// ProbeTypeHealthcheck expects services to be running and their health checks passing.
+	ProbeTypeHealthcheck = "healthcheck"
+	// ProbeTypeLog expects a line matching a pattern in the logs of a service.
+	ProbeTypeLog = "log"
+)
+
+// Probe is a condition that must hold before a deployment is usable.
+type Probe struct {
+	Type string `yaml:"type" json:"type"`
+	// Service restricts the probe to a service. Empty means any service for
+	// http, tcp and log probes, and every service for healthcheck probes.
+	Service string `yaml:"service" json:"service"`
+	// Port is the container port probed by http and tcp probes.
+	Port int `yaml:"port" json:"port"`
+	// Path is the HTTP path requested, "/" by default.
+	Path string `yaml:"path" json:"path"`
+	// Status is the expected HTTP status, any 2xx or 3xx status when zero.
+	Status int `yaml:"status" json:"status"`
+	// Body is a regular expression the HTTP response body must match.
+	Body string `yaml:"body" json:"body"`
+	// Pattern is a regular expression a log line must match.
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+// Validate validates the probe declaration.
+func (p Probe) Validate(templateID string) error {
+	switch p.Type {
+	case ProbeTypeHTTP, ProbeTypeTCP:
+		if p.Port < 1 || p.Port > 65535 {
+			return fmt.Errorf("template '%s', %s probe: port must be between 1 and 65535", templateID, p.Type)
+		}
+	case ProbeTypeHealthcheck:
+	case ProbeTypeLog:
+		if p.Pattern == "" {
+			return fmt.Errorf("template '%s', log probe: pattern can not be empty", templateID)
+		}
+	default:
+		return fmt.Errorf("template '%s': unknown probe type '%s', must be one of: http, tcp, healthcheck, log", templateID, p.Type)
+	}
+
+	if p.Type == ProbeTypeHTTP {
+		if p.Path != "" && !strings.HasPrefix(p.Path, "/") {
+			return fmt.Errorf("template '%s', http probe: path must start with '/'", templateID)
+		}
+		if p.Status != 0 && (p.Status < 100 || p.Status > 599) {
+			return fmt.Errorf("template '%s', http probe: invalid status %d", templateID, p.Status)
+		}
+	}
+
+	for _, expression := range []string{p.Body, p.Pattern} {
+		if _, err := regexp.Compile(expression); err != nil {
+			return fmt.Errorf("template '%s', %s probe: %w", templateID, p.Type, err)
+		}
+	}
+	return nil
+}
+
+// String describes the probe, e.g. "http probe of web:8080/login".
+func (p Probe) String() string {
+	target := p.Service
+	if target == "" {
+		target = "any service"
+		if p.Type == ProbeTypeHealthcheck {
+			target = "all services"
+		}
+	}
+
+	switch p.Type {
+	case ProbeTypeHTTP:
+		return fmt.Sprintf("http probe of %s:%d%s", target, p.Port, p.Path)
+	case ProbeTypeTCP:
+		return fmt.Sprintf("tcp probe of %s:%d", target, p.Port)
+	case ProbeTypeLog:
+		return fmt.Sprintf("log probe of %s for %q", target, p.Pattern)
+	default:
+		return fmt.Sprintf("%s probe of %s", p.Type, target)
+	}
+}
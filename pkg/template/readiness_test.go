@@ -0,0 +1,38 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeValidate(t *testing.T) {
+	valid := []Probe{
+		{Type: ProbeTypeHTTP, Service: "web", Port: 8080, Path: "/WebGoat/login", Status: 200, Body: "Sign in"},
+		{Type: ProbeTypeTCP, Port: 3306},
+		{Type: ProbeTypeHealthcheck},
+		{Type: ProbeTypeLog, Service: "web", Pattern: `Started WebGoat in \d+`},
+	}
+	for _, probe := range valid {
+		assert.NoError(t, probe.Validate("vt-test"), probe.String())
+	}
+
+	invalid := map[string]Probe{
+		"unknown probe type 'udp'": {Type: "udp", Port: 53},
+		"port must be between":     {Type: ProbeTypeTCP},
+		"path must start with '/'": {Type: ProbeTypeHTTP, Port: 80, Path: "login"},
+		"invalid status 42":        {Type: ProbeTypeHTTP, Port: 80, Status: 42},
+		"pattern can not be empty": {Type: ProbeTypeLog},
+		"error parsing regexp":     {Type: ProbeTypeLog, Pattern: "("},
+	}
+	for message, probe := range invalid {
+		assert.ErrorContains(t, probe.Validate("vt-test"), message)
+	}
+}
+
+func TestProbeString(t *testing.T) {
+	assert.Equal(t, "http probe of web:8080/login", Probe{Type: ProbeTypeHTTP, Service: "web", Port: 8080, Path: "/login"}.String())
+	assert.Equal(t, "tcp probe of any service:3306", Probe{Type: ProbeTypeTCP, Port: 3306}.String())
+	assert.Equal(t, "healthcheck probe of all services", Probe{Type: ProbeTypeHealthcheck}.String())
+	assert.Equal(t, `log probe of any service for "ready"`, Probe{Type: ProbeTypeLog, Pattern: "ready"}.String())
+}
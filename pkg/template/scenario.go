@@ -0,0 +1,256 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ScenariosDir is the directory of a template repository holding scenarios
+// rather than templates.
+const ScenariosDir = "scenarios"
+
+// scenarioNameRegex matches target and network names. Target names end up in
+// instance names, which can not hold '_'.
+var scenarioNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Scenario is an exercise made of several templates started and stopped as
+// one unit, such as a web application, the database behind it and a pivot
+// host. It lives in scenarios/<id>/index.yaml of a template repository.
+type Scenario struct {
+	ID   string       `yaml:"id" json:"id"`
+	Info ScenarioInfo `yaml:"info" json:"info"`
+	// Networks are the networks shared by the targets, in addition to their own.
+	Networks []string         `yaml:"networks" json:"networks"`
+	Targets  []ScenarioTarget `yaml:"targets" json:"targets"`
+
+	// Source is the name of the template source the scenario was loaded from.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+}
+
+// ScenarioInfo contains metadata about a scenario.
+type ScenarioInfo struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description"`
+	Author      string   `yaml:"author" json:"author"`
+	Tags        []string `yaml:"tags" json:"tags"`
+}
+
+// ScenarioTarget is a template deployed as part of a scenario.
+type ScenarioTarget struct {
+	// Name identifies the target within the scenario and names its instance
+	// <scenario instance>-<name>. It defaults to the template ID.
+	Name     string `yaml:"name" json:"name"`
+	Template string `yaml:"template" json:"template"`
+	// Networks are the shared networks of the scenario the services of the target join.
+	Networks []string `yaml:"networks" json:"networks"`
+	// Values are the template variable values of the target.
+	Values map[string]string `yaml:"values" json:"values"`
+	// DependsOn lists the targets that must be ready before the target starts.
+	DependsOn []string `yaml:"depends_on" json:"depends_on"`
+}
+
+// TargetName returns the name of the target, defaulting to its template ID.
+func (t ScenarioTarget) TargetName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Template
+}
+
+// LoadScenario loads a scenario from the index.yaml file of the given directory.
+func LoadScenario(dir string) (Scenario, error) {
+	var scenario Scenario
+	file, err := os.ReadFile(filepath.Join(dir, "index.yaml")) // #nosec G304
+	if err != nil {
+		return scenario, err
+	}
+	if err := yaml.Unmarshal(file, &scenario); err != nil {
+		return scenario, err
+	}
+	return scenario, scenario.Validate()
+}
+
+// LoadScenarios loads the scenarios of all sources. Sources must have been
+// checked out, by loading their templates first. Scenario IDs collide like
+// template IDs: the source with the highest priority wins.
+func LoadScenarios(sources []Source) (map[string]Scenario, error) {
+	scenarios := make(map[string]Scenario)
+	for _, source := range sortSources(sources) {
+		dir := filepath.Join(source.Path, ScenariosDir)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !isTemplateDirectory(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+			if _, exists := scenarios[entry.Name()]; exists {
+				continue
+			}
+
+			scenario, err := LoadScenario(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("error loading scenario %s: %w", entry.Name(), err)
+			}
+			if scenario.ID != entry.Name() {
+				return nil, fmt.Errorf("scenario id '%s' and directory name '%s' should match", scenario.ID, entry.Name())
+			}
+			scenario.Source = source.Name
+			scenarios[scenario.ID] = scenario
+		}
+	}
+	return scenarios, nil
+}
+
+// GetScenarioByID retrieves a specific scenario by its ID.
+func GetScenarioByID(scenarios map[string]Scenario, scenarioID string) (*Scenario, error) {
+	scenario, exists := scenarios[scenarioID]
+	if !exists {
+		return nil, fmt.Errorf("scenario %s not found", scenarioID)
+	}
+	return &scenario, nil
+}
+
+// Validate validates the scenario structure and references between its targets.
+func (s Scenario) Validate() error {
+	if s.ID == "" {
+		return fmt.Errorf("id can not be empty")
+	}
+	if !templateIDRegex.MatchString(s.ID) {
+		return fmt.Errorf("scenario '%s': id contains invalid characters", s.ID)
+	}
+	if s.Info.Name == "" {
+		return fmt.Errorf("scenario '%s': name can not be empty", s.ID)
+	}
+	if len(s.Targets) == 0 {
+		return fmt.Errorf("scenario '%s': targets can not be empty", s.ID)
+	}
+
+	networks := make(map[string]bool, len(s.Networks))
+	for _, network := range s.Networks {
+		if !scenarioNameRegex.MatchString(network) {
+			return fmt.Errorf("scenario '%s': invalid network name '%s'", s.ID, network)
+		}
+		if networks[network] {
+			return fmt.Errorf("scenario '%s': duplicate network '%s'", s.ID, network)
+		}
+		networks[network] = true
+	}
+
+	targets := make(map[string]bool, len(s.Targets))
+	for _, target := range s.Targets {
+		name := target.TargetName()
+		if target.Template == "" {
+			return fmt.Errorf("scenario '%s', target '%s': template can not be empty", s.ID, name)
+		}
+		if !scenarioNameRegex.MatchString(name) {
+			return fmt.Errorf("scenario '%s': invalid target name '%s'", s.ID, name)
+		}
+		if targets[name] {
+			return fmt.Errorf("scenario '%s': duplicate target '%s'", s.ID, name)
+		}
+		targets[name] = true
+
+		for _, network := range target.Networks {
+			if !networks[network] {
+				return fmt.Errorf("scenario '%s', target '%s': unknown network '%s'", s.ID, name, network)
+			}
+		}
+	}
+
+	for _, target := range s.Targets {
+		for _, dependency := range target.DependsOn {
+			if !targets[dependency] {
+				return fmt.Errorf("scenario '%s', target '%s': unknown dependency '%s'", s.ID, target.TargetName(), dependency)
+			}
+		}
+	}
+
+	_, err := s.StartOrder()
+	return err
+}
+
+// StartOrder returns the targets ordered so that every target comes after its
+// dependencies. Targets that do not depend on each other keep their order in
+// the scenario file.
+func (s Scenario) StartOrder() ([]ScenarioTarget, error) {
+	started := make(map[string]bool, len(s.Targets))
+	order := make([]ScenarioTarget, 0, len(s.Targets))
+
+	for len(order) < len(s.Targets) {
+		progress := false
+		for _, target := range s.Targets {
+			if started[target.TargetName()] || !dependenciesStarted(target, started) {
+				continue
+			}
+			started[target.TargetName()] = true
+			order = append(order, target)
+			progress = true
+		}
+
+		if !progress {
+			var cycle []string
+			for _, target := range s.Targets {
+				if !started[target.TargetName()] {
+					cycle = append(cycle, target.TargetName())
+				}
+			}
+			return nil, fmt.Errorf("scenario '%s': dependency cycle between targets %s", s.ID, strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
+
+// dependenciesStarted reports whether every dependency of target is started.
+func dependenciesStarted(target ScenarioTarget, started map[string]bool) bool {
+	for _, dependency := range target.DependsOn {
+		if !started[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
+// Dependencies returns the names of the targets other targets depend on.
+func (s Scenario) Dependencies() map[string]bool {
+	dependencies := make(map[string]bool)
+	for _, target := range s.Targets {
+		for _, dependency := range target.DependsOn {
+			dependencies[dependency] = true
+		}
+	}
+	return dependencies
+}
+
+// ScenarioListTable returns the scenarios as a table sorted by ID.
+func ScenarioListTable(scenarios map[string]Scenario) table.Writer {
+	ids := make([]string, 0, len(scenarios))
+	for id := range scenarios {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"ID", "Name", "Targets", "Tags"})
+	for _, id := range ids {
+		scenario := scenarios[id]
+		targets := make([]string, 0, len(scenario.Targets))
+		for _, target := range scenario.Targets {
+			targets = append(targets, target.Template)
+		}
+		t.AppendRow(table.Row{id, scenario.Info.Name, strings.Join(targets, ", "), strings.Join(scenario.Info.Tags, ", ")})
+	}
+	return t
+}
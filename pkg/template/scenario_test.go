@@ -0,0 +1,121 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScenario = `
+id: sc-pivot
+info:
+  name: Pivot through a web application
+  author: testauthor
+networks:
+  - dmz
+  - internal
+targets:
+  - name: web
+    template: vt-dvwa
+    networks: [dmz, internal]
+    depends_on: [db]
+  - name: pivot
+    template: vt-ssh
+    networks: [internal]
+  - name: db
+    template: vt-mysql
+    networks: [internal]
+    values:
+      MYSQL_VERSION: "5.7"
+`
+
+func writeTestScenario(t *testing.T, repoPath, id, content string) {
+	t.Helper()
+	dir := filepath.Join(repoPath, ScenariosDir, id)
+	require.NoError(t, os.MkdirAll(dir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.yaml"), []byte(content), 0600))
+}
+
+func TestLoadScenarios(t *testing.T) {
+	source := createTestSource(t, "official", 0, "vt-dvwa")
+	writeTestScenario(t, source.Path, "sc-pivot", testScenario)
+
+	templates, _, err := LoadSources([]Source{source})
+	require.NoError(t, err)
+	assert.Len(t, templates, 1, "scenarios are not templates")
+
+	scenarios, err := LoadScenarios([]Source{source})
+	require.NoError(t, err)
+	require.Contains(t, scenarios, "sc-pivot")
+
+	scenario := scenarios["sc-pivot"]
+	assert.Equal(t, "official", scenario.Source)
+	assert.Equal(t, "5.7", scenario.Targets[2].Values["MYSQL_VERSION"])
+
+	writeTestScenario(t, source.Path, "sc-other", testScenario)
+	_, err = LoadScenarios([]Source{source})
+	assert.EqualError(t, err, "scenario id 'sc-pivot' and directory name 'sc-other' should match")
+}
+
+func TestScenarioStartOrder(t *testing.T) {
+	scenario := Scenario{
+		ID: "sc-pivot",
+		Targets: []ScenarioTarget{
+			{Name: "web", Template: "vt-dvwa", DependsOn: []string{"db"}},
+			{Name: "pivot", Template: "vt-ssh"},
+			{Template: "vt-mysql", Name: "db"},
+		},
+	}
+
+	order, err := scenario.StartOrder()
+	require.NoError(t, err)
+	names := make([]string, 0, len(order))
+	for _, target := range order {
+		names = append(names, target.TargetName())
+	}
+	assert.Equal(t, []string{"pivot", "db", "web"}, names)
+	assert.Equal(t, map[string]bool{"db": true}, scenario.Dependencies())
+
+	scenario.Targets[2].DependsOn = []string{"web"}
+	_, err = scenario.StartOrder()
+	assert.EqualError(t, err, "scenario 'sc-pivot': dependency cycle between targets web, db")
+}
+
+func TestScenarioValidate(t *testing.T) {
+	valid := func() Scenario {
+		return Scenario{
+			ID:       "sc-pivot",
+			Info:     ScenarioInfo{Name: "Pivot"},
+			Networks: []string{"internal"},
+			Targets: []ScenarioTarget{
+				{Template: "vt-dvwa", Networks: []string{"internal"}},
+				{Name: "db", Template: "vt-mysql", Networks: []string{"internal"}},
+			},
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	scenario := valid()
+	scenario.Targets[1].Name = "vt-dvwa"
+	assert.EqualError(t, scenario.Validate(), "scenario 'sc-pivot': duplicate target 'vt-dvwa'")
+
+	scenario = valid()
+	scenario.Targets[1].Name = "db_1"
+	assert.EqualError(t, scenario.Validate(), "scenario 'sc-pivot': invalid target name 'db_1'")
+
+	scenario = valid()
+	scenario.Targets[1].Networks = []string{"dmz"}
+	assert.EqualError(t, scenario.Validate(), "scenario 'sc-pivot', target 'db': unknown network 'dmz'")
+
+	scenario = valid()
+	scenario.Targets[0].DependsOn = []string{"cache"}
+	assert.EqualError(t, scenario.Validate(), "scenario 'sc-pivot', target 'vt-dvwa': unknown dependency 'cache'")
+
+	scenario = valid()
+	scenario.Targets[0].Template = ""
+	scenario.Targets[0].Name = "web"
+	assert.EqualError(t, scenario.Validate(), "scenario 'sc-pivot', target 'web': template can not be empty")
+}
@@ -0,0 +1,226 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// Source is a template repository. Git sources are cloned from URL into Path,
+// local sources are read from Path as is.
+type Source struct {
+	Name     string `json:"name" yaml:"name"`
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`
+	Path     string `json:"path" yaml:"path"`
+	Priority int    `json:"priority" yaml:"priority"`
+	// Ref is the branch, tag or commit of a git source, empty for the default branch.
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// Commit is the locked commit a git source is checked out at, empty when not locked.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+// IsLocal reports whether the source is a local directory rather than a git repository.
+func (s Source) IsLocal() bool {
+	return s.URL == ""
+}
+
+// Location returns the URL of a git source or the directory of a local source.
+func (s Source) Location() string {
+	if s.IsLocal() {
+		return s.Path
+	}
+	return s.URL
+}
+
+// Collision reports a template ID provided by more than one source.
+type Collision struct {
+	ID       string   `json:"id" yaml:"id"`
+	Winner   string   `json:"winner" yaml:"winner"`
+	Shadowed []string `json:"shadowed" yaml:"shadowed"`
+}
+
+// String describes the collision and the source that won it.
+func (c Collision) String() string {
+	return fmt.Sprintf("template %q from %s shadows the one from %s", c.ID, c.Winner, strings.Join(c.Shadowed, ", "))
+}
+
+// LoadSources loads the templates of all sources, cloning git sources that are
+// not present yet, and merges them into a single map. When several sources
+// provide the same template ID, the source with the highest priority wins and
+// sources with equal priority keep their configured order. A source failing to
+// load is skipped with a warning; an error is returned only if none could be loaded.
+func LoadSources(sources []Source) (map[string]Template, []Collision, error) {
+	ordered := sortSources(sources)
+
+	templates := make(map[string]Template)
+	collisions := make(map[string]*Collision)
+	var errs []error
+
+	for _, source := range ordered {
+		sourceTemplates, err := loadSource(source)
+		if err != nil {
+			log.Warn().Err(err).Msgf("skipping template source %s", source.Name)
+			errs = append(errs, fmt.Errorf("source %s: %w", source.Name, err))
+			continue
+		}
+
+		for id, tmpl := range sourceTemplates {
+			existing, exists := templates[id]
+			if !exists {
+				templates[id] = tmpl
+				continue
+			}
+
+			collision, ok := collisions[id]
+			if !ok {
+				collision = &Collision{ID: id, Winner: existing.Source}
+				collisions[id] = collision
+			}
+			collision.Shadowed = append(collision.Shadowed, source.Name)
+		}
+	}
+
+	if len(ordered) > 0 && len(errs) == len(ordered) {
+		return nil, nil, fmt.Errorf("failed to load templates: %w", errors.Join(errs...))
+	}
+
+	result := make([]Collision, 0, len(collisions))
+	for _, collision := range collisions {
+		log.Debug().Msg(collision.String())
+		result = append(result, *collision)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return templates, result, nil
+}
+
+// Update describes how the templates of a git source changed when it was updated.
+// From is empty when the source was cloned.
+type Update struct {
+	Source   string   `json:"source" yaml:"source"`
+	From     string   `json:"from" yaml:"from"`
+	To       string   `json:"to" yaml:"to"`
+	Added    []string `json:"added" yaml:"added"`
+	Removed  []string `json:"removed" yaml:"removed"`
+	Modified []string `json:"modified" yaml:"modified"`
+}
+
+// Changed reports whether the update added, removed or modified any template.
+func (u Update) Changed() bool {
+	return len(u.Added)+len(u.Removed)+len(u.Modified) > 0
+}
+
+// UpdateSources checks out the latest commit of the ref of every git source,
+// discarding local changes and ignoring locked commits, and reports the
+// templates each update changed. Local sources are left untouched.
+func UpdateSources(sources []Source) ([]Update, error) {
+	var updates []Update
+	var errs []error
+	for _, source := range sources {
+		if source.IsLocal() {
+			continue
+		}
+		update, err := updateSource(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source %s: %w", source.Name, err))
+			continue
+		}
+		updates = append(updates, update)
+	}
+	return updates, errors.Join(errs...)
+}
+
+// updateSource checks out the latest commit of a git source and compares its
+// templates with the previous checkout.
+func updateSource(source Source) (Update, error) {
+	log.Info().Msgf("fetching %s", source.Location())
+	previous, current, err := checkoutTemplatesRepo(source.Path, source.URL, source.Ref, "", true)
+	if err != nil {
+		return Update{}, fmt.Errorf("failed to sync templates: %w", err)
+	}
+
+	update := Update{Source: source.Name, To: current.String(), Added: []string{}, Removed: []string{}, Modified: []string{}}
+	if !previous.IsZero() {
+		update.From = previous.String()
+	}
+	if previous == current {
+		return update, nil
+	}
+
+	repo, err := git.PlainOpen(source.Path)
+	if err != nil {
+		return update, err
+	}
+	added, removed, modified, err := diffTemplates(repo, previous, current)
+	if err != nil {
+		return update, err
+	}
+	update.Added = append(update.Added, added...)
+	update.Removed = append(update.Removed, removed...)
+	update.Modified = append(update.Modified, modified...)
+	return update, nil
+}
+
+// sortSources returns the sources ordered by descending priority, keeping the
+// given order for equal priorities.
+func sortSources(sources []Source) []Source {
+	ordered := make([]Source, len(sources))
+	copy(ordered, sources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	return ordered
+}
+
+// loadSource loads the templates of a single source and records their origin.
+func loadSource(source Source) (map[string]Template, error) {
+	var templates map[string]Template
+	var err error
+	if source.IsLocal() {
+		if _, statErr := os.Stat(source.Path); statErr != nil {
+			return nil, fmt.Errorf("template directory not found: %w", statErr)
+		}
+		templates, err = loadTemplatesFromDirectory(source.Path)
+	} else {
+		templates, err = loadGitSource(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for id, tmpl := range templates {
+		tmpl.Source = source.Name
+		tmpl.RepoPath = source.Path
+		templates[id] = tmpl
+	}
+	return templates, nil
+}
+
+// loadGitSource loads the templates of a git source, cloning it when it is not
+// present yet. A locked source is checked out at its locked commit first, an
+// unlocked clone is used as is.
+func loadGitSource(source Source) (map[string]Template, error) {
+	_, statErr := os.Stat(source.Path)
+	missing := os.IsNotExist(statErr)
+	if missing {
+		log.Info().Msgf("Fetching templates of %s for the first time", source.Name)
+	}
+
+	if missing || source.Commit != "" {
+		if _, _, err := checkoutTemplatesRepo(source.Path, source.URL, source.Ref, source.Commit, false); err != nil {
+			if missing {
+				return nil, fmt.Errorf("failed to clone templates repository: %w", err)
+			}
+			log.Warn().Err(err).Msgf("failed to check out the locked commit of %s, using the current checkout", source.Name)
+		}
+	}
+
+	return loadTemplatesFromDirectory(source.Path)
+}
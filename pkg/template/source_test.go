@@ -0,0 +1,58 @@
+package template
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestSource creates a local source with the given templates in a single category.
+func createTestSource(t *testing.T, name string, priority int, templateIDs ...string) Source {
+	t.Helper()
+	path := t.TempDir()
+	for _, id := range templateIDs {
+		createTestTemplate(t, filepath.Join(path, "web"), id)
+	}
+	return Source{Name: name, Path: path, Priority: priority}
+}
+
+func TestLoadSourcesPrecedence(t *testing.T) {
+	official := createTestSource(t, "official", 0, "vt-dvwa", "vt-juice-shop")
+	internal := createTestSource(t, "internal", 10, "vt-dvwa", "vt-internal")
+	mirror := createTestSource(t, "mirror", 0, "vt-juice-shop")
+
+	templates, collisions, err := LoadSources([]Source{official, internal, mirror})
+	require.NoError(t, err)
+	assert.Len(t, templates, 3)
+
+	// the higher priority wins, equal priorities keep the configured order
+	assert.Equal(t, "internal", templates["vt-dvwa"].Source)
+	assert.Equal(t, internal.Path, templates["vt-dvwa"].RepoPath)
+	assert.Equal(t, "official", templates["vt-juice-shop"].Source)
+	assert.Equal(t, "internal", templates["vt-internal"].Source)
+
+	assert.Equal(t, []Collision{
+		{ID: "vt-dvwa", Winner: "internal", Shadowed: []string{"official"}},
+		{ID: "vt-juice-shop", Winner: "official", Shadowed: []string{"mirror"}},
+	}, collisions)
+	assert.Equal(t, `template "vt-dvwa" from internal shadows the one from official`, collisions[0].String())
+}
+
+func TestLoadSourcesSkipsBrokenSource(t *testing.T) {
+	official := createTestSource(t, "official", 0, "vt-dvwa")
+	missing := Source{Name: "missing", Path: filepath.Join(t.TempDir(), "missing")}
+
+	templates, _, err := LoadSources([]Source{official, missing})
+	require.NoError(t, err)
+	assert.Contains(t, templates, "vt-dvwa")
+
+	_, _, err = LoadSources([]Source{missing})
+	assert.Error(t, err, "loading must fail when no source can be loaded")
+}
+
+func TestTemplateRepositoryPath(t *testing.T) {
+	assert.Equal(t, "/srv/templates", Template{}.RepositoryPath("/srv/templates"))
+	assert.Equal(t, "/srv/internal", Template{RepoPath: "/srv/internal"}.RepositoryPath("/srv/templates"))
+}
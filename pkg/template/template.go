@@ -4,53 +4,70 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/rs/zerolog/log"
 )
 
-// TemplateRemoteRepository is a constant for repo url.
+// TemplateRemoteRepository is the url of the official template repository.
 const TemplateRemoteRepository string = "https://github.com/HappyHackingSpace/vt-templates"
 
 // Template represents a vulnerable target environment configuration.
 type Template struct {
-	ID             string                    `yaml:"id"`
-	Info           Info                      `yaml:"info"`
-	ProofOfConcept map[string][]string       `yaml:"poc"`
-	Remediation    []string                  `yaml:"remediation"`
-	Providers      map[string]ProviderConfig `yaml:"providers"`
-	PostInstall    []string                  `yaml:"post-install"`
+	ID             string                    `yaml:"id" json:"id"`
+	Info           Info                      `yaml:"info" json:"info"`
+	ProofOfConcept map[string][]string       `yaml:"poc" json:"poc"`
+	Remediation    []string                  `yaml:"remediation" json:"remediation"`
+	Providers      map[string]ProviderConfig `yaml:"providers" json:"providers"`
+	PostInstall    []string                  `yaml:"post-install" json:"post-install"`
+	Variables      []Variable                `yaml:"variables" json:"variables"`
+	Readiness      []Probe                   `yaml:"readiness" json:"readiness"`
+	Flags          []Flag                    `yaml:"flags" json:"flags"`
+	Verify         []VerifyStep              `yaml:"verify" json:"verify"`
+
+	// Source is the name of the template source the template was loaded from.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+	// RepoPath is the local directory of that source.
+	RepoPath string `yaml:"-" json:"-"`
+	// Dir is the directory holding the index.yaml of the template.
+	Dir string `yaml:"-" json:"-"`
 }
 
 // Info contains metadata about a template.
 type Info struct {
-	Name             string   `yaml:"name"`
-	Description      string   `yaml:"description"`
-	Author           string   `yaml:"author"`
-	Targets          []string `yaml:"targets"`
-	Type             string   `yaml:"type"`
-	AffectedVersions []string `yaml:"affected_versions"`
-	FixedVersion     string   `yaml:"fixed_version"`
-	Cwe              string   `yaml:"cwe"`
-	Cvss             Cvss     `yaml:"cvss"`
-	Tags             []string `yaml:"tags"`
-	References       []string `yaml:"references"`
+	Name             string   `yaml:"name" json:"name"`
+	Description      string   `yaml:"description" json:"description"`
+	Author           string   `yaml:"author" json:"author"`
+	Targets          []string `yaml:"targets" json:"targets"`
+	Type             string   `yaml:"type" json:"type"`
+	AffectedVersions []string `yaml:"affected_versions" json:"affected_versions"`
+	FixedVersion     string   `yaml:"fixed_version" json:"fixed_version"`
+	Cwe              string   `yaml:"cwe" json:"cwe"`
+	Cvss             Cvss     `yaml:"cvss" json:"cvss"`
+	Tags             []string `yaml:"tags" json:"tags"`
+	References       []string `yaml:"references" json:"references"`
 }
 
 // ProviderConfig contains configuration for a specific provider.
 type ProviderConfig struct {
-	Path string `yaml:"path"`
+	Path string `yaml:"path" json:"path"`
 }
 
 // Cvss represents Common Vulnerability Scoring System information.
 type Cvss struct {
-	Score   string `yaml:"score"`
-	Metrics string `yaml:"metrics"`
+	Score   string `yaml:"score" json:"score"`
+	Metrics string `yaml:"metrics" json:"metrics"`
 }
 
 // String returns template fields as a table
 func (t Template) String() string {
+	return t.Table().Render()
+}
+
+// Table returns template fields as a two column table
+func (t Template) Table() table.Writer {
 	tw := table.NewWriter()
 	tw.AppendRow(table.Row{"ID", t.ID})
 	tw.AppendRow(table.Row{"Name", t.Info.Name})
@@ -69,12 +86,17 @@ func (t Template) String() string {
 	tw.AppendRow(table.Row{"Remediation", formatList(t.Remediation)})
 	tw.AppendRow(table.Row{"Providers", formatProviders(t.Providers)})
 	tw.AppendRow(table.Row{"Post Install", formatList(t.PostInstall)})
+	tw.AppendRow(table.Row{"Variables", formatVariables(t.Variables)})
+	tw.AppendRow(table.Row{"Readiness", formatProbes(t.Readiness)})
+	tw.AppendRow(table.Row{"Flags", formatFlags(t.Flags)})
+	tw.AppendRow(table.Row{"Verify", formatVerifySteps(t.Verify)})
+	tw.AppendRow(table.Row{"Source", t.Source})
 
 	tw.Style().Options.DrawBorder = true
 	tw.Style().Options.SeparateRows = true
 	tw.Style().Options.SeparateColumns = true
 
-	return tw.Render()
+	return tw
 }
 
 func formatPoc(poc map[string][]string) string {
@@ -99,6 +121,55 @@ func formatProviders(providers map[string]ProviderConfig) string {
 	return strings.Join(names, "\n")
 }
 
+func formatVariables(variables []Variable) string {
+	parts := make([]string, 0, len(variables))
+	for _, variable := range variables {
+		part := variable.Name
+		switch {
+		case variable.Required:
+			part += " (required)"
+		case variable.Default != "":
+			part += fmt.Sprintf(" (default %s)", variable.Default)
+		}
+		if len(variable.Allowed) > 0 {
+			part += ": " + strings.Join(variable.Allowed, ", ")
+		}
+		if variable.Description != "" {
+			part += " - " + variable.Description
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatProbes(probes []Probe) string {
+	parts := make([]string, 0, len(probes))
+	for _, probe := range probes {
+		parts = append(parts, probe.String())
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatFlags(flags []Flag) string {
+	parts := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		part := flag.Name
+		if flag.Description != "" {
+			part += " - " + flag.Description
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatVerifySteps(steps []VerifyStep) string {
+	parts := make([]string, 0, len(steps))
+	for _, step := range steps {
+		parts = append(parts, fmt.Sprintf("%s (%s)", step.Name, step.Type))
+	}
+	return strings.Join(parts, "\n")
+}
+
 func formatList(items []string) string {
 	if len(items) == 0 {
 		return ""
@@ -107,12 +178,12 @@ func formatList(items []string) string {
 }
 
 // LoadTemplates loads all templates from the given repository path.
-// If the repository doesn't exist, it clones it first.
+// If the repository doesn't exist, it clones it from repoURL first.
 // Returns a map of templates indexed by their ID.
-func LoadTemplates(repoPath string) (map[string]Template, error) {
+func LoadTemplates(repoPath, repoURL string) (map[string]Template, error) {
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 		log.Info().Msg("Fetching templates for the first time")
-		if err := cloneTemplatesRepo(repoPath, false); err != nil {
+		if _, _, err := checkoutTemplatesRepo(repoPath, repoURL, "", "", false); err != nil {
 			return nil, fmt.Errorf("failed to clone templates repository: %w", err)
 		}
 	}
@@ -120,8 +191,8 @@ func LoadTemplates(repoPath string) (map[string]Template, error) {
 	return loadTemplatesFromDirectory(repoPath)
 }
 
-// loadTemplatesFromDirectory reads all templates from the given path.
-// Returns a map of templates indexed by their ID.
+// loadTemplatesFromDirectory reads all templates from the given path, skipping
+// the scenarios directory. Returns a map of templates indexed by their ID.
 func loadTemplatesFromDirectory(repoPath string) (map[string]Template, error) {
 	templates := make(map[string]Template)
 
@@ -131,7 +202,7 @@ func loadTemplatesFromDirectory(repoPath string) (map[string]Template, error) {
 	}
 
 	for _, categoryEntry := range dirEntry {
-		if strings.HasPrefix(categoryEntry.Name(), ".") || !categoryEntry.IsDir() {
+		if strings.HasPrefix(categoryEntry.Name(), ".") || !categoryEntry.IsDir() || categoryEntry.Name() == ScenariosDir {
 			continue
 		}
 
@@ -227,9 +298,9 @@ func isTemplateDirectory(dirPath string) bool {
 }
 
 // SyncTemplates downloads or updates all templates from the remote repository.
-func SyncTemplates(repoPath string) error {
-	log.Info().Msgf("cloning %s", TemplateRemoteRepository)
-	if err := cloneTemplatesRepo(repoPath, true); err != nil {
+func SyncTemplates(repoPath, repoURL string) error {
+	log.Info().Msgf("cloning %s", repoURL)
+	if _, _, err := checkoutTemplatesRepo(repoPath, repoURL, "", "", true); err != nil {
 		return fmt.Errorf("failed to sync templates: %w", err)
 	}
 	return nil
@@ -242,55 +313,93 @@ func ListTemplates(templates map[string]Template) {
 
 // ListTemplatesWithFilter displays templates in a table format, optionally filtered by tag.
 func ListTemplatesWithFilter(templates map[string]Template, filterTag string) {
-	t := table.NewWriter()
+	matched := ListWithFilter(templates, filterTag)
+	if len(matched) == 0 {
+		if filterTag != "" {
+			fmt.Printf("No templates found with tag matching '%s'\n", filterTag)
+		} else {
+			fmt.Println("No templates found")
+		}
+		return
+	}
+
+	t := ListTable(matched)
 	t.SetStyle(table.StyleDefault)
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Name", "Author", "Targets", "Type", "Tags"})
+	if filterTag != "" {
+		t.SetCaption("Found %d templates with tag matching '%s'", len(matched), filterTag)
+	} else {
+		t.SetCaption("there are %d templates", len(matched))
+	}
+	t.SetIndexColumn(0)
+	t.Render()
+}
 
-	count := 0
+// ListWithFilter returns the templates having a tag matching filterTag, or all
+// templates when filterTag is empty, sorted by ID.
+func ListWithFilter(templates map[string]Template, filterTag string) []Template {
+	matched := make([]Template, 0, len(templates))
 	for _, tmpl := range templates {
-		if filterTag != "" {
-			hasTag := false
-			for _, tag := range tmpl.Info.Tags {
-				if strings.EqualFold(tag, filterTag) || strings.Contains(strings.ToLower(tag), strings.ToLower(filterTag)) {
-					hasTag = true
-					break
-				}
-			}
-			if !hasTag {
-				continue
-			}
+		if filterTag != "" && !tmpl.HasTag(filterTag) {
+			continue
 		}
+		matched = append(matched, tmpl)
+	}
 
-		tags := strings.Join(tmpl.Info.Tags, ", ")
-		targets := strings.Join(tmpl.Info.Targets, ", ")
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+
+	return matched
+}
+
+// ListTable returns a table with a summary row for each template.
+func ListTable(templates []Template) table.Writer {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"ID", "Name", "Author", "Targets", "Type", "Tags"})
+
+	for _, tmpl := range templates {
 		t.AppendRow(table.Row{
 			tmpl.ID,
 			tmpl.Info.Name,
 			tmpl.Info.Author,
-			targets,
+			strings.Join(tmpl.Info.Targets, ", "),
 			tmpl.Info.Type,
-			tags,
+			strings.Join(tmpl.Info.Tags, ", "),
 		})
-		count++
 	}
 
-	if count == 0 {
-		if filterTag != "" {
-			fmt.Printf("No templates found with tag matching '%s'\n", filterTag)
-		} else {
-			fmt.Println("No templates found")
+	return t
+}
+
+// HasTag reports whether any of the template tags matches filterTag.
+// Matching is case-insensitive and accepts partial tag matches.
+func (t Template) HasTag(filterTag string) bool {
+	for _, tag := range t.Info.Tags {
+		if strings.EqualFold(tag, filterTag) || strings.Contains(strings.ToLower(tag), strings.ToLower(filterTag)) {
+			return true
 		}
-		return
 	}
+	return false
+}
 
-	if filterTag != "" {
-		t.SetCaption("Found %d templates with tag matching '%s'", count, filterTag)
-	} else {
-		t.SetCaption("there are %d templates", count)
+// FilterByTags returns the templates matching at least one of the given tags, sorted by ID.
+func FilterByTags(templates map[string]Template, tags []string) []Template {
+	var matched []Template
+	for _, tmpl := range templates {
+		for _, tag := range tags {
+			if tag != "" && tmpl.HasTag(tag) {
+				matched = append(matched, tmpl)
+				break
+			}
+		}
 	}
-	t.SetIndexColumn(0)
-	t.Render()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+
+	return matched
 }
 
 // GetByID retrieves a template by its ID from the given templates map.
@@ -302,10 +411,41 @@ func GetByID(templates map[string]Template, templateID string) (*Template, error
 	return &tmpl, nil
 }
 
+// RepositoryPath returns the directory of the source the template was loaded
+// from, or fallback when the template does not record its source.
+func (t Template) RepositoryPath(fallback string) string {
+	if t.RepoPath == "" {
+		return fallback
+	}
+	return t.RepoPath
+}
+
 // GetDockerComposePath finds and returns the docker-compose file path for a given template ID.
 // It searches through all category directories in the templates repository to locate the template.
 // Returns the absolute path to the compose file and the working directory.
 func GetDockerComposePath(templateID, repoPath string) (composePath string, workingDir string, err error) {
+	return GetComposePath(templateID, repoPath, "docker-compose")
+}
+
+// GetComposePath finds and returns the compose file path configured for providerName
+// in the given template. Templates without a dedicated entry for providerName fall back
+// to their docker-compose configuration, as compose-compatible providers share the file.
+// Returns the absolute path to the compose file and the working directory.
+func GetComposePath(templateID, repoPath, providerName string) (composePath string, workingDir string, err error) {
+	return findProviderPath(templateID, repoPath, providerName, "docker-compose")
+}
+
+// GetProviderPath finds and returns the file path configured for providerName in the given template.
+// Returns the absolute path to the provider file and its directory.
+func GetProviderPath(templateID, repoPath, providerName string) (providerPath string, workingDir string, err error) {
+	return findProviderPath(templateID, repoPath, providerName)
+}
+
+// findProviderPath locates the template and returns the path of the first configured
+// provider entry among providerNames, along with its directory.
+func findProviderPath(templateID, repoPath string, providerNames ...string) (composePath string, workingDir string, err error) {
+	providerName := providerNames[0]
+
 	// Search for template in all category directories
 	dirEntries, err := os.ReadDir(repoPath)
 	if err != nil {
@@ -345,13 +485,21 @@ func GetDockerComposePath(templateID, repoPath string) (composePath string, work
 			continue
 		}
 
-		// Get docker-compose provider config
-		providerConfig, exists := tmpl.Providers["docker-compose"]
+		// Get the first configured provider entry
+		var configName string
+		var providerConfig ProviderConfig
+		exists := false
+		for _, name := range providerNames {
+			if providerConfig, exists = tmpl.Providers[name]; exists {
+				configName = name
+				break
+			}
+		}
 		if !exists {
-			return "", "", fmt.Errorf("template %q missing docker-compose provider configuration", templateID)
+			return "", "", fmt.Errorf("template %q missing %s provider configuration", templateID, providerName)
 		}
 		if providerConfig.Path == "" {
-			return "", "", fmt.Errorf("template %q docker-compose.path is empty", templateID)
+			return "", "", fmt.Errorf("template %q %s.path is empty", templateID, configName)
 		}
 
 		// Construct the compose file path
@@ -361,13 +509,13 @@ func GetDockerComposePath(templateID, repoPath string) (composePath string, work
 
 		composePath = filepath.Join(templateDir, providerConfig.Path)
 		if _, statErr := os.Stat(composePath); statErr != nil {
-			return "", "", fmt.Errorf("template %q has invalid docker-compose path %q: %w", templateID, composePath, statErr)
+			return "", "", fmt.Errorf("template %q has invalid %s path %q: %w", templateID, configName, composePath, statErr)
 		}
 
 		return composePath, filepath.Dir(composePath), nil
 	}
 
-	return "", "", fmt.Errorf("docker-compose file for template %q not found", templateID)
+	return "", "", fmt.Errorf("%s file for template %q not found", providerName, templateID)
 }
 
 // findTemplateInCategory recursively searches for a template directory within a category.
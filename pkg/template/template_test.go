@@ -235,3 +235,24 @@ func TestLoadTemplatesFromDirectoryWithNestedCategories(t *testing.T) {
 	assert.Contains(t, templates, "template-b")
 	assert.Contains(t, templates, "template-c")
 }
+
+func TestFilterByTags(t *testing.T) {
+	templates := map[string]Template{
+		"vt-b": {ID: "vt-b", Info: Info{Tags: []string{"sqli", "web"}}},
+		"vt-a": {ID: "vt-a", Info: Info{Tags: []string{"SQLi-blind"}}},
+		"vt-c": {ID: "vt-c", Info: Info{Tags: []string{"xss"}}},
+	}
+
+	matched := FilterByTags(templates, []string{"sqli"})
+	assert.Len(t, matched, 2)
+	assert.Equal(t, "vt-a", matched[0].ID)
+	assert.Equal(t, "vt-b", matched[1].ID)
+
+	matched = FilterByTags(templates, []string{"xss", "web"})
+	assert.Len(t, matched, 2)
+	assert.Equal(t, "vt-b", matched[0].ID)
+	assert.Equal(t, "vt-c", matched[1].ID)
+
+	assert.Empty(t, FilterByTags(templates, []string{"ssrf"}))
+	assert.Empty(t, FilterByTags(templates, []string{""}))
+}
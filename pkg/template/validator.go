@@ -36,6 +36,53 @@ func (template Template) Validate() error {
 		}
 	}
 
+	names := make(map[string]bool, len(template.Variables))
+	for _, variable := range template.Variables {
+		if err := variable.Validate(template.ID); err != nil {
+			return err
+		}
+		if names[variable.Name] {
+			return fmt.Errorf("template '%s': duplicate variable '%s'", template.ID, variable.Name)
+		}
+		names[variable.Name] = true
+	}
+
+	flagNames := make(map[string]bool, len(template.Flags))
+	flagEnvs := make(map[string]bool, len(template.Flags))
+	for _, flag := range template.Flags {
+		if err := flag.Validate(template.ID); err != nil {
+			return err
+		}
+		if flagNames[flag.Name] {
+			return fmt.Errorf("template '%s': duplicate flag '%s'", template.ID, flag.Name)
+		}
+		flagNames[flag.Name] = true
+		if flag.Env == "" {
+			continue
+		}
+		if names[flag.Env] || flagEnvs[flag.Env] {
+			return fmt.Errorf("template '%s', flag '%s': env '%s' is already used", template.ID, flag.Name, flag.Env)
+		}
+		flagEnvs[flag.Env] = true
+	}
+
+	stepNames := make(map[string]bool, len(template.Verify))
+	for _, step := range template.Verify {
+		if err := step.Validate(template.ID); err != nil {
+			return err
+		}
+		if stepNames[step.Name] {
+			return fmt.Errorf("template '%s': duplicate verify step '%s'", template.ID, step.Name)
+		}
+		stepNames[step.Name] = true
+	}
+
+	for _, probe := range template.Readiness {
+		if err := probe.Validate(template.ID); err != nil {
+			return err
+		}
+	}
+
 	infoError := template.Info.Validate(template.ID)
 	if infoError != nil {
 		return infoError
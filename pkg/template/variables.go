@@ -0,0 +1,138 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variable types accepted in the variables section of a template.
+const (
+	VariableTypeString = "string"
+	VariableTypeInt    = "int"
+	VariableTypeBool   = "bool"
+)
+
+var variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Variable is a template parameter, injected into the compose interpolation
+// environment or substituted for ${NAME} in Kubernetes manifests.
+type Variable struct {
+	Name        string   `yaml:"name" json:"name"`
+	Type        string   `yaml:"type" json:"type"`
+	Default     string   `yaml:"default" json:"default"`
+	Allowed     []string `yaml:"allowed" json:"allowed"`
+	Description string   `yaml:"description" json:"description"`
+	// Required variables have no default and must be set when starting.
+	Required bool `yaml:"required" json:"required"`
+}
+
+// Validate validates the variable declaration.
+func (v Variable) Validate(templateID string) error {
+	if !variableNameRegex.MatchString(v.Name) {
+		return fmt.Errorf("template '%s': invalid variable name '%s'", templateID, v.Name)
+	}
+
+	switch v.Type {
+	case "", VariableTypeString, VariableTypeInt, VariableTypeBool:
+	default:
+		return fmt.Errorf("template '%s', variable '%s': unknown type '%s', must be one of: string, int, bool", templateID, v.Name, v.Type)
+	}
+
+	for _, allowed := range v.Allowed {
+		if err := v.checkType(allowed); err != nil {
+			return fmt.Errorf("template '%s', variable '%s': allowed value %w", templateID, v.Name, err)
+		}
+	}
+
+	if v.Required {
+		if v.Default != "" {
+			return fmt.Errorf("template '%s', variable '%s': a required variable can not have a default", templateID, v.Name)
+		}
+		return nil
+	}
+
+	if err := v.Check(v.Default); err != nil {
+		return fmt.Errorf("template '%s', variable '%s': default %w", templateID, v.Name, err)
+	}
+	return nil
+}
+
+// Check reports whether value is valid for the variable.
+func (v Variable) Check(value string) error {
+	if err := v.checkType(value); err != nil {
+		return err
+	}
+	if len(v.Allowed) > 0 && !slices.Contains(v.Allowed, value) {
+		return fmt.Errorf("%q is not one of: %s", value, strings.Join(v.Allowed, ", "))
+	}
+	return nil
+}
+
+// checkType reports whether value can be parsed as the type of the variable.
+func (v Variable) checkType(value string) error {
+	var err error
+	switch v.Type {
+	case VariableTypeInt:
+		_, err = strconv.Atoi(value)
+	case VariableTypeBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%q is not a valid %s", value, v.Type)
+	}
+	return nil
+}
+
+// ResolveVariables validates values against the variables of the template and
+// returns the value of every variable, defaults filling the ones not set.
+func (t Template) ResolveVariables(values map[string]string) (map[string]string, error) {
+	known := make(map[string]bool, len(t.Variables))
+	for _, variable := range t.Variables {
+		known[variable.Name] = true
+	}
+
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("template %s has no variable %s", t.ID, strings.Join(unknown, ", "))
+	}
+
+	resolved := make(map[string]string, len(t.Variables))
+	for _, variable := range t.Variables {
+		value, ok := values[variable.Name]
+		if !ok {
+			if variable.Required {
+				return nil, fmt.Errorf("variable %s of template %s is required", variable.Name, t.ID)
+			}
+			value = variable.Default
+		}
+		if err := variable.Check(value); err != nil {
+			return nil, fmt.Errorf("invalid value of variable %s: %w", variable.Name, err)
+		}
+		resolved[variable.Name] = value
+	}
+	return resolved, nil
+}
+
+// VariableValues returns the default of every variable overridden by values,
+// without validation. It is used for deployments started with values that
+// were validated at the time.
+func (t Template) VariableValues(values map[string]string) map[string]string {
+	resolved := make(map[string]string, len(t.Variables)+len(values))
+	for _, variable := range t.Variables {
+		resolved[variable.Name] = variable.Default
+	}
+	for name, value := range values {
+		resolved[name] = value
+	}
+	return resolved
+}
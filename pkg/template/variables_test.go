@@ -0,0 +1,72 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableValidate(t *testing.T) {
+	valid := []Variable{
+		{Name: "DB_VERSION", Default: "10"},
+		{Name: "PORT", Type: VariableTypeInt, Default: "8080"},
+		{Name: "DEBUG", Type: VariableTypeBool, Default: "false"},
+		{Name: "LEVEL", Default: "low", Allowed: []string{"low", "high"}},
+		{Name: "FLAG", Required: true},
+	}
+	for _, variable := range valid {
+		assert.NoError(t, variable.Validate("vt-test"), variable.Name)
+	}
+
+	invalid := map[string]Variable{
+		"invalid variable name":    {Name: "1PORT"},
+		"unknown type":             {Name: "PORT", Type: "float"},
+		"allowed value \"x\"":      {Name: "PORT", Type: VariableTypeInt, Default: "1", Allowed: []string{"1", "x"}},
+		"can not have a default":   {Name: "FLAG", Default: "x", Required: true},
+		"default \"yes please\"":   {Name: "DEBUG", Type: VariableTypeBool, Default: "yes please"},
+		"\"medium\" is not one of": {Name: "LEVEL", Default: "medium", Allowed: []string{"low", "high"}},
+	}
+	for message, variable := range invalid {
+		assert.ErrorContains(t, variable.Validate("vt-test"), message)
+	}
+}
+
+func TestValidateRejectsDuplicateVariables(t *testing.T) {
+	template := Template{
+		ID:        "vt-test",
+		Info:      Info{Name: "Test", Author: "vt", Type: "Lab", Targets: []string{"php"}, Tags: []string{"web"}},
+		Providers: map[string]ProviderConfig{"docker-compose": {Path: "docker-compose.yaml"}},
+	}
+	template.Variables = []Variable{{Name: "PORT"}, {Name: "PORT"}}
+	assert.ErrorContains(t, template.Validate(), "duplicate variable 'PORT'")
+}
+
+func TestResolveVariables(t *testing.T) {
+	template := Template{
+		ID: "vt-test",
+		Variables: []Variable{
+			{Name: "PORT", Type: VariableTypeInt, Default: "8080"},
+			{Name: "LEVEL", Default: "low", Allowed: []string{"low", "high"}},
+			{Name: "FLAG", Required: true},
+		},
+	}
+
+	values, err := template.ResolveVariables(map[string]string{"FLAG": "vt{x}", "LEVEL": "high"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PORT": "8080", "LEVEL": "high", "FLAG": "vt{x}"}, values)
+
+	_, err = template.ResolveVariables(nil)
+	assert.EqualError(t, err, "variable FLAG of template vt-test is required")
+
+	_, err = template.ResolveVariables(map[string]string{"FLAG": "x", "PORTS": "1", "DEBUG": "1"})
+	assert.EqualError(t, err, "template vt-test has no variable DEBUG, PORTS")
+
+	_, err = template.ResolveVariables(map[string]string{"FLAG": "x", "PORT": "http"})
+	assert.EqualError(t, err, `invalid value of variable PORT: "http" is not a valid int`)
+}
+
+func TestVariableValues(t *testing.T) {
+	template := Template{Variables: []Variable{{Name: "PORT", Default: "8080"}, {Name: "LEVEL", Default: "low"}}}
+	assert.Equal(t, map[string]string{"PORT": "8080", "LEVEL": "high"}, template.VariableValues(map[string]string{"LEVEL": "high"}))
+}
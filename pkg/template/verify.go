@@ -0,0 +1,90 @@
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Verification step types.
+const (
+	// VerifyTypeHTTP sends an HTTP request to a published port of the deployment.
+	VerifyTypeHTTP = "http"
+	// VerifyTypeScript runs a shell script in a sidecar container next to the services.
+	VerifyTypeScript = "script"
+)
+
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// VerifyStep is an executable step of the proof of concept of a template.
+// The template is exploitable when every step passes.
+type VerifyStep struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"`
+
+	// Service and Port select the published container port an http step requests.
+	Service string            `yaml:"service" json:"service"`
+	Port    int               `yaml:"port" json:"port"`
+	Method  string            `yaml:"method" json:"method"`
+	Path    string            `yaml:"path" json:"path"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Body    string            `yaml:"body" json:"body"`
+
+	// Image is the image of the sidecar container a script step runs in.
+	Image  string `yaml:"image" json:"image"`
+	Script string `yaml:"script" json:"script"`
+
+	Match Matcher `yaml:"match" json:"match"`
+}
+
+// Matcher holds the conditions the outcome of a verification step must meet.
+type Matcher struct {
+	// Status is the expected HTTP status, any status when zero.
+	Status int `yaml:"status" json:"status"`
+	// ExitCode is the expected exit code of a script.
+	ExitCode int `yaml:"exit_code" json:"exit_code"`
+	// Body lists regular expressions the response body, or the script output,
+	// must all match.
+	Body []string `yaml:"body" json:"body"`
+}
+
+// Validate validates the verification step declaration.
+func (s VerifyStep) Validate(templateID string) error {
+	if s.Name == "" {
+		return fmt.Errorf("template '%s': verify step name can not be empty", templateID)
+	}
+
+	switch s.Type {
+	case VerifyTypeHTTP:
+		if s.Port < 1 || s.Port > 65535 {
+			return fmt.Errorf("template '%s', verify step '%s': port must be between 1 and 65535", templateID, s.Name)
+		}
+		if s.Method != "" && !slices.Contains(httpMethods, strings.ToUpper(s.Method)) {
+			return fmt.Errorf("template '%s', verify step '%s': unknown method '%s'", templateID, s.Name, s.Method)
+		}
+		if s.Path != "" && !strings.HasPrefix(s.Path, "/") {
+			return fmt.Errorf("template '%s', verify step '%s': path must start with '/'", templateID, s.Name)
+		}
+		if s.Match.Status != 0 && (s.Match.Status < 100 || s.Match.Status > 599) {
+			return fmt.Errorf("template '%s', verify step '%s': invalid status %d", templateID, s.Name, s.Match.Status)
+		}
+	case VerifyTypeScript:
+		if s.Image == "" || s.Script == "" {
+			return fmt.Errorf("template '%s', verify step '%s': image and script can not be empty", templateID, s.Name)
+		}
+	default:
+		return fmt.Errorf("template '%s', verify step '%s': unknown type '%s', must be one of: http, script", templateID, s.Name, s.Type)
+	}
+
+	for _, expression := range s.Match.Body {
+		if _, err := regexp.Compile(expression); err != nil {
+			return fmt.Errorf("template '%s', verify step '%s': %w", templateID, s.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyStepValidate(t *testing.T) {
+	valid := []VerifyStep{
+		{Name: "login", Type: VerifyTypeHTTP, Port: 80, Method: "post", Path: "/login.php", Match: Matcher{Status: 302}},
+		{Name: "rce", Type: VerifyTypeScript, Image: "curlimages/curl", Script: "curl http://web/", Match: Matcher{Body: []string{`uid=\d+`}}},
+	}
+	for _, step := range valid {
+		assert.NoError(t, step.Validate("vt-test"), step.Name)
+	}
+
+	invalid := map[string]VerifyStep{
+		"verify step name can not be empty": {Type: VerifyTypeHTTP, Port: 80},
+		"unknown type 'sql'":                {Name: "s", Type: "sql"},
+		"port must be between":              {Name: "s", Type: VerifyTypeHTTP},
+		"unknown method 'FETCH'":            {Name: "s", Type: VerifyTypeHTTP, Port: 80, Method: "FETCH"},
+		"path must start with '/'":          {Name: "s", Type: VerifyTypeHTTP, Port: 80, Path: "login"},
+		"invalid status 1000":               {Name: "s", Type: VerifyTypeHTTP, Port: 80, Match: Matcher{Status: 1000}},
+		"image and script can not be empty": {Name: "s", Type: VerifyTypeScript, Script: "id"},
+		"error parsing regexp":              {Name: "s", Type: VerifyTypeHTTP, Port: 80, Match: Matcher{Body: []string{"("}}},
+	}
+	for message, step := range invalid {
+		assert.ErrorContains(t, step.Validate("vt-test"), message)
+	}
+}
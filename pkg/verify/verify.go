@@ -0,0 +1,182 @@
+// Package verify runs the executable proof of concept of a template against
+// a deployment, to tell whether the template is still exploitable.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/readiness"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+)
+
+const (
+	// requestTimeout bounds a single HTTP step.
+	requestTimeout = 30 * time.Second
+	// scriptTimeout bounds a single script step.
+	scriptTimeout = 5 * time.Minute
+	// maxBodySize bounds the part of a response or script output that is matched.
+	maxBodySize = 4 << 20
+)
+
+// Result is the outcome of a verification step.
+type Result struct {
+	Step     string        `json:"step" yaml:"step"`
+	Type     string        `json:"type" yaml:"type"`
+	Passed   bool          `json:"passed" yaml:"passed"`
+	Message  string        `json:"message" yaml:"message"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+}
+
+// Run runs every verification step of the target template in order. HTTP
+// steps share a cookie jar, so that a login step authenticates the next
+// ones. env is passed to script steps.
+func Run(ctx context.Context, target readiness.Target, env []string) ([]Result, error) {
+	if len(target.Template.Verify) == 0 {
+		return nil, fmt.Errorf("template %s has no verify steps", target.Template.ID)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+			DisableKeepAlives: true,
+		},
+	}
+
+	results := make([]Result, 0, len(target.Template.Verify))
+	for _, step := range target.Template.Verify {
+		started := time.Now()
+
+		var err error
+		switch step.Type {
+		case tmpl.VerifyTypeHTTP:
+			err = runHTTP(ctx, client, target, step)
+		case tmpl.VerifyTypeScript:
+			err = runScript(target, step, env)
+		default:
+			err = fmt.Errorf("unknown step type %s", step.Type)
+		}
+
+		result := Result{Step: step.Name, Type: step.Type, Passed: err == nil, Message: "passed", Duration: time.Since(started).Truncate(time.Millisecond)}
+		if err != nil {
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Passed reports whether every step passed.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+// runHTTP sends the request of an http step and matches the response.
+func runHTTP(ctx context.Context, client *http.Client, target readiness.Target, step tmpl.VerifyStep) error {
+	status, err := target.Provider.Status(target.Template, target.Instance)
+	if err != nil {
+		return err
+	}
+	url, err := target.URL(status, step.Service, step.Port, step.Path)
+	if err != nil {
+		return err
+	}
+
+	method := strings.ToUpper(step.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(step.Body))
+	if err != nil {
+		return err
+	}
+	for name, value := range step.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close() //nolint:errcheck
+
+	if step.Match.Status != 0 && response.StatusCode != step.Match.Status {
+		return fmt.Errorf("%s %s returned status %d, expected %d", method, url, response.StatusCode, step.Match.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	return matchBody("response", body, step.Match.Body)
+}
+
+// runScript runs the script of a script step in a sidecar container and
+// matches its exit code and output.
+func runScript(target readiness.Target, step tmpl.VerifyStep, env []string) error {
+	runner, ok := target.Provider.(provider.ScriptRunner)
+	if !ok {
+		return fmt.Errorf("provider %s can not run scripts", target.Provider.Name())
+	}
+
+	var output bytes.Buffer
+	exitCode, err := runner.RunScript(target.Template, target.Instance, provider.ScriptOptions{
+		Image:   step.Image,
+		Script:  step.Script,
+		Env:     env,
+		Timeout: scriptTimeout,
+		Output:  &output,
+	})
+	if err != nil {
+		return err
+	}
+	if exitCode != step.Match.ExitCode {
+		return fmt.Errorf("script exited with code %d, expected %d: %s", exitCode, step.Match.ExitCode, lastLine(output.String()))
+	}
+
+	data := output.Bytes()
+	if len(data) > maxBodySize {
+		data = data[len(data)-maxBodySize:]
+	}
+	return matchBody("output", data, step.Match.Body)
+}
+
+// matchBody requires every expression to match body.
+func matchBody(what string, body []byte, expressions []string) error {
+	for _, expression := range expressions {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return err
+		}
+		if !pattern.Match(body) {
+			return fmt.Errorf("%s does not match %q", what, expression)
+		}
+	}
+	return nil
+}
+
+// lastLine returns the last non empty line of output, which usually explains a failure.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
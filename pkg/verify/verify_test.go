@@ -0,0 +1,129 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/happyhackingspace/vt/internal/state"
+	"github.com/happyhackingspace/vt/pkg/provider"
+	"github.com/happyhackingspace/vt/pkg/readiness"
+	tmpl "github.com/happyhackingspace/vt/pkg/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider runs scripts by printing a fixed output.
+type fakeProvider struct {
+	provider.Provider
+	output   string
+	exitCode int
+	env      []string
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) Status(*tmpl.Template, string) (provider.Status, error) {
+	return provider.Status{State: provider.StateRunning}, nil
+}
+
+func (f *fakeProvider) RunScript(_ *tmpl.Template, _ string, opts provider.ScriptOptions) (int, error) {
+	f.env = opts.Env
+	_, err := io.WriteString(opts.Output, f.output)
+	return f.exitCode, err
+}
+
+// newTarget returns a target whose web service on port 80 is served by handler.
+func newTarget(t *testing.T, p provider.Provider, steps []tmpl.VerifyStep, handler http.Handler) readiness.Target {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	hostPort, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return readiness.Target{
+		Provider:  p,
+		Template:  &tmpl.Template{ID: "vt-dvwa", Verify: steps},
+		Instance:  "vt-dvwa-verify",
+		Endpoints: []state.Endpoint{{Service: "web", HostIP: host, HostPort: hostPort, ContainerPort: 80, Protocol: "tcp"}},
+	}
+}
+
+func TestRunHTTPStepsShareCookies(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login.php", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.Method == http.MethodPost && r.Form.Get("username") == "admin' -- " {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "admin"})
+		}
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/admin.php", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "admin" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "Welcome to the admin panel")
+	})
+
+	steps := []tmpl.VerifyStep{
+		{
+			Name: "login-bypass", Type: tmpl.VerifyTypeHTTP, Service: "web", Port: 80,
+			Method: "post", Path: "/login.php",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Body:    "username=admin%27+--+&password=x",
+		},
+		{
+			Name: "admin-panel", Type: tmpl.VerifyTypeHTTP, Port: 80, Path: "/admin.php",
+			Match: tmpl.Matcher{Status: http.StatusOK, Body: []string{"Welcome", "admin panel"}},
+		},
+		{
+			Name: "missing", Type: tmpl.VerifyTypeHTTP, Port: 80, Path: "/admin.php",
+			Match: tmpl.Matcher{Body: []string{"root shell"}},
+		},
+	}
+	target := newTarget(t, &fakeProvider{}, steps, mux)
+
+	results, err := Run(context.Background(), target, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Passed, results[0].Message)
+	assert.True(t, results[1].Passed, results[1].Message)
+	assert.False(t, results[2].Passed)
+	assert.Equal(t, `response does not match "root shell"`, results[2].Message)
+	assert.False(t, Passed(results))
+}
+
+func TestRunScriptStep(t *testing.T) {
+	p := &fakeProvider{output: "uid=0(root)\n"}
+	steps := []tmpl.VerifyStep{{
+		Name: "rce", Type: tmpl.VerifyTypeScript, Image: "curlimages/curl", Script: "curl -s http://web/cmd?c=id",
+		Match: tmpl.Matcher{Body: []string{`uid=0\(root\)`}},
+	}}
+	target := newTarget(t, p, steps, http.NotFoundHandler())
+
+	results, err := Run(context.Background(), target, []string{"VT_FLAG_ROOT=vt{root}"})
+	require.NoError(t, err)
+	assert.True(t, Passed(results), results[0].Message)
+	assert.Equal(t, []string{"VT_FLAG_ROOT=vt{root}"}, p.env)
+
+	p.exitCode = 7
+	p.output = "curl: (7) Failed to connect\n"
+	results, err = Run(context.Background(), target, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "script exited with code 7, expected 0: curl: (7) Failed to connect", results[0].Message)
+}
+
+func TestRunWithoutSteps(t *testing.T) {
+	target := newTarget(t, &fakeProvider{}, nil, http.NotFoundHandler())
+	_, err := Run(context.Background(), target, nil)
+	assert.EqualError(t, err, "template vt-dvwa has no verify steps")
+}